@@ -0,0 +1,1236 @@
+package archive
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/naetharu/rpg-api/internal/calendar"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/wiki"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Identifies world archives and the layout they use. Bump WorldVersion
+// whenever the layout changes and keep reading the older versions.
+const (
+	WorldFormat  = "rpg-world"
+	WorldVersion = 1
+)
+
+// WorldArchive is a complete, self-contained copy of a world. Rows refer to
+// each other by Ref, numbered from 1 per type in ID order, so database IDs
+// never leave the server and exporting an imported world gives back the same
+// archive. Images are shared, not copied: a copy keeps the original's image
+// IDs, so the images count as in use for as long as the copy exists, but
+// its owner never uploaded them and so never deletes them.
+type WorldArchive struct {
+	Format            string                   `json:"format"`
+	Version           int                      `json:"version"`
+	ExportedAt        time.Time                `json:"exported_at"`
+	World             WorldRecord              `json:"world"`
+	Eras              []EraRecord              `json:"eras"`
+	Events            []EventRecord            `json:"events"`
+	Locations         []LocationRecord         `json:"locations"`
+	Organizations     []OrganizationRecord     `json:"organizations"`
+	Ranks             []RankRecord             `json:"ranks"`
+	NPCs              []NPCRecord              `json:"npcs"`
+	Memberships       []MembershipRecord       `json:"memberships"`
+	Relationships     []RelationshipRecord     `json:"relationships"`
+	GenerationConfigs []GenerationConfigRecord `json:"generation_configs"`
+	Lore              []LoreRecord             `json:"lore"`
+	Stories           []StoryRecord            `json:"stories"`
+	Calendar          *CalendarRecord          `json:"calendar"` // nil when the world has none
+	EventLinks        []EventLinkRecord        `json:"event_links"`
+	Participants      []ParticipantRecord      `json:"participants"`
+
+	Refs Refs `json:"-"` // The rows an export read, empty for archives from a file
+}
+
+// Refs maps the refs of each kind of row that other worlds can track, such
+// as forks, to database IDs
+type Refs struct {
+	Eras          map[int]uint
+	Events        map[int]uint
+	Locations     map[int]uint
+	Organizations map[int]uint
+	NPCs          map[int]uint
+}
+
+func newRefs() Refs {
+	return Refs{
+		Eras:          make(map[int]uint),
+		Events:        make(map[int]uint),
+		Locations:     make(map[int]uint),
+		Organizations: make(map[int]uint),
+		NPCs:          make(map[int]uint),
+	}
+}
+
+type WorldRecord struct {
+	Title          string   `json:"title"`
+	Description    string   `json:"description"`
+	BannerImageID  string   `json:"banner_image_id"`
+	BannerImageURL string   `json:"banner_image_url"`
+	CardImageID    string   `json:"card_image_id"`
+	CardImageURL   string   `json:"card_image_url"`
+	Genres         []string `json:"genres"`
+	AgeRating      string   `json:"age_rating"`
+}
+
+type CalendarRecord struct {
+	Name string `json:"name"`
+	calendar.Calendar
+}
+
+type EraRecord struct {
+	Ref       int     `json:"ref"`
+	Name      string  `json:"name"`
+	SortOrder int     `json:"sort_order"`
+	StartDate string  `json:"start_date"`
+	EndDate   *string `json:"end_date"`
+}
+
+// The era's day numbers under cal, nil when there is no calendar or it can't
+// read the dates
+func (e EraRecord) days(cal *calendar.Calendar) (start, end *int64) {
+	if cal == nil || e.StartDate == "" {
+		return nil, nil
+	}
+	first, last, err := cal.Range(e.StartDate, e.EndDate)
+	if err != nil {
+		return nil, nil
+	}
+	if e.EndDate != nil && *e.EndDate != "" {
+		return &first, &last
+	}
+	return &first, nil
+}
+
+type EventRecord struct {
+	Ref         int     `json:"ref"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	StartDate   string  `json:"start_date"`
+	EndDate     *string `json:"end_date"`
+	Era         string  `json:"era"`
+	Importance  string  `json:"importance"`
+	SortOrder   int     `json:"sort_order"`
+	ImageID     string  `json:"image_id"`
+	ImageURL    string  `json:"image_url"`
+	Details     string  `json:"details"`
+}
+
+type EventLinkRecord struct {
+	From int    `json:"from"` // Event refs
+	To   int    `json:"to"`
+	Type string `json:"type"`
+	Note string `json:"note"`
+}
+
+// ParticipantRecord puts an NPC, organization or location in an event.
+// Exactly one of them is set.
+type ParticipantRecord struct {
+	Event        int    `json:"event"`
+	NPC          *int   `json:"npc,omitempty"`
+	Organization *int   `json:"organization,omitempty"`
+	Location     *int   `json:"location,omitempty"`
+	Role         string `json:"role,omitempty"` // NPCs only
+}
+
+type LocationRecord struct {
+	Ref          int    `json:"ref"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	LocationType string `json:"location_type"`
+	Population   int    `json:"population"`
+	WealthLevel  string `json:"wealth_level"`
+}
+
+type OrganizationRecord struct {
+	Ref         int    `json:"ref"`
+	Name        string `json:"name"`
+	OrgType     string `json:"org_type"`
+	Description string `json:"description"`
+	PowerLevel  int    `json:"power_level"`
+	IsActive    bool   `json:"is_active"`
+}
+
+type RankRecord struct {
+	Ref            int    `json:"ref"`
+	Organization   int    `json:"organization"`
+	Title          string `json:"title"`
+	AuthorityLevel int    `json:"authority_level"`
+	Description    string `json:"description"`
+	SortOrder      int    `json:"sort_order"`
+}
+
+type NPCRecord struct {
+	Ref         int    `json:"ref"`
+	Location    *int   `json:"location"`
+	Name        string `json:"name"`
+	Age         int    `json:"age"`
+	Gender      string `json:"gender"`
+	Profession  string `json:"profession"`
+	SocialClass string `json:"social_class"`
+	Personality string `json:"personality"`
+	IsAlive     bool   `json:"is_alive"`
+}
+
+type MembershipRecord struct {
+	Ref          int        `json:"ref"`
+	NPC          int        `json:"npc"`
+	Organization int        `json:"organization"`
+	Rank         int        `json:"rank"`
+	Status       string     `json:"status"`
+	JoinedAt     time.Time  `json:"joined_at"`
+	LeftAt       *time.Time `json:"left_at"`
+	Notes        string     `json:"notes"`
+}
+
+type RelationshipRecord struct {
+	Ref                 int        `json:"ref"`
+	FromNPC             int        `json:"from_npc"`
+	ToNPC               int        `json:"to_npc"`
+	RelationshipType    string     `json:"relationship_type"`
+	RelationshipSubtype string     `json:"relationship_subtype"`
+	Strength            int        `json:"strength"`
+	IsPublic            bool       `json:"is_public"`
+	StartedAt           *time.Time `json:"started_at"`
+	EndedAt             *time.Time `json:"ended_at"`
+	Notes               string     `json:"notes"`
+}
+
+type GenerationConfigRecord struct {
+	Ref               int       `json:"ref"`
+	Seed              int64     `json:"seed"`
+	PopulationSize    int       `json:"population_size"`
+	FamilyDensity     float64   `json:"family_density"`
+	OrganizationCount int       `json:"organization_count"`
+	SocialClassDist   string    `json:"social_class_distribution"`
+	GeneratedAt       time.Time `json:"generated_at"`
+}
+
+type LoreRecord struct {
+	Ref        int      `json:"ref"`
+	Title      string   `json:"title"`
+	Summary    string   `json:"summary"`
+	Body       string   `json:"body"`
+	Categories []string `json:"categories"`
+	ImageID    string   `json:"image_id"`
+	ImageURL   string   `json:"image_url"`
+}
+
+type StoryRecord struct {
+	Ref           int             `json:"ref"`
+	Title         string          `json:"title"`
+	Category      string          `json:"category"`
+	Excerpt       string          `json:"excerpt"`
+	CoverImageID  string          `json:"cover_image_id"`
+	CoverImageURL string          `json:"cover_image_url"`
+	Status        string          `json:"status"`
+	PublishedAt   *time.Time      `json:"published_at"`
+	Events        []int           `json:"events"` // Event refs the story covers
+	Chapters      []ChapterRecord `json:"chapters"`
+}
+
+// ChapterRecord is a story chapter; chapters are listed in reading order
+type ChapterRecord struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// ImportSummary counts what an import created
+type ImportSummary struct {
+	WorldID           uint `json:"world_id"`
+	Eras              int  `json:"eras"`
+	Events            int  `json:"events"`
+	EventLinks        int  `json:"event_links"`
+	Participants      int  `json:"participants"`
+	Locations         int  `json:"locations"`
+	Organizations     int  `json:"organizations"`
+	Ranks             int  `json:"ranks"`
+	NPCs              int  `json:"npcs"`
+	Memberships       int  `json:"memberships"`
+	Relationships     int  `json:"relationships"`
+	GenerationConfigs int  `json:"generation_configs"`
+	Lore              int  `json:"lore"`
+	Stories           int  `json:"stories"`
+	Chapters          int  `json:"chapters"`
+
+	Refs Refs `json:"-"` // The rows created, by their ref in the archive
+}
+
+// ExportWorld reads a world and everything in it into an archive. The
+// caller checks the world is visible to user, and only the stories user can
+// read are included. user is nil for visitors.
+func ExportWorld(db *gorm.DB, worldID uint, user *models.User) (*WorldArchive, error) {
+	var world models.World
+	if err := db.First(&world, worldID).Error; err != nil {
+		return nil, err
+	}
+
+	archive := &WorldArchive{
+		Format:     WorldFormat,
+		Version:    WorldVersion,
+		ExportedAt: time.Now().UTC(),
+		World: WorldRecord{
+			Title:          world.Title,
+			Description:    world.Description,
+			BannerImageID:  world.BannerImageID,
+			BannerImageURL: world.BannerImageURL,
+			CardImageID:    world.CardImageID,
+			CardImageURL:   world.CardImageURL,
+			Genres:         append([]string{}, world.Genres...),
+			AgeRating:      world.AgeRating,
+		},
+		Eras:              []EraRecord{},
+		Events:            []EventRecord{},
+		Locations:         []LocationRecord{},
+		Organizations:     []OrganizationRecord{},
+		Ranks:             []RankRecord{},
+		NPCs:              []NPCRecord{},
+		Memberships:       []MembershipRecord{},
+		Relationships:     []RelationshipRecord{},
+		GenerationConfigs: []GenerationConfigRecord{},
+		Lore:              []LoreRecord{},
+		Stories:           []StoryRecord{},
+		EventLinks:        []EventLinkRecord{},
+		Participants:      []ParticipantRecord{},
+		Refs:              newRefs(),
+	}
+
+	var worldCalendar models.WorldCalendar
+	if err := db.Where("world_id = ?", worldID).Limit(1).Find(&worldCalendar).Error; err != nil {
+		return nil, err
+	}
+	if worldCalendar.ID != 0 {
+		archive.Calendar = &CalendarRecord{Name: worldCalendar.Name, Calendar: worldCalendar.Calendar()}
+	}
+
+	var eras []models.WorldEra
+	if err := db.Where("world_id = ?", worldID).Order("id").Find(&eras).Error; err != nil {
+		return nil, err
+	}
+	for i, era := range eras {
+		archive.Refs.Eras[i+1] = era.ID
+		archive.Eras = append(archive.Eras, EraRecord{
+			Ref:       i + 1,
+			Name:      era.Name,
+			SortOrder: era.SortOrder,
+			StartDate: era.StartDate,
+			EndDate:   era.EndDate,
+		})
+	}
+
+	var events []models.TimelineEvent
+	if err := db.Where("world_id = ?", worldID).Order("id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	eventRefs := make(map[uint]int)
+	for i, event := range events {
+		eventRefs[event.ID] = i + 1
+		archive.Refs.Events[i+1] = event.ID
+		archive.Events = append(archive.Events, EventRecord{
+			Ref:         i + 1,
+			Title:       event.Title,
+			Description: event.Description,
+			StartDate:   event.StartDate,
+			EndDate:     event.EndDate,
+			Era:         event.Era,
+			Importance:  event.Importance,
+			SortOrder:   event.SortOrder,
+			ImageID:     event.ImageID,
+			ImageURL:    event.ImageURL,
+			Details:     event.Details,
+		})
+	}
+
+	var links []models.TimelineEventLink
+	if err := db.Where("world_id = ?", worldID).Order("id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		archive.EventLinks = append(archive.EventLinks, EventLinkRecord{
+			From: eventRefs[link.FromEventID],
+			To:   eventRefs[link.ToEventID],
+			Type: link.Type,
+			Note: link.Note,
+		})
+	}
+
+	var locations []models.NPCLocation
+	if err := db.Where("world_id = ?", worldID).Order("id").Find(&locations).Error; err != nil {
+		return nil, err
+	}
+	locationRefs := make(map[uint]int)
+	for i, location := range locations {
+		locationRefs[location.ID] = i + 1
+		archive.Refs.Locations[i+1] = location.ID
+		archive.Locations = append(archive.Locations, LocationRecord{
+			Ref:          i + 1,
+			Name:         location.Name,
+			Description:  location.Description,
+			LocationType: location.LocationType,
+			Population:   location.Population,
+			WealthLevel:  location.WealthLevel,
+		})
+	}
+
+	var organizations []models.Organization
+	if err := db.Where("world_id = ?", worldID).Order("id").Find(&organizations).Error; err != nil {
+		return nil, err
+	}
+	organizationRefs := make(map[uint]int)
+	var organizationIDs []uint
+	for i, organization := range organizations {
+		organizationRefs[organization.ID] = i + 1
+		archive.Refs.Organizations[i+1] = organization.ID
+		organizationIDs = append(organizationIDs, organization.ID)
+		archive.Organizations = append(archive.Organizations, OrganizationRecord{
+			Ref:         i + 1,
+			Name:        organization.Name,
+			OrgType:     organization.OrgType,
+			Description: organization.Description,
+			PowerLevel:  organization.PowerLevel,
+			IsActive:    organization.IsActive,
+		})
+	}
+
+	rankRefs := make(map[uint]int)
+	if len(organizationIDs) > 0 {
+		var ranks []models.OrganizationRank
+		if err := db.Where("organization_id IN ?", organizationIDs).Order("id").Find(&ranks).Error; err != nil {
+			return nil, err
+		}
+		for i, rank := range ranks {
+			rankRefs[rank.ID] = i + 1
+			archive.Ranks = append(archive.Ranks, RankRecord{
+				Ref:            i + 1,
+				Organization:   organizationRefs[rank.OrganizationID],
+				Title:          rank.Title,
+				AuthorityLevel: rank.AuthorityLevel,
+				Description:    rank.Description,
+				SortOrder:      rank.SortOrder,
+			})
+		}
+	}
+
+	var npcs []models.NPC
+	if err := db.Where("world_id = ?", worldID).Order("id").Find(&npcs).Error; err != nil {
+		return nil, err
+	}
+	npcRefs := make(map[uint]int)
+	var npcIDs []uint
+	for i, npc := range npcs {
+		npcRefs[npc.ID] = i + 1
+		archive.Refs.NPCs[i+1] = npc.ID
+		npcIDs = append(npcIDs, npc.ID)
+		record := NPCRecord{
+			Ref:         i + 1,
+			Name:        npc.Name,
+			Age:         npc.Age,
+			Gender:      npc.Gender,
+			Profession:  npc.Profession,
+			SocialClass: npc.SocialClass,
+			Personality: npc.Personality,
+			IsAlive:     npc.IsAlive,
+		}
+		if npc.LocationID != nil {
+			if ref, ok := locationRefs[*npc.LocationID]; ok {
+				record.Location = &ref
+			}
+		}
+		archive.NPCs = append(archive.NPCs, record)
+	}
+
+	if len(npcIDs) > 0 {
+		var memberships []models.OrganizationMembership
+		if err := db.Where("npc_id IN ?", npcIDs).Order("id").Find(&memberships).Error; err != nil {
+			return nil, err
+		}
+		for _, membership := range memberships {
+			// Memberships pointing outside the world are dropped
+			organization, rank := organizationRefs[membership.OrganizationID], rankRefs[membership.RankID]
+			if organization == 0 || rank == 0 {
+				continue
+			}
+			archive.Memberships = append(archive.Memberships, MembershipRecord{
+				Ref:          len(archive.Memberships) + 1,
+				NPC:          npcRefs[membership.NPCID],
+				Organization: organization,
+				Rank:         rank,
+				Status:       membership.Status,
+				JoinedAt:     membership.JoinedAt.UTC(),
+				LeftAt:       utc(membership.LeftAt),
+				Notes:        membership.Notes,
+			})
+		}
+	}
+
+	var relationships []models.NPCRelationship
+	if err := db.Where("world_id = ?", worldID).Order("id").Find(&relationships).Error; err != nil {
+		return nil, err
+	}
+	for _, relationship := range relationships {
+		from, to := npcRefs[relationship.FromNPCID], npcRefs[relationship.ToNPCID]
+		if from == 0 || to == 0 {
+			continue
+		}
+		archive.Relationships = append(archive.Relationships, RelationshipRecord{
+			Ref:                 len(archive.Relationships) + 1,
+			FromNPC:             from,
+			ToNPC:               to,
+			RelationshipType:    relationship.RelationshipType,
+			RelationshipSubtype: relationship.RelationshipSubtype,
+			Strength:            relationship.Strength,
+			IsPublic:            relationship.IsPublic,
+			StartedAt:           utc(relationship.StartedAt),
+			EndedAt:             utc(relationship.EndedAt),
+			Notes:               relationship.Notes,
+		})
+	}
+
+	worldEvents := db.Model(&models.TimelineEvent{}).Select("id").Where("world_id = ?", worldID)
+	var eventNPCs []models.TimelineEventNPC
+	if err := db.Where("timeline_event_id IN (?)", worldEvents).Order("timeline_event_id, npc_id").Find(&eventNPCs).Error; err != nil {
+		return nil, err
+	}
+	for _, participant := range eventNPCs {
+		if npc, ok := npcRefs[participant.NPCID]; ok {
+			archive.Participants = append(archive.Participants, ParticipantRecord{Event: eventRefs[participant.TimelineEventID], NPC: &npc, Role: participant.Role})
+		}
+	}
+	for _, join := range []struct {
+		table, column string
+		refs          map[uint]int
+		organization  bool
+	}{
+		{"timeline_event_organizations", "organization_id", organizationRefs, true},
+		{"timeline_event_locations", "npc_location_id", locationRefs, false},
+	} {
+		var rows []struct{ EventID, OtherID uint }
+		if err := db.Table(join.table).Select("timeline_event_id AS event_id, "+join.column+" AS other_id").
+			Where("timeline_event_id IN (?)", worldEvents).Order("event_id, other_id").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			ref, ok := join.refs[row.OtherID]
+			if !ok {
+				continue
+			}
+			record := ParticipantRecord{Event: eventRefs[row.EventID]}
+			if join.organization {
+				record.Organization = &ref
+			} else {
+				record.Location = &ref
+			}
+			archive.Participants = append(archive.Participants, record)
+		}
+	}
+
+	var configs []models.NPCGenerationConfig
+	if err := db.Where("world_id = ?", worldID).Order("id").Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	for i, config := range configs {
+		archive.GenerationConfigs = append(archive.GenerationConfigs, GenerationConfigRecord{
+			Ref:               i + 1,
+			Seed:              config.Seed,
+			PopulationSize:    config.PopulationSize,
+			FamilyDensity:     config.FamilyDensity,
+			OrganizationCount: config.OrganizationCount,
+			SocialClassDist:   config.SocialClassDist,
+			GeneratedAt:       config.GeneratedAt.UTC(),
+		})
+	}
+
+	var articles []models.LoreArticle
+	if err := db.Where("world_id = ?", worldID).Order("id").Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	for i, article := range articles {
+		archive.Lore = append(archive.Lore, LoreRecord{
+			Ref:        i + 1,
+			Title:      article.Title,
+			Summary:    article.Summary,
+			Body:       article.Body,
+			Categories: append([]string{}, article.Categories...),
+			ImageID:    article.ImageID,
+			ImageURL:   article.ImageURL,
+		})
+	}
+
+	var stories []models.Story
+	if err := models.VisibleStories(db.Where("world_id = ?", worldID), user).Order("id").
+		Preload("Chapters", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") }).
+		Preload("TimelineEvents", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Find(&stories).Error; err != nil {
+		return nil, err
+	}
+	for i, story := range stories {
+		record := StoryRecord{
+			Ref:           i + 1,
+			Title:         story.Title,
+			Category:      story.Category,
+			Excerpt:       story.Excerpt,
+			CoverImageID:  story.CoverImageID,
+			CoverImageURL: story.CoverImageURL,
+			Status:        story.Status,
+			PublishedAt:   utc(story.PublishedAt),
+			Events:        []int{},
+			Chapters:      []ChapterRecord{},
+		}
+		for _, event := range story.TimelineEvents {
+			record.Events = append(record.Events, eventRefs[event.ID])
+		}
+		for _, chapter := range story.Chapters {
+			record.Chapters = append(record.Chapters, ChapterRecord{Title: chapter.Title, Body: chapter.Body})
+		}
+		archive.Stories = append(archive.Stories, record)
+	}
+
+	return archive, nil
+}
+
+// Validate checks the format, version and that every reference points at
+// a row in the archive
+func (a *WorldArchive) Validate() error {
+	if a.Format != WorldFormat {
+		return fmt.Errorf("not a world archive")
+	}
+	if a.Version != WorldVersion {
+		return fmt.Errorf("unsupported archive version %d", a.Version)
+	}
+	if a.World.Title == "" {
+		return fmt.Errorf("world title is required")
+	}
+	if a.Calendar != nil {
+		if err := a.Calendar.Validate(); err != nil {
+			return fmt.Errorf("calendar: %w", err)
+		}
+
+		// Events go in the one era containing them, so dated eras can't overlap
+		eras := make([]models.WorldEra, len(a.Eras))
+		for i, era := range a.Eras {
+			eras[i].Name = era.Name
+			eras[i].StartDay, eras[i].EndDay = era.days(&a.Calendar.Calendar)
+		}
+		if pairs := models.OverlappingEras(eras); len(pairs) > 0 {
+			return fmt.Errorf("era %q overlaps the era %q", pairs[0][0].Name, pairs[0][1].Name)
+		}
+	}
+
+	events, err := refSet("event", len(a.Events), func(i int) int { return a.Events[i].Ref })
+	if err != nil {
+		return err
+	}
+	locations, err := refSet("location", len(a.Locations), func(i int) int { return a.Locations[i].Ref })
+	if err != nil {
+		return err
+	}
+	organizations, err := refSet("organization", len(a.Organizations), func(i int) int { return a.Organizations[i].Ref })
+	if err != nil {
+		return err
+	}
+	ranks, err := refSet("rank", len(a.Ranks), func(i int) int { return a.Ranks[i].Ref })
+	if err != nil {
+		return err
+	}
+	npcs, err := refSet("NPC", len(a.NPCs), func(i int) int { return a.NPCs[i].Ref })
+	if err != nil {
+		return err
+	}
+
+	linked := make(map[EventLinkRecord]bool)
+	parents := make(map[int]bool)
+	for i, link := range a.EventLinks {
+		if !events[link.From] || !events[link.To] || link.From == link.To {
+			return fmt.Errorf("event link %d must join two different known events", i+1)
+		}
+		if !slices.Contains(models.LinkTypes, link.Type) {
+			return fmt.Errorf("event link %d has unknown type %q", i+1, link.Type)
+		}
+		key := EventLinkRecord{From: link.From, To: link.To, Type: link.Type}
+		if !models.Directed(link.Type) && key.From > key.To {
+			key.From, key.To = key.To, key.From
+		}
+		if linked[key] {
+			return fmt.Errorf("event link %d repeats another link", i+1)
+		}
+		linked[key] = true
+		if link.Type == models.LinkPartOf {
+			if parents[link.From] {
+				return fmt.Errorf("event %d is part of more than one event", link.From)
+			}
+			parents[link.From] = true
+		}
+	}
+
+	rankOrganization := make(map[int]int)
+	for _, rank := range a.Ranks {
+		if !organizations[rank.Organization] {
+			return fmt.Errorf("rank %d belongs to unknown organization %d", rank.Ref, rank.Organization)
+		}
+		rankOrganization[rank.Ref] = rank.Organization
+	}
+	for _, npc := range a.NPCs {
+		if npc.Location != nil && !locations[*npc.Location] {
+			return fmt.Errorf("NPC %d is in unknown location %d", npc.Ref, *npc.Location)
+		}
+	}
+	for _, membership := range a.Memberships {
+		if !npcs[membership.NPC] || !organizations[membership.Organization] || !ranks[membership.Rank] {
+			return fmt.Errorf("membership %d refers to an unknown NPC, organization or rank", membership.Ref)
+		}
+		if rankOrganization[membership.Rank] != membership.Organization {
+			return fmt.Errorf("membership %d uses a rank from another organization", membership.Ref)
+		}
+	}
+	for _, relationship := range a.Relationships {
+		if !npcs[relationship.FromNPC] || !npcs[relationship.ToNPC] {
+			return fmt.Errorf("relationship %d refers to an unknown NPC", relationship.Ref)
+		}
+	}
+
+	participants := make(map[string]bool)
+	for i, participant := range a.Participants {
+		if !events[participant.Event] {
+			return fmt.Errorf("participant %d is in unknown event %d", i+1, participant.Event)
+		}
+		var who []string
+		if participant.NPC != nil {
+			if !npcs[*participant.NPC] {
+				return fmt.Errorf("participant %d is unknown NPC %d", i+1, *participant.NPC)
+			}
+			who = append(who, fmt.Sprintf("NPC %d", *participant.NPC))
+		}
+		if participant.Organization != nil {
+			if !organizations[*participant.Organization] {
+				return fmt.Errorf("participant %d is unknown organization %d", i+1, *participant.Organization)
+			}
+			who = append(who, fmt.Sprintf("organization %d", *participant.Organization))
+		}
+		if participant.Location != nil {
+			if !locations[*participant.Location] {
+				return fmt.Errorf("participant %d is unknown location %d", i+1, *participant.Location)
+			}
+			who = append(who, fmt.Sprintf("location %d", *participant.Location))
+		}
+		if len(who) != 1 {
+			return fmt.Errorf("participant %d must be exactly one NPC, organization or location", i+1)
+		}
+		key := fmt.Sprintf("%d %s", participant.Event, who[0])
+		if participants[key] {
+			return fmt.Errorf("%s is in event %d twice", who[0], participant.Event)
+		}
+		participants[key] = true
+	}
+
+	// Links find articles by title, so titles are unique as they are on the API
+	titles := make(map[string]bool)
+	for _, article := range a.Lore {
+		title := strings.ToLower(strings.TrimSpace(article.Title))
+		if title == "" {
+			return fmt.Errorf("lore article %d has no title", article.Ref)
+		}
+		if titles[title] {
+			return fmt.Errorf("lore article %d repeats the title %q", article.Ref, article.Title)
+		}
+		titles[title] = true
+	}
+
+	for _, story := range a.Stories {
+		if strings.TrimSpace(story.Title) == "" {
+			return fmt.Errorf("story %d has no title", story.Ref)
+		}
+		if story.Status != models.StoryDraft && story.Status != models.StoryPublished {
+			return fmt.Errorf("story %d has unknown status %q", story.Ref, story.Status)
+		}
+		for _, event := range story.Events {
+			if !events[event] {
+				return fmt.Errorf("story %d covers unknown event %d", story.Ref, event)
+			}
+		}
+		for _, chapter := range story.Chapters {
+			if strings.TrimSpace(chapter.Title) == "" {
+				return fmt.Errorf("story %d has a chapter with no title", story.Ref)
+			}
+		}
+	}
+	return nil
+}
+
+// Collects the refs of one type, failing on zero or duplicate refs
+func refSet(kind string, count int, ref func(int) int) (map[int]bool, error) {
+	refs := make(map[int]bool, count)
+	for i := 0; i < count; i++ {
+		r := ref(i)
+		if r <= 0 {
+			return nil, fmt.Errorf("%s %d has no ref", kind, i+1)
+		}
+		if refs[r] {
+			return nil, fmt.Errorf("%s ref %d is used twice", kind, r)
+		}
+		refs[r] = true
+	}
+	return refs, nil
+}
+
+// ImportWorld recreates an archived world owned by userID. It runs inside
+// the caller's transaction and expects a validated archive. Rows are created
+// in archive order so their new IDs keep the same order.
+func ImportWorld(tx *gorm.DB, a *WorldArchive, userID uint) (*ImportSummary, error) {
+	// A new session, so every statement below starts from the Omit alone
+	db := tx.Omit(clause.Associations).Session(&gorm.Session{})
+
+	world := models.World{
+		Title:          a.World.Title,
+		Description:    a.World.Description,
+		BannerImageID:  a.World.BannerImageID,
+		BannerImageURL: a.World.BannerImageURL,
+		CardImageID:    a.World.CardImageID,
+		CardImageURL:   a.World.CardImageURL,
+		Genres:         pq.StringArray(a.World.Genres),
+		AgeRating:      a.World.AgeRating,
+		UserID:         &userID,
+	}
+	if world.Genres == nil {
+		world.Genres = pq.StringArray{}
+	}
+	if err := db.Create(&world).Error; err != nil {
+		return nil, fmt.Errorf("failed to create world: %w", err)
+	}
+	summary := &ImportSummary{WorldID: world.ID, Refs: newRefs()}
+
+	// Era and event day numbers come from the calendar, so it goes in first
+	var cal *calendar.Calendar
+	if a.Calendar != nil {
+		worldCalendar := models.WorldCalendar{
+			WorldID:      world.ID,
+			Name:         a.Calendar.Name,
+			Months:       a.Calendar.Months,
+			Weekdays:     a.Calendar.Weekdays,
+			FirstWeekday: a.Calendar.FirstWeekday,
+			Epochs:       a.Calendar.Epochs,
+			Leap:         a.Calendar.Leap,
+		}
+		if worldCalendar.Weekdays == nil {
+			worldCalendar.Weekdays = []string{}
+		}
+		if worldCalendar.Epochs == nil {
+			worldCalendar.Epochs = []calendar.Epoch{}
+		}
+		if err := db.Create(&worldCalendar).Error; err != nil {
+			return nil, fmt.Errorf("failed to create calendar: %w", err)
+		}
+		definition := worldCalendar.Calendar()
+		cal = &definition
+	}
+
+	// Events were kept in step with their era's name, which finds the era again
+	eraIDs := make(map[string]uint)
+	if len(a.Eras) > 0 {
+		eras := make([]models.WorldEra, len(a.Eras))
+		for i, era := range a.Eras {
+			eras[i] = models.WorldEra{
+				WorldID:   world.ID,
+				Name:      era.Name,
+				SortOrder: era.SortOrder,
+				StartDate: era.StartDate,
+				EndDate:   era.EndDate,
+			}
+			eras[i].StartDay, eras[i].EndDay = era.days(cal)
+		}
+		if err := db.CreateInBatches(&eras, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create eras: %w", err)
+		}
+		for i, era := range eras {
+			eraIDs[strings.ToLower(era.Name)] = era.ID
+			summary.Refs.Eras[a.Eras[i].Ref] = era.ID
+		}
+		summary.Eras = len(eras)
+	}
+
+	eventIDs := summary.Refs.Events
+	if len(a.Events) > 0 {
+		events := make([]models.TimelineEvent, len(a.Events))
+		for i, event := range a.Events {
+			events[i] = models.TimelineEvent{
+				WorldID:     world.ID,
+				Title:       event.Title,
+				Description: event.Description,
+				StartDate:   event.StartDate,
+				EndDate:     event.EndDate,
+				Era:         event.Era,
+				Importance:  event.Importance,
+				SortOrder:   event.SortOrder,
+				ImageID:     event.ImageID,
+				ImageURL:    event.ImageURL,
+				Details:     event.Details,
+				UserID:      &userID,
+			}
+			if id, ok := eraIDs[strings.ToLower(event.Era)]; ok && event.Era != "" {
+				events[i].EraID = &id
+			}
+			if cal != nil {
+				// Dates the calendar can't read stay undated, as they were
+				if first, last, err := cal.Range(event.StartDate, event.EndDate); err == nil {
+					duration := last - first + 1
+					events[i].StartDay, events[i].EndDay, events[i].DurationDays = &first, &last, &duration
+				}
+			}
+		}
+		if err := db.CreateInBatches(&events, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create timeline events: %w", err)
+		}
+		for i, event := range events {
+			eventIDs[a.Events[i].Ref] = event.ID
+		}
+		summary.Events = len(events)
+	}
+
+	if len(a.EventLinks) > 0 {
+		links := make([]models.TimelineEventLink, len(a.EventLinks))
+		for i, link := range a.EventLinks {
+			links[i] = models.TimelineEventLink{
+				WorldID:     world.ID,
+				FromEventID: eventIDs[link.From],
+				ToEventID:   eventIDs[link.To],
+				Type:        link.Type,
+				Note:        link.Note,
+				UserID:      &userID,
+			}
+			// Events keep their archive order, so this matches the API's own
+			if !models.Directed(link.Type) && links[i].FromEventID > links[i].ToEventID {
+				links[i].FromEventID, links[i].ToEventID = links[i].ToEventID, links[i].FromEventID
+			}
+		}
+		if err := db.CreateInBatches(&links, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create event links: %w", err)
+		}
+		summary.EventLinks = len(links)
+	}
+
+	locationIDs := summary.Refs.Locations
+	if len(a.Locations) > 0 {
+		locations := make([]models.NPCLocation, len(a.Locations))
+		for i, location := range a.Locations {
+			locations[i] = models.NPCLocation{
+				WorldID:      world.ID,
+				Name:         location.Name,
+				Description:  location.Description,
+				LocationType: location.LocationType,
+				Population:   location.Population,
+				WealthLevel:  location.WealthLevel,
+			}
+		}
+		if err := db.CreateInBatches(&locations, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create locations: %w", err)
+		}
+		for i, location := range locations {
+			locationIDs[a.Locations[i].Ref] = location.ID
+		}
+		summary.Locations = len(locations)
+	}
+
+	organizationIDs := summary.Refs.Organizations
+	if len(a.Organizations) > 0 {
+		organizations := make([]models.Organization, len(a.Organizations))
+		var inactive []int
+		for i, organization := range a.Organizations {
+			organizations[i] = models.Organization{
+				WorldID:     world.ID,
+				Name:        organization.Name,
+				OrgType:     organization.OrgType,
+				Description: organization.Description,
+				PowerLevel:  organization.PowerLevel,
+				IsActive:    organization.IsActive,
+			}
+			if !organization.IsActive {
+				inactive = append(inactive, i)
+			}
+		}
+		if err := db.CreateInBatches(&organizations, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create organizations: %w", err)
+		}
+		for i, organization := range organizations {
+			organizationIDs[a.Organizations[i].Ref] = organization.ID
+		}
+		if err := clearDefaultTrue(tx, "organizations", "is_active", organizations, inactive, func(o models.Organization) uint { return o.ID }); err != nil {
+			return nil, err
+		}
+		summary.Organizations = len(organizations)
+	}
+
+	rankIDs := make(map[int]uint)
+	if len(a.Ranks) > 0 {
+		ranks := make([]models.OrganizationRank, len(a.Ranks))
+		for i, rank := range a.Ranks {
+			ranks[i] = models.OrganizationRank{
+				OrganizationID: organizationIDs[rank.Organization],
+				Title:          rank.Title,
+				AuthorityLevel: rank.AuthorityLevel,
+				Description:    rank.Description,
+				SortOrder:      rank.SortOrder,
+			}
+		}
+		if err := db.CreateInBatches(&ranks, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create ranks: %w", err)
+		}
+		for i, rank := range ranks {
+			rankIDs[a.Ranks[i].Ref] = rank.ID
+		}
+		summary.Ranks = len(ranks)
+	}
+
+	npcIDs := summary.Refs.NPCs
+	if len(a.NPCs) > 0 {
+		npcs := make([]models.NPC, len(a.NPCs))
+		var dead []int
+		for i, npc := range a.NPCs {
+			npcs[i] = models.NPC{
+				WorldID:     world.ID,
+				Name:        npc.Name,
+				Age:         npc.Age,
+				Gender:      npc.Gender,
+				Profession:  npc.Profession,
+				SocialClass: npc.SocialClass,
+				Personality: npc.Personality,
+				IsAlive:     npc.IsAlive,
+			}
+			if npc.Location != nil {
+				locationID := locationIDs[*npc.Location]
+				npcs[i].LocationID = &locationID
+			}
+			if !npc.IsAlive {
+				dead = append(dead, i)
+			}
+		}
+		if err := db.CreateInBatches(&npcs, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create NPCs: %w", err)
+		}
+		for i, npc := range npcs {
+			npcIDs[a.NPCs[i].Ref] = npc.ID
+		}
+		if err := clearDefaultTrue(tx, "npcs", "is_alive", npcs, dead, func(n models.NPC) uint { return n.ID }); err != nil {
+			return nil, err
+		}
+		summary.NPCs = len(npcs)
+	}
+
+	if len(a.Memberships) > 0 {
+		memberships := make([]models.OrganizationMembership, len(a.Memberships))
+		for i, membership := range a.Memberships {
+			memberships[i] = models.OrganizationMembership{
+				NPCID:          npcIDs[membership.NPC],
+				OrganizationID: organizationIDs[membership.Organization],
+				RankID:         rankIDs[membership.Rank],
+				Status:         membership.Status,
+				JoinedAt:       membership.JoinedAt,
+				LeftAt:         membership.LeftAt,
+				Notes:          membership.Notes,
+			}
+		}
+		if err := db.CreateInBatches(&memberships, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create memberships: %w", err)
+		}
+		summary.Memberships = len(memberships)
+	}
+
+	if len(a.Relationships) > 0 {
+		relationships := make([]models.NPCRelationship, len(a.Relationships))
+		var secret []int
+		for i, relationship := range a.Relationships {
+			relationships[i] = models.NPCRelationship{
+				WorldID:             world.ID,
+				FromNPCID:           npcIDs[relationship.FromNPC],
+				ToNPCID:             npcIDs[relationship.ToNPC],
+				RelationshipType:    relationship.RelationshipType,
+				RelationshipSubtype: relationship.RelationshipSubtype,
+				Strength:            relationship.Strength,
+				IsPublic:            relationship.IsPublic,
+				StartedAt:           relationship.StartedAt,
+				EndedAt:             relationship.EndedAt,
+				Notes:               relationship.Notes,
+			}
+			if !relationship.IsPublic {
+				secret = append(secret, i)
+			}
+		}
+		if err := db.CreateInBatches(&relationships, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create relationships: %w", err)
+		}
+		if err := clearDefaultTrue(tx, "npc_relationships", "is_public", relationships, secret, func(r models.NPCRelationship) uint { return r.ID }); err != nil {
+			return nil, err
+		}
+		summary.Relationships = len(relationships)
+	}
+
+	if len(a.Participants) > 0 {
+		var eventNPCs []models.TimelineEventNPC
+		var eventOrganizations, eventLocations []map[string]interface{}
+		for _, participant := range a.Participants {
+			event := eventIDs[participant.Event]
+			switch {
+			case participant.NPC != nil:
+				role := strings.TrimSpace(participant.Role)
+				if role == "" {
+					role = models.DefaultEventRole
+				}
+				eventNPCs = append(eventNPCs, models.TimelineEventNPC{TimelineEventID: event, NPCID: npcIDs[*participant.NPC], Role: role})
+			case participant.Organization != nil:
+				eventOrganizations = append(eventOrganizations, map[string]interface{}{"timeline_event_id": event, "organization_id": organizationIDs[*participant.Organization]})
+			case participant.Location != nil:
+				eventLocations = append(eventLocations, map[string]interface{}{"timeline_event_id": event, "npc_location_id": locationIDs[*participant.Location]})
+			}
+		}
+		if len(eventNPCs) > 0 {
+			if err := db.CreateInBatches(&eventNPCs, 500).Error; err != nil {
+				return nil, fmt.Errorf("failed to add NPCs to events: %w", err)
+			}
+		}
+		if len(eventOrganizations) > 0 {
+			if err := tx.Table("timeline_event_organizations").CreateInBatches(eventOrganizations, 500).Error; err != nil {
+				return nil, fmt.Errorf("failed to add organizations to events: %w", err)
+			}
+		}
+		if len(eventLocations) > 0 {
+			if err := tx.Table("timeline_event_locations").CreateInBatches(eventLocations, 500).Error; err != nil {
+				return nil, fmt.Errorf("failed to add locations to events: %w", err)
+			}
+		}
+		summary.Participants = len(a.Participants)
+	}
+
+	if len(a.GenerationConfigs) > 0 {
+		configs := make([]models.NPCGenerationConfig, len(a.GenerationConfigs))
+		for i, config := range a.GenerationConfigs {
+			configs[i] = models.NPCGenerationConfig{
+				WorldID:           world.ID,
+				Seed:              config.Seed,
+				PopulationSize:    config.PopulationSize,
+				FamilyDensity:     config.FamilyDensity,
+				OrganizationCount: config.OrganizationCount,
+				SocialClassDist:   config.SocialClassDist,
+				GeneratedAt:       config.GeneratedAt,
+			}
+		}
+		if err := db.CreateInBatches(&configs, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create generation configs: %w", err)
+		}
+		summary.GenerationConfigs = len(configs)
+	}
+
+	// Links are stored by name, so they come back from the bodies as they are
+	if len(a.Lore) > 0 {
+		articles := make([]models.LoreArticle, len(a.Lore))
+		for i, record := range a.Lore {
+			articles[i] = models.LoreArticle{
+				WorldID:    world.ID,
+				Title:      record.Title,
+				Summary:    record.Summary,
+				Body:       record.Body,
+				Categories: pq.StringArray(append([]string{}, record.Categories...)),
+				ImageID:    record.ImageID,
+				ImageURL:   record.ImageURL,
+				UserID:     &userID,
+			}
+		}
+		if err := db.CreateInBatches(&articles, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to create lore articles: %w", err)
+		}
+		for _, article := range articles {
+			if err := wiki.SaveLinks(db, article); err != nil {
+				return nil, fmt.Errorf("failed to create lore links: %w", err)
+			}
+		}
+		summary.Lore = len(articles)
+	}
+
+	// Stories come back unreviewed like the world itself, with reading
+	// times recomputed from the chapters
+	for _, record := range a.Stories {
+		story := models.Story{
+			WorldID:       world.ID,
+			Title:         record.Title,
+			Category:      record.Category,
+			Excerpt:       record.Excerpt,
+			CoverImageID:  record.CoverImageID,
+			CoverImageURL: record.CoverImageURL,
+			Status:        record.Status,
+			PublishedAt:   record.PublishedAt,
+			UserID:        &userID,
+		}
+		chapters := make([]models.StoryChapter, len(record.Chapters))
+		for i, chapter := range record.Chapters {
+			chapters[i] = models.StoryChapter{
+				Title:     chapter.Title,
+				Body:      chapter.Body,
+				SortOrder: i + 1,
+				WordCount: models.CountWords(chapter.Body),
+			}
+			chapters[i].ReadingMinutes = models.ReadingMinutes(chapters[i].WordCount)
+			story.WordCount += chapters[i].WordCount
+		}
+		story.ReadingMinutes = models.ReadingMinutes(story.WordCount)
+		if err := db.Create(&story).Error; err != nil {
+			return nil, fmt.Errorf("failed to create story: %w", err)
+		}
+
+		if len(chapters) > 0 {
+			for i := range chapters {
+				chapters[i].StoryID = story.ID
+			}
+			if err := db.CreateInBatches(&chapters, 500).Error; err != nil {
+				return nil, fmt.Errorf("failed to create chapters: %w", err)
+			}
+		}
+
+		covered := make([]models.TimelineEvent, 0, len(record.Events))
+		seen := make(map[int]bool)
+		for _, ref := range record.Events {
+			if !seen[ref] {
+				seen[ref] = true
+				covered = append(covered, models.TimelineEvent{ID: eventIDs[ref]})
+			}
+		}
+		if len(covered) > 0 {
+			if err := tx.Model(&story).Omit("TimelineEvents.*").Association("TimelineEvents").Append(covered); err != nil {
+				return nil, fmt.Errorf("failed to link story events: %w", err)
+			}
+		}
+
+		summary.Stories++
+		summary.Chapters += len(chapters)
+	}
+
+	return summary, nil
+}
+
+// GORM skips false for columns with default:true when inserting, so those
+// rows get the column cleared afterwards
+func clearDefaultTrue[T any](tx *gorm.DB, table, column string, rows []T, indexes []int, id func(T) uint) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(indexes))
+	for i, index := range indexes {
+		ids[i] = id(rows[index])
+	}
+	if err := tx.Table(table).Where("id IN ?", ids).Update(column, false).Error; err != nil {
+		return fmt.Errorf("failed to update %s: %w", table, err)
+	}
+	return nil
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	value := t.UTC()
+	return &value
+}
@@ -0,0 +1,305 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/calendar"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func openWorldDB(t *testing.T) *gorm.DB {
+	return testdb.Open(t,
+		&models.User{}, &models.World{}, &models.WorldCalendar{}, &models.WorldEra{},
+		&models.TimelineEvent{}, &models.TimelineEventLink{}, &models.TimelineEventNPC{},
+		&models.NPCLocation{}, &models.Organization{}, &models.OrganizationRank{}, &models.NPC{},
+		&models.OrganizationMembership{}, &models.NPCRelationship{}, &models.NPCGenerationConfig{},
+		&models.LoreArticle{}, &models.LoreLink{}, &models.Story{}, &models.StoryChapter{})
+}
+
+// Imports in these tests belong to this user
+var owner = &models.User{ID: 1}
+
+func ref(r int) *int { return &r }
+
+func text(s string) *string { return &s }
+
+// An archive using every part of the format, in the order an export lists it
+func testWorldArchive() *WorldArchive {
+	joined := time.Date(1203, 3, 15, 0, 0, 0, 0, time.UTC)
+	left := time.Date(1204, 1, 2, 0, 0, 0, 0, time.UTC)
+	published := time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	return &WorldArchive{
+		Format:     WorldFormat,
+		Version:    WorldVersion,
+		ExportedAt: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		World: WorldRecord{
+			Title:          "Varn",
+			Description:    "A river kingdom",
+			BannerImageID:  "banner",
+			BannerImageURL: "https://example.com/banner.png",
+			Genres:         []string{"fantasy", "intrigue"},
+			AgeRating:      "teen",
+		},
+		Calendar: &CalendarRecord{
+			Name: "Varnish Reckoning",
+			Calendar: calendar.Calendar{
+				Months:   []calendar.Month{{Name: "Thawmoon", Days: 30}, {Name: "Frostfall", Days: 31}},
+				Weekdays: []string{"Oneday", "Twoday"},
+				Epochs:   []calendar.Epoch{{Name: "After the Reckoning", Abbreviation: "AR", StartYear: 1}},
+				Leap:     &calendar.LeapRule{Every: 4, Month: 2, Days: 1},
+			},
+		},
+		Eras: []EraRecord{
+			{Ref: 1, Name: "Founding", SortOrder: 1, StartDate: "1 Thawmoon 1200", EndDate: text("31 Frostfall 1202")},
+			{Ref: 2, Name: "Long War", SortOrder: 2, StartDate: "1 Thawmoon 1203"},
+		},
+		Events: []EventRecord{
+			{Ref: 1, Title: "The crowning", StartDate: "3 Thawmoon 1201", Era: "Founding", Importance: models.ImportanceMajor, SortOrder: 1},
+			{Ref: 2, Title: "Siege of Varn", Description: "The walls hold", StartDate: "15 Frostfall 1203", EndDate: text("20 Frostfall 1203"),
+				Era: "Long War", Importance: models.ImportanceCritical, SortOrder: 2, ImageID: "siege", ImageURL: "https://example.com/siege.png", Details: "{}"},
+			{Ref: 3, Title: "A rumour", StartDate: "someday", Importance: models.ImportanceMinor, SortOrder: 3},
+		},
+		EventLinks: []EventLinkRecord{
+			{From: 2, To: 1, Type: models.LinkCaused, Note: "Old grudges"},
+			{From: 1, To: 3, Type: models.LinkConcurrent},
+		},
+		Locations: []LocationRecord{
+			{Ref: 1, Name: "Varn", LocationType: "city", Population: 12000, WealthLevel: "rich"},
+		},
+		Organizations: []OrganizationRecord{
+			{Ref: 1, Name: "Iron Guild", OrgType: "guild", PowerLevel: 7, IsActive: true},
+			{Ref: 2, Name: "Old Watch", OrgType: "military", PowerLevel: 2, IsActive: false},
+		},
+		Ranks: []RankRecord{
+			{Ref: 1, Organization: 1, Title: "Master", AuthorityLevel: 10, SortOrder: 1},
+			{Ref: 2, Organization: 2, Title: "Sergeant", AuthorityLevel: 5, SortOrder: 1},
+		},
+		NPCs: []NPCRecord{
+			{Ref: 1, Location: ref(1), Name: "Aldric", Age: 40, Gender: "male", Profession: "smith", SocialClass: "artisan", IsAlive: true},
+			{Ref: 2, Name: "Mira", Age: 70, Gender: "female", Profession: "guard", SocialClass: "commoner", IsAlive: false},
+		},
+		Memberships: []MembershipRecord{
+			{Ref: 1, NPC: 1, Organization: 1, Rank: 1, Status: "active", JoinedAt: joined},
+			{Ref: 2, NPC: 2, Organization: 2, Rank: 2, Status: "former", JoinedAt: joined, LeftAt: &left, Notes: "Retired"},
+		},
+		Relationships: []RelationshipRecord{
+			{Ref: 1, FromNPC: 1, ToNPC: 2, RelationshipType: "family", RelationshipSubtype: "parent", Strength: 8, IsPublic: true},
+			{Ref: 2, FromNPC: 2, ToNPC: 1, RelationshipType: "rival", Strength: 3, IsPublic: false, StartedAt: &joined},
+		},
+		Participants: []ParticipantRecord{
+			{Event: 2, NPC: ref(1), Role: "instigator"},
+			{Event: 2, NPC: ref(2), Role: models.DefaultEventRole},
+			{Event: 1, Organization: ref(1)},
+			{Event: 2, Organization: ref(2)},
+			{Event: 2, Location: ref(1)},
+		},
+		GenerationConfigs: []GenerationConfigRecord{
+			{Ref: 1, Seed: 42, PopulationSize: 50, FamilyDensity: 0.5, OrganizationCount: 2, SocialClassDist: "{}", GeneratedAt: joined},
+		},
+		Lore: []LoreRecord{
+			{Ref: 1, Title: "The Iron Guild", Summary: "Smiths", Body: "Founded by [[npc:Aldric]] in [[Varn|the city]].", Categories: []string{"factions"},
+				ImageID: "guild", ImageURL: "https://example.com/guild.png"},
+			{Ref: 2, Title: "Varn", Body: "A city.", Categories: []string{}},
+		},
+		Stories: []StoryRecord{
+			{Ref: 1, Title: "The long night", Category: "tale", Excerpt: "Snow fell", CoverImageID: "night", Status: models.StoryPublished, PublishedAt: &published,
+				Events: []int{1, 2}, Chapters: []ChapterRecord{{Title: "One", Body: "It was cold."}, {Title: "Two", Body: "It was colder."}}},
+			{Ref: 2, Title: "Notes", Status: models.StoryDraft, Events: []int{}, Chapters: []ChapterRecord{}},
+		},
+	}
+}
+
+func importWorld(t *testing.T, db *gorm.DB, archive *WorldArchive) *ImportSummary {
+	t.Helper()
+
+	if err := archive.Validate(); err != nil {
+		t.Fatalf("Invalid archive: %v", err)
+	}
+	var summary *ImportSummary
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		summary, err = ImportWorld(tx, archive, owner.ID)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	return summary
+}
+
+// Compares archives as JSON, ignoring when they were exported
+func sameArchive(t *testing.T, got, want *WorldArchive) {
+	t.Helper()
+
+	gotCopy, wantCopy := *got, *want
+	gotCopy.ExportedAt, wantCopy.ExportedAt = time.Time{}, time.Time{}
+	gotJSON, _ := json.MarshalIndent(gotCopy, "", "  ")
+	wantJSON, _ := json.MarshalIndent(wantCopy, "", "  ")
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("Archives differ\ngot:\n%s\nwant:\n%s", gotJSON, wantJSON)
+	}
+}
+
+func TestWorldArchiveRoundTrip(t *testing.T) {
+	db := openWorldDB(t)
+	original := testWorldArchive()
+
+	summary := importWorld(t, db, original)
+	want := ImportSummary{
+		WorldID: summary.WorldID, Eras: 2, Events: 3, EventLinks: 2, Participants: 5, Locations: 1, Organizations: 2,
+		Ranks: 2, NPCs: 2, Memberships: 2, Relationships: 2, GenerationConfigs: 1, Lore: 2, Stories: 2, Chapters: 2,
+		Refs: summary.Refs,
+	}
+	if !reflect.DeepEqual(*summary, want) {
+		t.Errorf("Imported %+v, want %+v", *summary, want)
+	}
+
+	exported, err := ExportWorld(db, summary.WorldID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameArchive(t, exported, original)
+
+	// The export reads back the rows the import created, under the same refs
+	if len(summary.Refs.Eras) != 2 || len(summary.Refs.NPCs) != 2 || !reflect.DeepEqual(exported.Refs, summary.Refs) {
+		t.Errorf("Exported refs %+v, imported %+v", exported.Refs, summary.Refs)
+	}
+
+	// Importing the export again changes nothing either
+	again, err := ExportWorld(db, importWorld(t, db, exported).WorldID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameArchive(t, again, exported)
+}
+
+func TestImportWorldDerivedData(t *testing.T) {
+	db := openWorldDB(t)
+	worldID := importWorld(t, db, testWorldArchive()).WorldID
+
+	// Dates are numbered by the calendar and events find their era by name
+	var events []models.TimelineEvent
+	db.Where("world_id = ?", worldID).Order("id").Find(&events)
+	if len(events) != 3 {
+		t.Fatalf("Got %d events", len(events))
+	}
+	var eras []models.WorldEra
+	db.Where("world_id = ?", worldID).Order("id").Find(&eras)
+	if len(eras) != 2 || eras[0].StartDay == nil || eras[0].EndDay == nil || eras[1].EndDay != nil {
+		t.Errorf("Era days not set from the calendar: %+v", eras)
+	}
+	if events[1].StartDay == nil || events[1].DurationDays == nil || *events[1].DurationDays != 6 {
+		t.Errorf("Siege days not set from the calendar: %+v", events[1])
+	}
+	if events[2].StartDay != nil {
+		t.Errorf("Unreadable date got day %d", *events[2].StartDay)
+	}
+	if events[0].EraID == nil || *events[0].EraID != eras[0].ID || events[1].EraID == nil || *events[1].EraID != eras[1].ID {
+		t.Error("Events not linked to their eras")
+	}
+
+	// Wiki links are rebuilt from the lore bodies
+	var links []models.LoreLink
+	db.Where("world_id = ?", worldID).Order("id").Find(&links)
+	if len(links) != 2 || links[0].TargetName != "aldric" || links[1].TargetName != "varn" {
+		t.Errorf("Got lore links %+v", links)
+	}
+
+	// Reading times come from the chapters
+	var story models.Story
+	db.Where("world_id = ? AND title = ?", worldID, "The long night").First(&story)
+	if story.WordCount != 6 || story.ReadingMinutes < 1 {
+		t.Errorf("Story counted %d words, %d minutes", story.WordCount, story.ReadingMinutes)
+	}
+}
+
+func TestImportWorldSharesImages(t *testing.T) {
+	db := openWorldDB(t)
+	worldID := importWorld(t, db, testWorldArchive()).WorldID
+
+	// The copy keeps the original's image IDs, so the images stay in use
+	var world models.World
+	db.First(&world, worldID)
+	var event models.TimelineEvent
+	db.Where("world_id = ? AND title = ?", worldID, "Siege of Varn").First(&event)
+	var article models.LoreArticle
+	db.Where("world_id = ? AND title = ?", worldID, "The Iron Guild").First(&article)
+	var story models.Story
+	db.Where("world_id = ? AND title = ?", worldID, "The long night").First(&story)
+
+	for _, got := range []struct{ row, id, want string }{
+		{"world banner", world.BannerImageID, "banner"},
+		{"event", event.ImageID, "siege"},
+		{"lore article", article.ImageID, "guild"},
+		{"story cover", story.CoverImageID, "night"},
+	} {
+		if got.id != got.want {
+			t.Errorf("Imported %s has image %q, want %q", got.row, got.id, got.want)
+		}
+	}
+}
+
+func TestExportWorldStoryVisibility(t *testing.T) {
+	db := openWorldDB(t)
+	worldID := importWorld(t, db, testWorldArchive()).WorldID
+
+	// Imported stories are unreviewed, so only their owner and admins see them
+	db.Model(&models.Story{}).Where("world_id = ? AND status = ?", worldID, models.StoryPublished).Update("reviewed", true)
+
+	for _, test := range []struct {
+		name    string
+		user    *models.User
+		stories []string
+	}{
+		{"visitor", nil, []string{"The long night"}},
+		{"other user", &models.User{ID: 2}, []string{"The long night"}},
+		{"owner", owner, []string{"The long night", "Notes"}},
+		{"admin", &models.User{ID: 3, IsAdmin: true}, []string{"The long night", "Notes"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			exported, err := ExportWorld(db, worldID, test.user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, story := range exported.Stories {
+				titles = append(titles, story.Title)
+			}
+			if !slices.Equal(titles, test.stories) {
+				t.Errorf("Exported stories %v, want %v", titles, test.stories)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsOverlappingEras(t *testing.T) {
+	archive := testWorldArchive()
+	archive.Eras[1].StartDate = "1 Frostfall 1202"
+	if err := archive.Validate(); err == nil || err.Error() != `era "Founding" overlaps the era "Long War"` {
+		t.Errorf("Got %v", err)
+	}
+
+	// Without a calendar the dates are free text and never overlap
+	archive.Calendar = nil
+	if err := archive.Validate(); err != nil {
+		t.Errorf("Got %v", err)
+	}
+}
+
+func TestValidateRejectsOtherVersions(t *testing.T) {
+	for _, version := range []int{0, 2, 7} {
+		archive := testWorldArchive()
+		archive.Version = version
+		if err := archive.Validate(); err == nil || err.Error() != fmt.Sprintf("unsupported archive version %d", version) {
+			t.Errorf("Version %d: got %v", version, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// StreamTicketTTL is how long a stream ticket can be used to open (or reopen)
+// a session's event stream
+const StreamTicketTTL = 2 * time.Minute
+
+const streamTicketAudience = "session-stream"
+
+// StreamTicketClaims let a browser EventSource, which can't send headers,
+// open one session's stream without putting a long-lived credential in the URL
+type StreamTicketClaims struct {
+	SessionID uint `json:"session_id"`
+	jwt.RegisteredClaims
+}
+
+// Tickets are signed with their own key so they can never pass as a login token
+func streamTicketKey() []byte {
+	return []byte("stream-ticket:" + os.Getenv("JWT_SECRET"))
+}
+
+func GenerateStreamTicket(sessionID uint) (string, time.Time, error) {
+	expirationTime := time.Now().Add(StreamTicketTTL)
+
+	claims := &StreamTicketClaims{
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{streamTicketAudience},
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	ticket, err := token.SignedString(streamTicketKey())
+	return ticket, expirationTime, err
+}
+
+// ValidateStreamTicket checks a ticket is unexpired and was issued for the session
+func ValidateStreamTicket(ticket string, sessionID uint) error {
+	claims := &StreamTicketClaims{}
+
+	token, err := jwt.ParseWithClaims(ticket, claims, func(token *jwt.Token) (interface{}, error) {
+		return streamTicketKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(streamTicketAudience), jwt.WithExpirationRequired())
+
+	if err != nil {
+		return err
+	}
+
+	if !token.Valid || claims.SessionID != sessionID {
+		return errors.New("invalid ticket")
+	}
+
+	return nil
+}
@@ -0,0 +1,338 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits keep a definition small enough to parse and store comfortably
+const (
+	MaxMonths    = 100
+	MaxMonthDays = 1000
+	MaxWeekdays  = 100
+	MaxEpochs    = 20
+	MaxYear      = 100_000_000 // Either side of year 1, keeps day numbers in range
+)
+
+// Date precisions, from coarsest to finest
+const (
+	PrecisionYear  = "year"
+	PrecisionMonth = "month"
+	PrecisionDay   = "day"
+)
+
+type Month struct {
+	Name string `json:"name"`
+	Days int    `json:"days"`
+}
+
+// Epoch is a way of counting years, such as "After the Reckoning" (AR).
+// StartYear is the absolute year that epoch year 1 falls on. Backwards
+// epochs count down into the past from the year before StartYear, like BC.
+type Epoch struct {
+	Name         string `json:"name"`
+	Abbreviation string `json:"abbreviation"`
+	StartYear    int    `json:"start_year"`
+	Backwards    bool   `json:"backwards"`
+}
+
+// LeapRule adds Days to one month in leap years. Years divisible by Every
+// are leap years, except those divisible by Except, unless they are also
+// divisible by Unless. The Gregorian rule is Every 4, Except 100, Unless 400.
+type LeapRule struct {
+	Every  int `json:"every"`
+	Except int `json:"except,omitempty"`
+	Unless int `json:"unless,omitempty"`
+	Month  int `json:"month"` // 1-based month that gains the days
+	Days   int `json:"days"`
+}
+
+// Calendar describes how a world counts days. Dates map onto a single day
+// number: day 0 is the first day of the first month of absolute year 1, and
+// earlier days are negative, so day numbers sort chronologically.
+type Calendar struct {
+	Months       []Month   `json:"months"`
+	Weekdays     []string  `json:"weekdays"`
+	FirstWeekday int       `json:"first_weekday"` // Index into Weekdays of day 0
+	Epochs       []Epoch   `json:"epochs"`
+	Leap         *LeapRule `json:"leap,omitempty"`
+}
+
+// Date is a parsed date. Year is absolute; Month and Day are 1-based and
+// zero when the date isn't that precise.
+type Date struct {
+	Year      int    `json:"year"`
+	Month     int    `json:"month,omitempty"`
+	Day       int    `json:"day,omitempty"`
+	Precision string `json:"precision"`
+}
+
+// Validate checks a definition is complete and consistent
+func (c Calendar) Validate() error {
+	if len(c.Months) == 0 {
+		return fmt.Errorf("a calendar needs at least one month")
+	}
+	if len(c.Months) > MaxMonths {
+		return fmt.Errorf("a calendar can have at most %d months", MaxMonths)
+	}
+	names := make(map[string]bool)
+	for i, month := range c.Months {
+		name := strings.TrimSpace(month.Name)
+		if name == "" {
+			return fmt.Errorf("month %d has no name", i+1)
+		}
+		if isNumber(name) {
+			return fmt.Errorf("month name %q can't be a number", name)
+		}
+		if names[strings.ToLower(name)] {
+			return fmt.Errorf("month name %q is used twice", name)
+		}
+		names[strings.ToLower(name)] = true
+		if month.Days < 1 || month.Days > MaxMonthDays {
+			return fmt.Errorf("%s must have between 1 and %d days", name, MaxMonthDays)
+		}
+	}
+
+	if len(c.Weekdays) > MaxWeekdays {
+		return fmt.Errorf("a calendar can have at most %d weekdays", MaxWeekdays)
+	}
+	weekdays := make(map[string]bool)
+	for i, weekday := range c.Weekdays {
+		name := strings.TrimSpace(weekday)
+		if name == "" {
+			return fmt.Errorf("weekday %d has no name", i+1)
+		}
+		if weekdays[strings.ToLower(name)] {
+			return fmt.Errorf("weekday %q is used twice", name)
+		}
+		weekdays[strings.ToLower(name)] = true
+	}
+	if len(c.Weekdays) > 0 && (c.FirstWeekday < 0 || c.FirstWeekday >= len(c.Weekdays)) {
+		return fmt.Errorf("first_weekday must be between 0 and %d", len(c.Weekdays)-1)
+	}
+
+	if len(c.Epochs) > MaxEpochs {
+		return fmt.Errorf("a calendar can have at most %d epochs", MaxEpochs)
+	}
+	epochs := make(map[string]bool)
+	forward := false
+	for i, epoch := range c.Epochs {
+		if strings.TrimSpace(epoch.Name) == "" {
+			return fmt.Errorf("epoch %d has no name", i+1)
+		}
+		for _, name := range []string{epoch.Name, epoch.Abbreviation} {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if key == "" {
+				continue
+			}
+			if isNumber(key) {
+				return fmt.Errorf("epoch name %q can't be a number", name)
+			}
+			if epochs[key] || names[key] {
+				return fmt.Errorf("%q is used by more than one month or epoch", name)
+			}
+			epochs[key] = true
+		}
+		forward = forward || !epoch.Backwards
+	}
+	if len(c.Epochs) > 0 && !forward {
+		return fmt.Errorf("at least one epoch must count forwards")
+	}
+
+	if c.Leap != nil {
+		leap := c.Leap
+		if leap.Every < 1 {
+			return fmt.Errorf("leap.every must be at least 1")
+		}
+		if leap.Except != 0 && (leap.Except < 0 || leap.Except%leap.Every != 0) {
+			return fmt.Errorf("leap.except must be a multiple of leap.every")
+		}
+		if leap.Unless != 0 && (leap.Except == 0 || leap.Unless < 0 || leap.Unless%leap.Except != 0) {
+			return fmt.Errorf("leap.unless must be a multiple of leap.except")
+		}
+		if leap.Month < 1 || leap.Month > len(c.Months) {
+			return fmt.Errorf("leap.month must be between 1 and %d", len(c.Months))
+		}
+		if leap.Days < 1 || c.Months[leap.Month-1].Days+leap.Days > MaxMonthDays {
+			return fmt.Errorf("leap.days must be at least 1 and keep the month under %d days", MaxMonthDays)
+		}
+	}
+	return nil
+}
+
+// IsLeap reports whether an absolute year is a leap year
+func (c Calendar) IsLeap(year int) bool {
+	if c.Leap == nil || year%c.Leap.Every != 0 {
+		return false
+	}
+	if c.Leap.Except != 0 && year%c.Leap.Except == 0 {
+		return c.Leap.Unless != 0 && year%c.Leap.Unless == 0
+	}
+	return true
+}
+
+// MonthDays is the length of a 1-based month in an absolute year
+func (c Calendar) MonthDays(year, month int) int {
+	days := c.Months[month-1].Days
+	if c.Leap != nil && month == c.Leap.Month && c.IsLeap(year) {
+		days += c.Leap.Days
+	}
+	return days
+}
+
+// YearDays is the length of an absolute year
+func (c Calendar) YearDays(year int) int {
+	days := c.baseYearDays()
+	if c.IsLeap(year) {
+		days += c.Leap.Days
+	}
+	return days
+}
+
+func (c Calendar) baseYearDays() int {
+	days := 0
+	for _, month := range c.Months {
+		days += month.Days
+	}
+	return days
+}
+
+// Day number of the first day of an absolute year
+func (c Calendar) yearStart(year int) int64 {
+	n := int64(year - 1)
+	days := n * int64(c.baseYearDays())
+	if c.Leap != nil {
+		// Leap years in [1, year-1], negative when counting back from year 1
+		leaps := floorDiv(n, int64(c.Leap.Every))
+		if c.Leap.Except != 0 {
+			leaps -= floorDiv(n, int64(c.Leap.Except))
+		}
+		if c.Leap.Unless != 0 {
+			leaps += floorDiv(n, int64(c.Leap.Unless))
+		}
+		days += leaps * int64(c.Leap.Days)
+	}
+	return days
+}
+
+// Span is the first and last day a date covers: one day for a full date, the
+// whole month or year for less precise ones
+func (c Calendar) Span(d Date) (int64, int64) {
+	start := c.yearStart(d.Year)
+	if d.Precision == PrecisionYear {
+		return start, start + int64(c.YearDays(d.Year)) - 1
+	}
+
+	for month := 1; month < d.Month; month++ {
+		start += int64(c.MonthDays(d.Year, month))
+	}
+	if d.Precision == PrecisionMonth {
+		return start, start + int64(c.MonthDays(d.Year, d.Month)) - 1
+	}
+	start += int64(d.Day - 1)
+	return start, start
+}
+
+// FromDay turns a day number back into a full date
+func (c Calendar) FromDay(day int64) Date {
+	// Estimate the year from the average year length, then correct it
+	average := float64(c.baseYearDays())
+	if c.Leap != nil {
+		average += float64(c.Leap.Days) / float64(c.Leap.Every)
+	}
+	year := int(float64(day)/average) + 1
+	for c.yearStart(year) > day {
+		year--
+	}
+	for c.yearStart(year+1) <= day {
+		year++
+	}
+
+	rest := int(day - c.yearStart(year))
+	month := 1
+	for rest >= c.MonthDays(year, month) {
+		rest -= c.MonthDays(year, month)
+		month++
+	}
+	return Date{Year: year, Month: month, Day: rest + 1, Precision: PrecisionDay}
+}
+
+// Weekday names the day of the week of a day number, empty when the
+// calendar has no weeks
+func (c Calendar) Weekday(day int64) string {
+	n := int64(len(c.Weekdays))
+	if n == 0 {
+		return ""
+	}
+	return c.Weekdays[int(mod(day+int64(c.FirstWeekday), n))]
+}
+
+// Format writes a date the way Parse reads it, counting the year in the
+// epoch it falls in
+func (c Calendar) Format(d Date) string {
+	year := c.FormatYear(d.Year)
+	switch d.Precision {
+	case PrecisionYear:
+		return year
+	case PrecisionMonth:
+		return c.Months[d.Month-1].Name + " " + year
+	}
+	return fmt.Sprintf("%d %s %s", d.Day, c.Months[d.Month-1].Name, year)
+}
+
+// FormatYear writes an absolute year in the latest forward epoch that has
+// started, or the backwards epoch it falls in
+func (c Calendar) FormatYear(year int) string {
+	var best *Epoch
+	for i := range c.Epochs {
+		epoch := &c.Epochs[i]
+		if !epoch.Backwards && epoch.StartYear <= year && (best == nil || best.Backwards || epoch.StartYear > best.StartYear) {
+			best = epoch
+		}
+	}
+	if best == nil {
+		for i := range c.Epochs {
+			epoch := &c.Epochs[i]
+			if epoch.Backwards && epoch.StartYear > year && (best == nil || epoch.StartYear < best.StartYear) {
+				best = epoch
+			}
+		}
+	}
+	if best == nil {
+		// Before every epoch: count back from the earliest one
+		for i := range c.Epochs {
+			epoch := &c.Epochs[i]
+			if !epoch.Backwards && (best == nil || epoch.StartYear < best.StartYear) {
+				best = epoch
+			}
+		}
+	}
+	if best == nil {
+		return fmt.Sprintf("%d", year)
+	}
+
+	count := year - best.StartYear + 1
+	if best.Backwards {
+		count = best.StartYear - year
+	}
+	return fmt.Sprintf("%d %s", count, best.label())
+}
+
+func (e Epoch) label() string {
+	if e.Abbreviation != "" {
+		return e.Abbreviation
+	}
+	return e.Name
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+func mod(a, b int64) int64 {
+	return ((a % b) + b) % b
+}
@@ -0,0 +1,173 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+var gregorian = Calendar{
+	Months: []Month{
+		{"January", 31}, {"February", 28}, {"March", 31}, {"April", 30}, {"May", 31}, {"June", 30},
+		{"July", 31}, {"August", 31}, {"September", 30}, {"October", 31}, {"November", 30}, {"December", 31},
+	},
+	Weekdays:     []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"},
+	FirstWeekday: 0, // 1 January 1 AD was a Monday
+	Epochs: []Epoch{
+		{Name: "Anno Domini", Abbreviation: "AD", StartYear: 1},
+		{Name: "Before Christ", Abbreviation: "BC", StartYear: 1, Backwards: true},
+	},
+	Leap: &LeapRule{Every: 4, Except: 100, Unless: 400, Month: 2, Days: 1},
+}
+
+// Absolute years only, so years before 1 are written as 0, -1 and so on
+var plain = Calendar{
+	Months: []Month{{"Thawmoon", 30}, {"Frostfall", 31}},
+}
+
+// Years count down before the Reckoning in year 1000 and up after it
+var reckoning = Calendar{
+	Months:   []Month{{"Thawmoon", 30}, {"High Sun", 31}, {"Frostfall", 29}},
+	Weekdays: []string{"Moonday", "Fireday", "Restday"},
+	Epochs: []Epoch{
+		{Name: "After the Reckoning", Abbreviation: "AR", StartYear: 1000},
+		{Name: "Before the Reckoning", Abbreviation: "BR", StartYear: 1000, Backwards: true},
+	},
+	Leap: &LeapRule{Every: 4, Month: 3, Days: 1},
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []Calendar{gregorian, plain, reckoning} {
+		if err := c.Validate(); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+
+	for _, test := range []struct {
+		calendar Calendar
+		err      string
+	}{
+		{Calendar{}, "a calendar needs at least one month"},
+		{Calendar{Months: []Month{{" ", 30}}}, "month 1 has no name"},
+		{Calendar{Months: []Month{{"12", 30}}}, `month name "12" can't be a number`},
+		{Calendar{Months: []Month{{"Thaw", 30}, {"thaw", 30}}}, `month name "thaw" is used twice`},
+		{Calendar{Months: []Month{{"Thaw", 0}}}, "Thaw must have between 1 and 1000 days"},
+		{Calendar{Months: []Month{{"Thaw", 30}}, Weekdays: []string{"Moonday"}, FirstWeekday: 1}, "first_weekday must be between 0 and 0"},
+		{Calendar{Months: []Month{{"Thaw", 30}}, Epochs: []Epoch{{Name: "Thaw"}}}, `"Thaw" is used by more than one month or epoch`},
+		{Calendar{Months: []Month{{"Thaw", 30}}, Epochs: []Epoch{{Name: "Old", Backwards: true}}}, "at least one epoch must count forwards"},
+		{Calendar{Months: []Month{{"Thaw", 30}}, Leap: &LeapRule{Every: 4, Except: 10, Month: 1, Days: 1}}, "leap.except must be a multiple of leap.every"},
+		{Calendar{Months: []Month{{"Thaw", 30}}, Leap: &LeapRule{Every: 4, Month: 2, Days: 1}}, "leap.month must be between 1 and 1"},
+	} {
+		got := ""
+		if err := test.calendar.Validate(); err != nil {
+			got = err.Error()
+		}
+		if got != test.err {
+			t.Errorf("Got error %q, want %q", got, test.err)
+		}
+	}
+}
+
+func TestGregorianMatchesTime(t *testing.T) {
+	// time counts proleptic Gregorian days too, so it can check day numbers
+	// and weekdays for any year it can represent
+	epoch := time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, date := range []time.Time{
+		epoch,
+		time.Date(1900, time.March, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC),
+	} {
+		day := (date.Unix() - epoch.Unix()) / (24 * 60 * 60)
+		d := Date{Year: date.Year(), Month: int(date.Month()), Day: date.Day(), Precision: PrecisionDay}
+		if start, end := gregorian.Span(d); start != day || end != day {
+			t.Errorf("%s: span %d-%d, want %d", date.Format(time.DateOnly), start, end, day)
+		}
+		if got := gregorian.Weekday(day); got != date.Weekday().String() {
+			t.Errorf("%s: weekday %s, want %s", date.Format(time.DateOnly), got, date.Weekday())
+		}
+	}
+}
+
+// Parse, Span, FromDay and Format agree with each other
+func TestRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		calendar Calendar
+		dates    []string // Formatted the way Format writes them
+	}{
+		{"gregorian", gregorian, []string{
+			"1 January 1 AD", "31 December 1 BC", "29 February 4 AD", "29 February 1 BC",
+			"28 February 1900 AD", "1 March 1900 AD", "29 February 2000 AD", "15 March 44 BC", "1 January 10000 BC",
+		}},
+		{"plain", plain, []string{
+			"1 Thawmoon 1", "31 Frostfall 0", "1 Thawmoon 0", "15 Frostfall -40", "1 Thawmoon -100000",
+		}},
+		{"reckoning", reckoning, []string{
+			"1 Thawmoon 1 AR", "30 Frostfall 1 AR", "29 Frostfall 1 BR", "30 Frostfall 4 BR", "1 Thawmoon 1000 BR",
+			"1 Thawmoon 1001 BR", "1 High Sun 250 AR", "29 Frostfall 4 AR",
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, text := range test.dates {
+				d, err := test.calendar.Parse(text)
+				if err != nil {
+					t.Errorf("%v", err)
+					continue
+				}
+				start, end := test.calendar.Span(d)
+				if start != end {
+					t.Errorf("%q spans %d-%d", text, start, end)
+				}
+				back := test.calendar.FromDay(start)
+				if back != d {
+					t.Errorf("%q is day %d, which is %+v, want %+v", text, start, back, d)
+				}
+				if got := test.calendar.Format(back); got != text {
+					t.Errorf("%q formatted back as %q", text, got)
+				}
+			}
+
+			// Consecutive days run through months and years without gaps
+			first, _ := test.calendar.Span(Date{Year: -3, Precision: PrecisionYear})
+			previous := test.calendar.FromDay(first - 1)
+			for day := first; day < first+3000; day++ {
+				d := test.calendar.FromDay(day)
+				if start, _ := test.calendar.Span(d); start != day {
+					t.Fatalf("Day %d is %+v, which starts on day %d", day, d, start)
+				}
+				parsed, err := test.calendar.Parse(test.calendar.Format(d))
+				if err != nil || parsed != d {
+					t.Fatalf("Day %d formats as %q, which parses as %+v (%v)", day, test.calendar.Format(d), parsed, err)
+				}
+				if d.Day != 1 && d.Day != previous.Day+1 || d.Day == 1 && d.Month == previous.Month && d.Year == previous.Year {
+					t.Fatalf("Day %d is %+v after %+v", day, d, previous)
+				}
+				previous = d
+			}
+		})
+	}
+}
+
+func TestSpan(t *testing.T) {
+	for _, test := range []struct {
+		input      string
+		start, end string
+	}{
+		{"2024 AD", "1 January 2024 AD", "31 December 2024 AD"},
+		{"February 2024", "1 February 2024 AD", "29 February 2024 AD"},
+		{"February 1900", "1 February 1900 AD", "28 February 1900 AD"},
+		{"1 BC", "1 January 1 BC", "31 December 1 BC"},
+	} {
+		d, err := gregorian.Parse(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start, end := gregorian.Span(d)
+		if got := gregorian.Format(gregorian.FromDay(start)); got != test.start {
+			t.Errorf("%q starts on %q, want %q", test.input, got, test.start)
+		}
+		if got := gregorian.Format(gregorian.FromDay(end)); got != test.end {
+			t.Errorf("%q ends on %q, want %q", test.input, got, test.end)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package calendar
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseError explains why a date was rejected. Handlers return it as a 400.
+type ParseError struct {
+	Input  string
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%q is not a valid date: %s", e.Input, e.Reason)
+}
+
+// 1203-03-15, 1203-03 or -40, optionally followed by an epoch
+var numericDate = regexp.MustCompile(`^(-?\d+)(?:-(\d{1,3})(?:-(\d{1,4}))?)?(?:\s+(.+))?$`)
+
+var separators = strings.NewReplacer(",", " ", ".", " ")
+
+// Parse reads a date in one of these forms, ignoring case:
+//
+//	15 Frostfall 1203 AR    day, month name, year
+//	Frostfall 15, 1203      month name, day, year
+//	Frostfall 1203          month and year
+//	1203 AR, Year 1203      year alone
+//	1203-03-15, 1203-03     numeric, optionally followed by an epoch
+//
+// Years without an epoch count in the first forward epoch.
+func (c Calendar) Parse(input string) (Date, error) {
+	text := strings.Join(strings.Fields(input), " ")
+	if text == "" {
+		return Date{}, &ParseError{Input: input, Reason: "the date is empty"}
+	}
+	fail := func(format string, args ...interface{}) (Date, error) {
+		return Date{}, &ParseError{Input: input, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	// Numeric dates, unless what follows is a month name
+	match := numericDate.FindStringSubmatch(text)
+	var epoch *Epoch
+	var err error
+	if match != nil {
+		epoch, err = c.findEpoch(match[4])
+	}
+	if match != nil && (err == nil || match[2] != "") {
+		if err != nil {
+			return fail("%s", err)
+		}
+		year, err := strconv.Atoi(match[1])
+		if err != nil {
+			return fail("years can be at most %d", MaxYear)
+		}
+		d := Date{Year: c.absoluteYear(year, epoch), Precision: PrecisionYear}
+		if match[2] != "" {
+			d.Month, _ = strconv.Atoi(match[2])
+			d.Precision = PrecisionMonth
+			if d.Month < 1 || d.Month > len(c.Months) {
+				return fail("month %d does not exist, the calendar has %d months", d.Month, len(c.Months))
+			}
+		}
+		if match[3] != "" {
+			d.Day, _ = strconv.Atoi(match[3])
+			d.Precision = PrecisionDay
+		}
+		return c.check(input, d)
+	}
+
+	// The epoch comes last, the longest month name that matches is the month
+	epoch = nil
+	words := strings.Fields(strings.ToLower(separators.Replace(text)))
+	if len(words) > 1 {
+		for n := len(words) - 1; n >= 1 && epoch == nil; n-- {
+			if found, err := c.findEpoch(strings.Join(words[n:], " ")); err == nil && found != nil {
+				epoch = found
+				words = words[:n]
+			}
+		}
+	}
+	if len(words) > 0 && words[0] == "year" {
+		words = words[1:]
+	}
+
+	month := 0
+	rest := " " + strings.Join(words, " ") + " "
+	longest := ""
+	for i, m := range c.Months {
+		name := strings.ToLower(strings.Join(strings.Fields(m.Name), " "))
+		if strings.Contains(rest, " "+name+" ") && len(name) > len(longest) {
+			month, longest = i+1, name
+		}
+	}
+
+	var numbers []int
+	var unknown []string
+	before := 0 // Numbers that came before the month name
+	if month > 0 {
+		parts := strings.SplitN(rest, " "+longest+" ", 2)
+		for i, part := range parts {
+			for _, word := range strings.Fields(part) {
+				if n, ok := dayNumber(word); ok {
+					numbers = append(numbers, n)
+					if i == 0 {
+						before++
+					}
+				} else if word != "of" {
+					unknown = append(unknown, word)
+				}
+			}
+		}
+	} else {
+		for _, word := range words {
+			if n, err := strconv.Atoi(word); err == nil {
+				numbers = append(numbers, n)
+			} else {
+				unknown = append(unknown, word)
+			}
+		}
+	}
+
+	if len(unknown) > 0 {
+		if month == 0 && len(numbers) > 0 {
+			return fail("%q is not a month or epoch; months are %s%s", strings.Join(unknown, " "), c.monthNames(), c.epochHint())
+		}
+		return fail("did not understand %q; expected a date like %q", strings.Join(unknown, " "), c.Example())
+	}
+
+	var d Date
+	switch {
+	case month == 0 && len(numbers) == 1:
+		d = Date{Year: numbers[0], Precision: PrecisionYear}
+	case month > 0 && len(numbers) == 1 && before == 0:
+		d = Date{Year: numbers[0], Month: month, Precision: PrecisionMonth}
+	case month > 0 && len(numbers) == 2 && before <= 1:
+		// The year always comes last, the day either side of the month
+		d = Date{Year: numbers[1], Month: month, Day: numbers[0], Precision: PrecisionDay}
+	case month == 0 && len(numbers) == 0:
+		return fail("there is no year")
+	default:
+		return fail("expected a date like %q", c.Example())
+	}
+	d.Year = c.absoluteYear(d.Year, epoch)
+	return c.check(input, d)
+}
+
+// Example is a sample full date in this calendar
+func (c Calendar) Example() string {
+	return c.Format(Date{Year: c.defaultEpochStart() + 99, Month: 1, Day: 1, Precision: PrecisionDay})
+}
+
+// Rejects days past the end of their month and years too far out to count
+func (c Calendar) check(input string, d Date) (Date, error) {
+	if d.Year > MaxYear || d.Year < -MaxYear {
+		return Date{}, &ParseError{Input: input, Reason: fmt.Sprintf("years can be at most %d", MaxYear)}
+	}
+	if d.Precision != PrecisionDay {
+		return d, nil
+	}
+	days := c.MonthDays(d.Year, d.Month)
+	if d.Day < 1 || d.Day > days {
+		return Date{}, &ParseError{Input: input, Reason: fmt.Sprintf("%s has %d days in %s", c.Months[d.Month-1].Name, days, c.FormatYear(d.Year))}
+	}
+	return d, nil
+}
+
+// Finds an epoch by name or abbreviation. An empty name is no epoch.
+func (c Calendar) findEpoch(name string) (*Epoch, error) {
+	key := strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if key == "" {
+		return nil, nil
+	}
+	for i := range c.Epochs {
+		epoch := &c.Epochs[i]
+		if strings.ToLower(strings.TrimSpace(epoch.Name)) == key || strings.ToLower(strings.TrimSpace(epoch.Abbreviation)) == key {
+			return epoch, nil
+		}
+	}
+	if len(c.Epochs) == 0 {
+		return nil, fmt.Errorf("%q is not an epoch, this calendar has none", name)
+	}
+	return nil, fmt.Errorf("%q is not an epoch%s", name, c.epochHint())
+}
+
+// Turns a year counted in an epoch into an absolute year. A nil epoch means
+// the first forward epoch, or absolute years when there are no epochs.
+func (c Calendar) absoluteYear(year int, epoch *Epoch) int {
+	if epoch == nil {
+		for i := range c.Epochs {
+			if !c.Epochs[i].Backwards {
+				epoch = &c.Epochs[i]
+				break
+			}
+		}
+	}
+	if epoch == nil {
+		return year
+	}
+	if epoch.Backwards {
+		return epoch.StartYear - year
+	}
+	return epoch.StartYear + year - 1
+}
+
+func (c Calendar) defaultEpochStart() int {
+	return c.absoluteYear(1, nil)
+}
+
+func (c Calendar) monthNames() string {
+	names := make([]string, len(c.Months))
+	for i, month := range c.Months {
+		names[i] = month.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func (c Calendar) epochHint() string {
+	if len(c.Epochs) == 0 {
+		return ""
+	}
+	names := make([]string, len(c.Epochs))
+	for i, epoch := range c.Epochs {
+		names[i] = epoch.label()
+	}
+	return "; epochs are " + strings.Join(names, ", ")
+}
+
+// Reads 15 as well as 1st, 2nd, 3rd and 15th
+func dayNumber(word string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		word = strings.TrimSuffix(word, suffix)
+	}
+	if !isNumber(word) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(word)
+	return n, err == nil
+}
+
+func isNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Range parses an event's start and optional end date into the first and
+// last day it covers
+func (c Calendar) Range(start string, end *string) (int64, int64, error) {
+	from, err := c.Parse(start)
+	if err != nil {
+		return 0, 0, err
+	}
+	first, last := c.Span(from)
+
+	if end != nil && strings.TrimSpace(*end) != "" {
+		to, err := c.Parse(*end)
+		if err != nil {
+			return 0, 0, err
+		}
+		_, last = c.Span(to)
+		if last < first {
+			return 0, 0, &ParseError{Input: *end, Reason: fmt.Sprintf("the end date is before the start date %q", start)}
+		}
+	}
+	return first, last, nil
+}
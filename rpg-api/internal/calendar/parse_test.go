@@ -0,0 +1,87 @@
+package calendar
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		calendar Calendar
+		input    string
+		want     Date
+	}{
+		{reckoning, "15 Frostfall 1203 AR", Date{Year: 2202, Month: 3, Day: 15, Precision: PrecisionDay}},
+		{reckoning, "frostfall 15th, 1203 after the reckoning", Date{Year: 2202, Month: 3, Day: 15, Precision: PrecisionDay}},
+		{reckoning, "2nd of high  sun 3", Date{Year: 1002, Month: 2, Day: 2, Precision: PrecisionDay}},
+		{reckoning, "Frostfall 1203", Date{Year: 2202, Month: 3, Precision: PrecisionMonth}},
+		{reckoning, "1203 AR", Date{Year: 2202, Precision: PrecisionYear}},
+		{reckoning, "Year 12", Date{Year: 1011, Precision: PrecisionYear}},
+		{reckoning, "12 BR", Date{Year: 988, Precision: PrecisionYear}},
+		{reckoning, "1203-03-15", Date{Year: 2202, Month: 3, Day: 15, Precision: PrecisionDay}},
+		{reckoning, "40-02 BR", Date{Year: 960, Month: 2, Precision: PrecisionMonth}},
+		{plain, "-40", Date{Year: -40, Precision: PrecisionYear}},
+		{plain, "0-02-31", Date{Year: 0, Month: 2, Day: 31, Precision: PrecisionDay}},
+	} {
+		got, err := test.calendar.Parse(test.input)
+		if err != nil || got != test.want {
+			t.Errorf("%q: got %+v (%v), want %+v", test.input, got, err, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		calendar Calendar
+		input    string
+		reason   string
+	}{
+		{reckoning, "  ", "the date is empty"},
+		{reckoning, "Year", "there is no year"},
+		{reckoning, "banana", `did not understand "banana"; expected a date like "1 Thawmoon 100 AR"`},
+		{reckoning, "the 2nd of High Sun 3", `did not understand "the"; expected a date like "1 Thawmoon 100 AR"`},
+		{reckoning, "15 Smarch 1203", `"smarch" is not a month or epoch; months are Thawmoon, High Sun, Frostfall; epochs are AR, BR`},
+		{reckoning, "1203 XY", `"xy" is not a month or epoch; months are Thawmoon, High Sun, Frostfall; epochs are AR, BR`},
+		{reckoning, "1203-03 XY", `"XY" is not an epoch; epochs are AR, BR`},
+		{plain, "1203-01 AR", `"AR" is not an epoch, this calendar has none`},
+		{reckoning, "1203-04", "month 4 does not exist, the calendar has 3 months"},
+		{reckoning, "Frostfall", `expected a date like "1 Thawmoon 100 AR"`},
+		{reckoning, "15 16 Frostfall 1203", `expected a date like "1 Thawmoon 100 AR"`},
+		{reckoning, "31 Frostfall 1203", "Frostfall has 29 days in 1203 AR"},
+		{reckoning, "30 Frostfall 1202", "Frostfall has 29 days in 1202 AR"},
+		{reckoning, "0 Thawmoon 1", "Thawmoon has 30 days in 1 AR"},
+		{gregorian, "29 February 1900", "February has 28 days in 1900 AD"},
+		{plain, "100000001", "years can be at most 100000000"},
+		{plain, "99999999999999999999", "years can be at most 100000000"},
+	} {
+		_, err := test.calendar.Parse(test.input)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%q: got %v, want a ParseError", test.input, err)
+			continue
+		}
+		if parseErr.Input != test.input || parseErr.Reason != test.reason {
+			t.Errorf("%q: got %q, want %q", test.input, parseErr.Reason, test.reason)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	end := "Frostfall 1203"
+	first, last, err := reckoning.Range("Thawmoon 1203", &end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reckoning.Format(reckoning.FromDay(first)); got != "1 Thawmoon 1203 AR" {
+		t.Errorf("Starts on %q", got)
+	}
+	if got := reckoning.Format(reckoning.FromDay(last)); got != "29 Frostfall 1203 AR" {
+		t.Errorf("Ends on %q", got)
+	}
+
+	end = "1202 AR"
+	_, _, err = reckoning.Range("Thawmoon 1203", &end)
+	if err == nil || err.Error() != `"1202 AR" is not a valid date: the end date is before the start date "Thawmoon 1203"` {
+		t.Errorf("Got %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+package dice
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits keep a single expression from doing unbounded work
+const (
+	MaxDicePerTerm      = 100
+	MaxSides            = 1000
+	MaxTerms            = 20
+	MaxExplosionsPerDie = 20
+)
+
+// Simple D6 outcome bands
+const (
+	OutcomeFailure = "Failure"
+	OutcomePartial = "Partial Success"
+	OutcomeSuccess = "Success"
+)
+
+// Term is one part of an expression: either a group of dice or a flat number
+type Term struct {
+	Sign      int  // +1 or -1
+	Count     int  // Number of dice, 0 for a flat number
+	Sides     int  // Sides per die
+	Exploding bool // Roll again and add when a die shows its maximum
+	Keep      int  // Dice to keep, 0 keeps them all
+	KeepLow   bool // Keep the lowest dice instead of the highest
+	Value     int  // Flat number when Count is 0
+}
+
+func (t Term) IsDice() bool {
+	return t.Count > 0
+}
+
+func (t Term) String() string {
+	if !t.IsDice() {
+		return strconv.Itoa(t.Value)
+	}
+	s := fmt.Sprintf("%dd%d", t.Count, t.Sides)
+	if t.Exploding {
+		s += "!"
+	}
+	if t.Keep > 0 {
+		if t.KeepLow {
+			s += fmt.Sprintf("kl%d", t.Keep)
+		} else {
+			s += fmt.Sprintf("kh%d", t.Keep)
+		}
+	}
+	return s
+}
+
+type Expression struct {
+	Terms []Term
+}
+
+func (e Expression) String() string {
+	var b strings.Builder
+	for i, term := range e.Terms {
+		if term.Sign < 0 {
+			b.WriteString("-")
+		} else if i > 0 {
+			b.WriteString("+")
+		}
+		b.WriteString(term.String())
+	}
+	return b.String()
+}
+
+// Die is a single die as rolled. Exploded dice list every roll that was added.
+type Die struct {
+	Sides int   `json:"sides"`
+	Rolls []int `json:"rolls"`
+	Value int   `json:"value"`
+	Kept  bool  `json:"kept"`
+}
+
+type TermResult struct {
+	Term  string `json:"term"`
+	Sign  int    `json:"sign"`
+	Dice  []Die  `json:"dice,omitempty"`
+	Total int    `json:"total"`
+}
+
+type Result struct {
+	Expression string       `json:"expression"`
+	Seed       int64        `json:"seed"`
+	Terms      []TermResult `json:"terms"`
+	Natural    int          `json:"natural"`  // Sum of kept dice
+	Modifier   int          `json:"modifier"` // Sum of flat numbers
+	Total      int          `json:"total"`
+	Outcome    string       `json:"outcome,omitempty"` // Set for Simple D6 rolls
+}
+
+// Parse reads expressions such as "d6", "2d6kh1+1", "3d6!" or "1d20-2".
+// Whitespace is ignored and "k" is shorthand for "kh".
+func Parse(input string) (Expression, error) {
+	s := strings.ToLower(strings.Join(strings.Fields(input), ""))
+	if s == "" {
+		return Expression{}, fmt.Errorf("expression is empty")
+	}
+
+	var expr Expression
+	pos := 0
+	for pos < len(s) {
+		sign := 1
+		if s[pos] == '+' || s[pos] == '-' {
+			if s[pos] == '-' {
+				sign = -1
+			}
+			pos++
+		} else if pos > 0 {
+			return Expression{}, fmt.Errorf("expected + or - at position %d", pos+1)
+		}
+
+		term, next, err := parseTerm(s, pos)
+		if err != nil {
+			return Expression{}, err
+		}
+		term.Sign = sign
+		expr.Terms = append(expr.Terms, term)
+		pos = next
+
+		if len(expr.Terms) > MaxTerms {
+			return Expression{}, fmt.Errorf("expression has more than %d terms", MaxTerms)
+		}
+	}
+
+	return expr, nil
+}
+
+func parseTerm(s string, pos int) (Term, int, error) {
+	start := pos
+	count, pos := readNumber(s, pos)
+
+	if pos >= len(s) || s[pos] != 'd' {
+		if pos == start {
+			return Term{}, pos, fmt.Errorf("expected a number or dice at position %d", start+1)
+		}
+		return Term{Value: count}, pos, nil
+	}
+
+	// "d6" means one die
+	if pos == start {
+		count = 1
+	}
+	pos++ // skip 'd'
+
+	sidesStart := pos
+	sides, pos := readNumber(s, pos)
+	if pos == sidesStart {
+		return Term{}, pos, fmt.Errorf("expected number of sides at position %d", pos+1)
+	}
+
+	term := Term{Count: count, Sides: sides}
+
+	if pos < len(s) && s[pos] == '!' {
+		term.Exploding = true
+		pos++
+	}
+
+	if pos < len(s) && s[pos] == 'k' {
+		pos++
+		if pos < len(s) && (s[pos] == 'h' || s[pos] == 'l') {
+			term.KeepLow = s[pos] == 'l'
+			pos++
+		}
+		keepStart := pos
+		term.Keep, pos = readNumber(s, pos)
+		if pos == keepStart {
+			return Term{}, pos, fmt.Errorf("expected number of dice to keep at position %d", pos+1)
+		}
+		if term.Keep == 0 {
+			return Term{}, pos, fmt.Errorf("must keep at least 1 die at position %d", keepStart+1)
+		}
+	}
+
+	if err := validateTerm(term); err != nil {
+		return Term{}, pos, err
+	}
+
+	return term, pos, nil
+}
+
+func validateTerm(term Term) error {
+	if term.Count < 1 || term.Count > MaxDicePerTerm {
+		return fmt.Errorf("number of dice must be between 1 and %d", MaxDicePerTerm)
+	}
+	if term.Sides < 1 || term.Sides > MaxSides {
+		return fmt.Errorf("dice must have between 1 and %d sides", MaxSides)
+	}
+	if term.Exploding && term.Sides < 2 {
+		return fmt.Errorf("exploding dice need at least 2 sides")
+	}
+	if term.Keep > term.Count {
+		return fmt.Errorf("cannot keep %d of %d dice", term.Keep, term.Count)
+	}
+	return nil
+}
+
+// Reads digits starting at pos, capped well above any valid limit so huge
+// inputs fail validation instead of overflowing
+func readNumber(s string, pos int) (int, int) {
+	value := 0
+	for pos < len(s) && s[pos] >= '0' && s[pos] <= '9' {
+		if value < 1000000 {
+			value = value*10 + int(s[pos]-'0')
+		}
+		pos++
+	}
+	return value, pos
+}
+
+// Roll evaluates the expression with a seeded generator so the same seed
+// always reproduces the same result
+func Roll(expr Expression, seed int64) Result {
+	rng := rand.New(rand.NewSource(seed))
+
+	result := Result{
+		Expression: expr.String(),
+		Seed:       seed,
+	}
+
+	for _, term := range expr.Terms {
+		termResult := TermResult{Term: term.String(), Sign: term.Sign}
+
+		if !term.IsDice() {
+			termResult.Total = term.Value
+			result.Modifier += term.Sign * term.Value
+		} else {
+			termResult.Dice = rollTerm(rng, term)
+			for _, die := range termResult.Dice {
+				if die.Kept {
+					termResult.Total += die.Value
+				}
+			}
+			result.Natural += term.Sign * termResult.Total
+		}
+
+		result.Terms = append(result.Terms, termResult)
+	}
+
+	result.Total = result.Natural + result.Modifier
+
+	if isSimpleD6(expr) {
+		result.Outcome = Interpret(result.Natural, result.Modifier)
+	}
+
+	return result
+}
+
+func rollTerm(rng *rand.Rand, term Term) []Die {
+	dice := make([]Die, term.Count)
+	for i := range dice {
+		die := Die{Sides: term.Sides, Kept: true}
+		for explosions := 0; ; explosions++ {
+			roll := rng.Intn(term.Sides) + 1
+			die.Rolls = append(die.Rolls, roll)
+			die.Value += roll
+			if !term.Exploding || roll != term.Sides || explosions >= MaxExplosionsPerDie {
+				break
+			}
+		}
+		dice[i] = die
+	}
+
+	if term.Keep > 0 && term.Keep < len(dice) {
+		order := make([]int, len(dice))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			if term.KeepLow {
+				return dice[order[a]].Value < dice[order[b]].Value
+			}
+			return dice[order[a]].Value > dice[order[b]].Value
+		})
+		for rank, index := range order {
+			dice[index].Kept = rank < term.Keep
+		}
+	}
+
+	return dice
+}
+
+// A Simple D6 roll resolves exactly one kept d6, e.g. "d6+1" or "2d6kh1"
+func isSimpleD6(expr Expression) bool {
+	kept := 0
+	for _, term := range expr.Terms {
+		if !term.IsDice() {
+			continue
+		}
+		if term.Sides != 6 || term.Exploding || term.Sign < 0 {
+			return false
+		}
+		if term.Keep > 0 {
+			kept += term.Keep
+		} else {
+			kept += term.Count
+		}
+	}
+	return kept == 1
+}
+
+// Interpret applies the Simple D6 bands: 1-2 failure, 3-4 partial success,
+// 5-6 success. Modifiers shift the thresholds, and under extreme conditions
+// (-2 or worse) the best possible result is a partial success.
+func Interpret(natural int, modifier int) string {
+	failureThreshold := 2 - modifier
+	partialThreshold := 4 - modifier
+
+	if natural <= failureThreshold {
+		return OutcomeFailure
+	}
+	if natural <= partialThreshold || modifier <= -2 {
+		return OutcomePartial
+	}
+	return OutcomeSuccess
+}
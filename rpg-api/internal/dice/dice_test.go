@@ -0,0 +1,196 @@
+package dice
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  string // Parsed expression written back out
+		err   string
+	}{
+		{"d6", "1d6", ""},
+		{" 2D6 K1 + 1 ", "2d6kh1+1", ""},
+		{"4d6kl3-2", "4d6kl3-2", ""},
+		{"3d6!", "3d6!", ""},
+		{"-1+d20", "-1+1d20", ""},
+		{"3", "3", ""},
+		{"", "", "expression is empty"},
+		{"   ", "", "expression is empty"},
+		{"d", "", "expected number of sides at position 2"},
+		{"2d6+", "", "expected a number or dice at position 5"},
+		{"2d6x", "", "expected + or - at position 4"},
+		{"1d6+d", "", "expected number of sides at position 6"},
+		{"2d6k", "", "expected number of dice to keep at position 5"},
+		{"2d6kh", "", "expected number of dice to keep at position 6"},
+		{"2d6k0", "", "must keep at least 1 die at position 5"},
+		{"2d6kl0+1", "", "must keep at least 1 die at position 6"},
+		{"0d6", "", "number of dice must be between 1 and 100"},
+		{"101d6", "", "number of dice must be between 1 and 100"},
+		{"1d0", "", "dice must have between 1 and 1000 sides"},
+		{"1d1001", "", "dice must have between 1 and 1000 sides"},
+		{"1d99999999999", "", "dice must have between 1 and 1000 sides"},
+		{"1d1!", "", "exploding dice need at least 2 sides"},
+		{"2d6k3", "", "cannot keep 3 of 2 dice"},
+		{"1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1", "", "expression has more than 20 terms"},
+	} {
+		expr, err := Parse(test.input)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != test.err {
+			t.Errorf("%q: got error %q, want %q", test.input, got, test.err)
+			continue
+		}
+		if err == nil && expr.String() != test.want {
+			t.Errorf("%q: parsed as %q, want %q", test.input, expr.String(), test.want)
+		}
+	}
+}
+
+func TestRollKeeps(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		low   bool
+		keep  int
+	}{
+		{"4d6kh3", false, 3},
+		{"4d6k1", false, 1},
+		{"4d6kl2", true, 2},
+		{"3d6", false, 3},
+	} {
+		expr, err := Parse(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for seed := int64(0); seed < 50; seed++ {
+			result := Roll(expr, seed)
+			dice := result.Terms[0].Dice
+
+			var kept, dropped []int
+			for _, die := range dice {
+				if die.Kept {
+					kept = append(kept, die.Value)
+				} else {
+					dropped = append(dropped, die.Value)
+				}
+			}
+			if len(kept) != test.keep {
+				t.Fatalf("%q seed %d: kept %d dice, want %d", test.input, seed, len(kept), test.keep)
+			}
+			// Every kept die beats (or for low, undercuts) every dropped one
+			for _, k := range kept {
+				for _, d := range dropped {
+					if !test.low && k < d || test.low && k > d {
+						t.Errorf("%q seed %d: kept %v and dropped %v", test.input, seed, kept, dropped)
+					}
+				}
+			}
+			sum := 0
+			for _, k := range kept {
+				sum += k
+			}
+			if result.Natural != sum || result.Total != sum {
+				t.Errorf("%q seed %d: natural %d, total %d, want %d", test.input, seed, result.Natural, result.Total, sum)
+			}
+		}
+	}
+}
+
+func TestRollIsReproducible(t *testing.T) {
+	expr, err := Parse("3d6!kh2+1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := Roll(expr, 42), Roll(expr, 42)
+	if first.Total != second.Total || first.Terms[0].Dice[0].Value != second.Terms[0].Dice[0].Value {
+		t.Errorf("Seed 42 rolled %+v then %+v", first, second)
+	}
+	if first.Modifier != 1 || first.Total != first.Natural+1 {
+		t.Errorf("Got %+v", first)
+	}
+}
+
+// Always yields the highest value, so every power-of-two die rolls its maximum
+type maxSource struct{}
+
+func (maxSource) Int63() int64 { return 1<<63 - 1 }
+func (maxSource) Seed(int64)   {}
+
+func TestExplosionsAreCapped(t *testing.T) {
+	rng := rand.New(maxSource{})
+	for _, test := range []struct {
+		term  Term
+		rolls int
+	}{
+		{Term{Count: 2, Sides: 4, Exploding: true}, MaxExplosionsPerDie + 1},
+		{Term{Count: 1, Sides: 2, Exploding: true}, MaxExplosionsPerDie + 1},
+		{Term{Count: 1, Sides: 4}, 1},
+	} {
+		for _, die := range rollTerm(rng, test.term) {
+			want := slices.Repeat([]int{test.term.Sides}, test.rolls)
+			if !slices.Equal(die.Rolls, want) || die.Value != test.term.Sides*test.rolls {
+				t.Errorf("%s rolled %v for %d, want %d rolls of %d", test.term, die.Rolls, die.Value, test.rolls, test.term.Sides)
+			}
+		}
+	}
+}
+
+func TestIsSimpleD6(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  bool
+	}{
+		{"d6", true},
+		{"1d6+1", true},
+		{"1d6-2", true},
+		{"2d6kh1", true},
+		{"3d6kl1+1", true},
+		{"2d6", false},
+		{"2d6kh2", false},
+		{"1d6+1d6", false},
+		{"1d8", false},
+		{"1d6!", false},
+		{"-1d6", false},
+		{"1d6+1d4", false},
+		{"3", false},
+	} {
+		expr, err := Parse(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isSimpleD6(expr); got != test.want {
+			t.Errorf("%q: got %v, want %v", test.input, got, test.want)
+		}
+		if outcome := Roll(expr, 1).Outcome; (outcome != "") != test.want {
+			t.Errorf("%q: got outcome %q", test.input, outcome)
+		}
+	}
+}
+
+func TestInterpret(t *testing.T) {
+	const (
+		F = OutcomeFailure
+		P = OutcomePartial
+		S = OutcomeSuccess
+	)
+	for _, test := range []struct {
+		modifier int
+		want     [6]string // Outcome for each natural roll 1-6
+	}{
+		{+1, [6]string{F, P, P, S, S, S}},
+		{0, [6]string{F, F, P, P, S, S}},
+		{-1, [6]string{F, F, F, P, P, S}},
+		{-2, [6]string{F, F, F, F, P, P}},
+	} {
+		for natural := 1; natural <= 6; natural++ {
+			if got := Interpret(natural, test.modifier); got != test.want[natural-1] {
+				t.Errorf("%d with modifier %+d: got %q, want %q", natural, test.modifier, got, test.want[natural-1])
+			}
+		}
+	}
+}
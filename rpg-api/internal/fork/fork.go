@@ -0,0 +1,339 @@
+// Package fork copies a world into a what-if branch and compares the branch
+// with the world it came from.
+package fork
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/archive"
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Kinds of change, as seen from the fork
+const (
+	Added   = "added"   // In the fork only
+	Removed = "removed" // Deleted from the fork but still in the source
+	Changed = "changed" // Edited in the fork
+)
+
+// Change is one row that differs between a fork and its source
+type Change struct {
+	Kind     string   `json:"kind"` // models.ForkEvent or models.ForkNPC
+	Change   string   `json:"change"`
+	ForkID   *uint    `json:"fork_id,omitempty"`   // Nil when removed
+	SourceID *uint    `json:"source_id,omitempty"` // Nil when added
+	Name     string   `json:"name"`
+	Fields   []string `json:"fields,omitempty"` // What a change edits
+	// The source also changed or deleted the row since the fork, so merging
+	// would overwrite that
+	Conflict bool `json:"conflict,omitempty"`
+}
+
+// Diff lists how a fork's events and NPCs differ from its source's
+type Diff struct {
+	ForkID   uint      `json:"fork_id"`
+	SourceID uint      `json:"source_id"`
+	ForkedAt time.Time `json:"forked_at"`
+	Events   []Change  `json:"events"`
+	NPCs     []Change  `json:"npcs"`
+}
+
+// Origins maps a fork's rows, by kind and then ID, to where they came from
+type Origins map[string]map[uint]models.WorldForkOrigin
+
+// Source is the ID of the source row a fork row came from, and whether it has one
+func (o Origins) Source(kind string, id uint) (uint, bool) {
+	origin, ok := o[kind][id]
+	return origin.SourceID, ok
+}
+
+// LoadOrigins reads where a fork's rows came from
+func LoadOrigins(db *gorm.DB, forkID uint) (Origins, error) {
+	var rows []models.WorldForkOrigin
+	if err := db.Where("world_id = ?", forkID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	origins := make(Origins)
+	for _, row := range rows {
+		if origins[row.Kind] == nil {
+			origins[row.Kind] = make(map[uint]models.WorldForkOrigin)
+		}
+		origins[row.Kind][row.ID] = row
+	}
+	return origins, nil
+}
+
+// Fork copies source and everything in it into a new world owned by user,
+// which remembers where it came from. It runs inside the caller's
+// transaction. The copy is made through a world archive, so it holds exactly
+// what an export by user would, leaving out stories user can't read.
+func Fork(tx *gorm.DB, source models.World, user *models.User, title string, now time.Time) (*archive.ImportSummary, error) {
+	copied, err := archive.ExportWorld(tx, source.ID, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world: %w", err)
+	}
+	copied.World.Title = title
+
+	summary, err := archive.ImportWorld(tx, copied, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.World{}).Where("id = ?", summary.WorldID).
+		Updates(map[string]interface{}{"forked_from_id": source.ID, "forked_at": now}).Error; err != nil {
+		return nil, fmt.Errorf("failed to record lineage: %w", err)
+	}
+
+	// Each fork row is the copy of the source row exported under its ref
+	var origins []models.WorldForkOrigin
+	for _, kind := range []struct {
+		name         string
+		source, fork map[int]uint
+	}{
+		{models.ForkEra, copied.Refs.Eras, summary.Refs.Eras},
+		{models.ForkEvent, copied.Refs.Events, summary.Refs.Events},
+		{models.ForkLocation, copied.Refs.Locations, summary.Refs.Locations},
+		{models.ForkOrganization, copied.Refs.Organizations, summary.Refs.Organizations},
+		{models.ForkNPC, copied.Refs.NPCs, summary.Refs.NPCs},
+	} {
+		if len(kind.source) != len(kind.fork) {
+			return nil, fmt.Errorf("copied %d of %d %s rows", len(kind.fork), len(kind.source), kind.name)
+		}
+		for ref, forkID := range kind.fork {
+			sourceID, ok := kind.source[ref]
+			if !ok {
+				return nil, fmt.Errorf("%s ref %d was not exported", kind.name, ref)
+			}
+			origins = append(origins, models.WorldForkOrigin{WorldID: summary.WorldID, Kind: kind.name, ID: forkID, SourceID: sourceID})
+		}
+	}
+
+	// Record what compared rows looked like, to tell later edits apart. The
+	// copies are read rather than the source, which may have moved on since
+	// the export.
+	locations := make(map[uint]uint, len(summary.Refs.Locations))
+	for ref, forkID := range summary.Refs.Locations {
+		locations[forkID] = copied.Refs.Locations[ref]
+	}
+	bases := make(map[string]map[uint]string)
+	var events []models.TimelineEvent
+	if err := tx.Where("world_id = ?", summary.WorldID).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	bases[models.ForkEvent] = make(map[uint]string, len(events))
+	for _, event := range events {
+		bases[models.ForkEvent][event.ID] = EventFingerprint(event)
+	}
+	var npcs []models.NPC
+	if err := tx.Where("world_id = ?", summary.WorldID).Find(&npcs).Error; err != nil {
+		return nil, err
+	}
+	bases[models.ForkNPC] = make(map[uint]string, len(npcs))
+	for _, npc := range npcs {
+		if npc.LocationID != nil {
+			sourceID := locations[*npc.LocationID]
+			npc.LocationID = &sourceID
+		}
+		bases[models.ForkNPC][npc.ID] = NPCFingerprint(npc)
+	}
+	for i := range origins {
+		origins[i].Base = bases[origins[i].Kind][origins[i].ID]
+	}
+
+	if len(origins) > 0 {
+		if err := tx.CreateInBatches(&origins, 500).Error; err != nil {
+			return nil, fmt.Errorf("failed to record lineage: %w", err)
+		}
+	}
+	return summary, nil
+}
+
+// Compare lists the events and NPCs a fork has added, removed or changed
+// since it was made. Whether the source changed the same rows meanwhile is
+// flagged, but changes made only in the source are left out.
+func Compare(db *gorm.DB, fork models.World) (*Diff, error) {
+	if fork.ForkedFromID == nil || fork.ForkedAt == nil {
+		return nil, fmt.Errorf("world %d is not a fork", fork.ID)
+	}
+	diff := &Diff{ForkID: fork.ID, SourceID: *fork.ForkedFromID, ForkedAt: *fork.ForkedAt, Events: []Change{}, NPCs: []Change{}}
+
+	origins, err := LoadOrigins(db, fork.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var forkEvents, sourceEvents []models.TimelineEvent
+	if err := db.Where("world_id = ?", fork.ID).Order("id").Find(&forkEvents).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("world_id = ?", diff.SourceID).Order("id").Find(&sourceEvents).Error; err != nil {
+		return nil, err
+	}
+	diff.Events = compare(models.ForkEvent, origins[models.ForkEvent],
+		rows(forkEvents, func(e models.TimelineEvent) row { return row{e.ID, e.Title, eventFields(e)} }),
+		rows(sourceEvents, func(e models.TimelineEvent) row { return row{e.ID, e.Title, eventFields(e)} }))
+
+	var forkNPCs, sourceNPCs []models.NPC
+	if err := db.Where("world_id = ?", fork.ID).Order("id").Find(&forkNPCs).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("world_id = ?", diff.SourceID).Order("id").Find(&sourceNPCs).Error; err != nil {
+		return nil, err
+	}
+	// Fork NPCs are compared with their location as the source knows it
+	for i, npc := range forkNPCs {
+		if npc.LocationID != nil {
+			sourceID, _ := origins.Source(models.ForkLocation, *npc.LocationID)
+			forkNPCs[i].LocationID = &sourceID // 0, matching nothing, for new locations
+		}
+	}
+	diff.NPCs = compare(models.ForkNPC, origins[models.ForkNPC],
+		rows(forkNPCs, func(n models.NPC) row { return row{n.ID, n.Name, npcFields(n)} }),
+		rows(sourceNPCs, func(n models.NPC) row { return row{n.ID, n.Name, npcFields(n)} }))
+
+	return diff, nil
+}
+
+// EventFingerprint identifies the values of the event fields forks compare
+func EventFingerprint(event models.TimelineEvent) string {
+	return fingerprint(eventFields(event))
+}
+
+// NPCFingerprint identifies the values of the NPC fields forks compare, with
+// the location as the source world's ID
+func NPCFingerprint(npc models.NPC) string {
+	return fingerprint(npcFields(npc))
+}
+
+type field struct {
+	Name  string
+	Value string
+}
+
+// Participants, links and images are left out: they point at other rows,
+// and a fork never owns its images
+func eventFields(event models.TimelineEvent) []field {
+	endDate := ""
+	if event.EndDate != nil {
+		endDate = *event.EndDate
+	}
+	return []field{
+		{"title", event.Title},
+		{"description", event.Description},
+		{"start_date", event.StartDate},
+		{"end_date", endDate},
+		{"era", event.Era},
+		{"importance", event.Importance},
+		{"sort_order", strconv.Itoa(event.SortOrder)},
+		{"details", event.Details},
+	}
+}
+
+func npcFields(npc models.NPC) []field {
+	location := ""
+	if npc.LocationID != nil {
+		location = strconv.FormatUint(uint64(*npc.LocationID), 10)
+	}
+	return []field{
+		{"name", npc.Name},
+		{"age", strconv.Itoa(npc.Age)},
+		{"gender", npc.Gender},
+		{"profession", npc.Profession},
+		{"social_class", npc.SocialClass},
+		{"personality", npc.Personality},
+		{"is_alive", strconv.FormatBool(npc.IsAlive)},
+		{"location_id", location},
+	}
+}
+
+func fingerprint(fields []field) string {
+	data, _ := json.Marshal(fields)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// A row of either kind, reduced to what's compared
+type row struct {
+	ID     uint
+	Name   string
+	Fields []field
+}
+
+func rows[T any](items []T, convert func(T) row) map[uint]row {
+	byID := make(map[uint]row, len(items))
+	for _, item := range items {
+		r := convert(item)
+		byID[r.ID] = r
+	}
+	return byID
+}
+
+func compare(kind string, origins map[uint]models.WorldForkOrigin, fork, source map[uint]row) []Change {
+	changes := []Change{}
+	for id, forkRow := range fork {
+		forkID := id
+		origin, copied := origins[id]
+		sourceRow, inSource := source[origin.SourceID]
+		switch {
+		case !copied:
+			changes = append(changes, Change{Kind: kind, Change: Added, ForkID: &forkID, Name: forkRow.Name})
+		case fingerprint(forkRow.Fields) == origin.Base:
+			// Untouched in the fork
+		case !inSource:
+			// Edited here but deleted from the source: merging brings it back
+			changes = append(changes, Change{Kind: kind, Change: Added, ForkID: &forkID, Name: forkRow.Name, Conflict: true})
+		default:
+			sourceID := origin.SourceID
+			change := Change{Kind: kind, Change: Changed, ForkID: &forkID, SourceID: &sourceID, Name: forkRow.Name}
+			for i, f := range forkRow.Fields {
+				if f.Value != sourceRow.Fields[i].Value {
+					change.Fields = append(change.Fields, f.Name)
+				}
+			}
+			if len(change.Fields) == 0 {
+				continue // The source made the same edit
+			}
+			change.Conflict = fingerprint(sourceRow.Fields) != origin.Base
+			changes = append(changes, change)
+		}
+	}
+	for _, origin := range origins {
+		if _, inFork := fork[origin.ID]; inFork {
+			continue
+		}
+		sourceRow, inSource := source[origin.SourceID]
+		if !inSource {
+			continue // Deleted from both
+		}
+		sourceID := origin.SourceID
+		changes = append(changes, Change{
+			Kind:     kind,
+			Change:   Removed,
+			SourceID: &sourceID,
+			Name:     sourceRow.Name,
+			Conflict: fingerprint(sourceRow.Fields) != origin.Base,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Change != b.Change {
+			return a.Change < b.Change
+		}
+		return id(a) < id(b)
+	})
+	return changes
+}
+
+func id(change Change) uint {
+	if change.ForkID != nil {
+		return *change.ForkID
+	}
+	return *change.SourceID
+}
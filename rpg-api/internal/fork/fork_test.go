@@ -0,0 +1,185 @@
+package fork
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func openForkDB(t *testing.T) *gorm.DB {
+	return testdb.Open(t,
+		&models.User{}, &models.World{}, &models.WorldCalendar{}, &models.WorldEra{},
+		&models.TimelineEvent{}, &models.TimelineEventLink{}, &models.TimelineEventNPC{},
+		&models.NPCLocation{}, &models.Organization{}, &models.OrganizationRank{}, &models.NPC{},
+		&models.OrganizationMembership{}, &models.NPCRelationship{}, &models.NPCGenerationConfig{},
+		&models.LoreArticle{}, &models.LoreLink{}, &models.Story{}, &models.StoryChapter{},
+		&models.WorldForkOrigin{})
+}
+
+func create(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Forks source as user and loads the new world
+func forkWorld(t *testing.T, db *gorm.DB, source models.World, user *models.User) models.World {
+	t.Helper()
+
+	var worldID uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		summary, err := Fork(tx, source, user, "What if", time.Now())
+		if err == nil {
+			worldID = summary.WorldID
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	var forked models.World
+	if err := db.First(&forked, worldID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return forked
+}
+
+func TestFork(t *testing.T) {
+	db := openForkDB(t)
+	ownerID := uint(1)
+	source := models.World{Title: "Varn", UserID: &ownerID}
+	create(t, db, &source)
+
+	// Rows of other worlds in between give the source gaps in its IDs
+	other := models.World{Title: "Elsewhere", UserID: &ownerID}
+	create(t, db, &other)
+	for _, title := range []string{"Crowning", "Noise", "Siege"} {
+		world := source.ID
+		if title == "Noise" {
+			world = other.ID
+		}
+		create(t, db, &models.TimelineEvent{WorldID: world, Title: title, StartDate: "1203", Importance: models.ImportanceMinor, ImageID: "img-" + title})
+	}
+	city := models.NPCLocation{WorldID: source.ID, Name: "Varn"}
+	create(t, db, &city)
+	create(t, db, &models.NPC{WorldID: source.ID, Name: "Aldric", LocationID: &city.ID, IsAlive: true})
+	create(t, db, &models.NPC{WorldID: other.ID, Name: "Stranger", IsAlive: true})
+	create(t, db, &models.NPC{WorldID: source.ID, Name: "Mira", IsAlive: true})
+	create(t, db, &models.Story{WorldID: source.ID, Title: "Published", Status: models.StoryPublished, Reviewed: true, UserID: &ownerID})
+	create(t, db, &models.Story{WorldID: source.ID, Title: "Draft", Status: models.StoryDraft, UserID: &ownerID})
+
+	forker := &models.User{ID: 2}
+	forked := forkWorld(t, db, source, forker)
+	if forked.ForkedFromID == nil || *forked.ForkedFromID != source.ID || forked.UserID == nil || *forked.UserID != forker.ID {
+		t.Fatalf("Fork %+v doesn't record its source and owner", forked)
+	}
+
+	// Only the stories the forker can read are copied
+	var stories []string
+	db.Model(&models.Story{}).Where("world_id = ?", forked.ID).Pluck("title", &stories)
+	if len(stories) != 1 || stories[0] != "Published" {
+		t.Errorf("Fork has stories %v, want only the published one", stories)
+	}
+
+	// Copies share the source's images, so they stay in use
+	var imageIDs []string
+	db.Model(&models.TimelineEvent{}).Where("world_id = ?", forked.ID).Order("id").Pluck("image_id", &imageIDs)
+	if !slices.Equal(imageIDs, []string{"img-Crowning", "img-Siege"}) {
+		t.Errorf("Fork events have images %v", imageIDs)
+	}
+
+	// Every copy points back at the row of the same name
+	origins, err := LoadOrigins(db, forked.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []struct {
+		name  string
+		model interface{}
+		field string
+	}{
+		{models.ForkEvent, &models.TimelineEvent{}, "title"},
+		{models.ForkNPC, &models.NPC{}, "name"},
+		{models.ForkLocation, &models.NPCLocation{}, "name"},
+	} {
+		var forkRows []struct {
+			ID   uint
+			Name string
+		}
+		db.Model(kind.model).Select("id, "+kind.field+" AS name").Where("world_id = ?", forked.ID).Scan(&forkRows)
+		if len(forkRows) != len(origins[kind.name]) {
+			t.Errorf("%d %s copies but %d origins", len(forkRows), kind.name, len(origins[kind.name]))
+		}
+		for _, row := range forkRows {
+			sourceID, ok := origins.Source(kind.name, row.ID)
+			var sourceName string
+			db.Model(kind.model).Select(kind.field).Where("id = ? AND world_id = ?", sourceID, source.ID).Scan(&sourceName)
+			if !ok || sourceName != row.Name {
+				t.Errorf("%s %q came from %d %q", kind.name, row.Name, sourceID, sourceName)
+			}
+		}
+	}
+
+	diff, err := Compare(db, forked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Events) != 0 || len(diff.NPCs) != 0 {
+		t.Errorf("A new fork differs from its source: %+v", diff)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	db := openForkDB(t)
+	ownerID := uint(1)
+	source := models.World{Title: "Varn", UserID: &ownerID}
+	create(t, db, &source)
+	for _, title := range []string{"Crowning", "Siege", "Flood"} {
+		create(t, db, &models.TimelineEvent{WorldID: source.ID, Title: title, StartDate: "1203", Importance: models.ImportanceMinor})
+	}
+	create(t, db, &models.NPC{WorldID: source.ID, Name: "Aldric", IsAlive: true})
+
+	forked := forkWorld(t, db, source, &models.User{ID: 2})
+	var forkEvents []models.TimelineEvent
+	db.Where("world_id = ?", forked.ID).Order("id").Find(&forkEvents)
+	var sourceEvents []models.TimelineEvent
+	db.Where("world_id = ?", source.ID).Order("id").Find(&sourceEvents)
+
+	// The fork edits the crowning, edits the siege the source also edited,
+	// adds an event and drops its NPC
+	db.Model(&forkEvents[0]).Update("title", "Coronation")
+	db.Model(&forkEvents[1]).Update("description", "The walls fall")
+	db.Model(&sourceEvents[1]).Update("description", "The walls hold")
+	create(t, db, &models.TimelineEvent{WorldID: forked.ID, Title: "Dragon", StartDate: "1204", Importance: models.ImportanceMinor})
+	db.Where("world_id = ?", forked.ID).Delete(&models.NPC{})
+
+	diff, err := Compare(db, forked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		change, name string
+		conflict     bool
+	}{
+		{Added, "Dragon", false},
+		{Changed, "Coronation", false},
+		{Changed, "Siege", true},
+	}
+	if len(diff.Events) != len(want) {
+		t.Fatalf("Got event changes %+v", diff.Events)
+	}
+	for i, w := range want {
+		got := diff.Events[i]
+		if got.Change != w.change || got.Name != w.name || got.Conflict != w.conflict {
+			t.Errorf("Change %d is %+v, want %+v", i, got, w)
+		}
+	}
+	if len(diff.NPCs) != 1 || diff.NPCs[0].Change != Removed || diff.NPCs[0].Name != "Aldric" {
+		t.Errorf("Got NPC changes %+v", diff.NPCs)
+	}
+}
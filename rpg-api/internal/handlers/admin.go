@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/naetharu/rpg-api/internal/middleware"
 	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/query"
 	"gorm.io/gorm"
 )
 
@@ -52,6 +53,20 @@ func (h *AdminHandler) GetStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// Sorting and filters for GET /admin/users
+var userList = query.List{
+	Sorts:        map[string]string{"name": "name", "email": "email", "created_at": "created_at"},
+	DefaultSort:  "created_at",
+	DefaultOrder: "desc",
+	Filters: map[string]query.Filter{
+		"is_admin":  {Column: "is_admin", Kind: query.Bool},
+		"is_active": {Column: "is_active", Kind: query.Bool},
+		"provider":  {Column: "provider", Kind: query.Equal},
+		"email":     {Column: "email", Kind: query.Like},
+	},
+}
+
+// GET /admin/users
 func (h *AdminHandler) GetUsers(c *gin.Context) {
 	// Get current user and verify admin
 	user, exists := middleware.GetCurrentUser(c)
@@ -60,13 +75,20 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 		return
 	}
 
+	params, err := userList.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var users []models.User
-	if err := h.DB.Find(&users).Error; err != nil {
+	meta, err := params.Find(h.DB, &users)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, query.Response(users, meta))
 }
 
 // PATCH /admin/users/:id/status - ban/unban user
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Bumped whenever the export layout changes
+const adventureExportVersion = 1
+
+// AdventureExport is a self-contained copy of an adventure for running it
+// offline or printing it: every episode and scene in order, plus the assets
+// it uses with their stat blocks
+type AdventureExport struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Adventure  models.Adventure `json:"adventure"`
+	Assets     []models.Asset   `json:"assets"`
+}
+
+// GET /adventures/:id/export
+func (h *AdventureHandler) ExportAdventure(c *gin.Context) {
+	adventureID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid adventure ID"})
+		return
+	}
+
+	// Verify user has access to this adventure
+	if !h.hasAdventureAccess(c, uint(adventureID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adventure not found or access denied"})
+		return
+	}
+
+	var adventure models.Adventure
+	if err := h.DB.
+		Preload("Episodes", func(db *gorm.DB) *gorm.DB { return db.Order("\"order\" ASC") }).
+		Preload("Episodes.Scenes", func(db *gorm.DB) *gorm.DB { return db.Order("\"order\" ASC") }).
+		Preload("TitlePage").
+		Preload("Epilogue.Outcomes").
+		Preload("Epilogue.FollowUpHooks").
+		First(&adventure, adventureID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adventure not found"})
+		return
+	}
+
+	// Scenes reference assets by ID so each asset appears once in the export
+	var sceneIDs []uint
+	for _, episode := range adventure.Episodes {
+		for _, scene := range episode.Scenes {
+			sceneIDs = append(sceneIDs, scene.ID)
+		}
+	}
+
+	var sceneAssets []struct {
+		SceneID uint
+		AssetID uint
+	}
+	if len(sceneIDs) > 0 {
+		if err := h.DB.Raw("SELECT scene_id, asset_id FROM scene_assets WHERE scene_id IN ?", sceneIDs).Scan(&sceneAssets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scene assets"})
+			return
+		}
+	}
+
+	assetIDsByScene := make(map[uint][]uint)
+	var assetIDs []uint
+	for _, row := range sceneAssets {
+		assetIDsByScene[row.SceneID] = append(assetIDsByScene[row.SceneID], row.AssetID)
+		assetIDs = append(assetIDs, row.AssetID)
+	}
+	for i := range adventure.Episodes {
+		for j := range adventure.Episodes[i].Scenes {
+			scene := &adventure.Episodes[i].Scenes[j]
+			scene.AssetIDs = assetIDsByScene[scene.ID]
+		}
+	}
+
+	var adventureAssetIDs []uint
+	if err := h.DB.Raw("SELECT asset_id FROM adventure_assets WHERE adventure_id = ?", adventureID).Scan(&adventureAssetIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch adventure assets"})
+		return
+	}
+	assetIDs = append(assetIDs, adventureAssetIDs...)
+
+	export := AdventureExport{
+		Version:    adventureExportVersion,
+		ExportedAt: time.Now(),
+		Adventure:  adventure,
+		Assets:     []models.Asset{},
+	}
+
+	// Only assets the requester could open themselves are included
+	if len(assetIDs) > 0 {
+		query := h.DB.Where("id IN ?", assetIDs).Order("id ASC")
+		if user, isAuthenticated := middleware.GetCurrentUser(c); isAuthenticated {
+			query = query.Where("is_official = ? OR user_id = ?", true, user.ID)
+		} else {
+			query = query.Where("is_official = ?", true)
+		}
+		if err := query.Find(&export.Assets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assets"})
+			return
+		}
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=adventure-"+strconv.Itoa(adventureID)+".json")
+	c.JSON(http.StatusOK, export)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/lint"
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// GET /adventures/:id/lint - pre-session checklist of problems in an adventure
+func (h *AdventureHandler) LintAdventure(c *gin.Context) {
+	adventureID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid adventure ID"})
+		return
+	}
+
+	// Verify user has access to this adventure
+	if !h.hasAdventureAccess(c, uint(adventureID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adventure not found or access denied"})
+		return
+	}
+
+	var adventure models.Adventure
+	if err := h.DB.
+		Preload("Episodes", func(db *gorm.DB) *gorm.DB { return db.Order("\"order\" ASC") }).
+		Preload("Episodes.Scenes", func(db *gorm.DB) *gorm.DB { return db.Order("\"order\" ASC") }).
+		Preload("Epilogue.Outcomes").
+		First(&adventure, adventureID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adventure not found"})
+		return
+	}
+
+	input := lint.AdventureInput{
+		Adventure:     adventure,
+		SceneAssetIDs: make(map[uint][]uint),
+		Assets:        make(map[uint]models.Asset),
+	}
+
+	// Read the join tables directly so references to deleted assets still show up
+	var sceneIDs []uint
+	for _, episode := range adventure.Episodes {
+		for _, scene := range episode.Scenes {
+			sceneIDs = append(sceneIDs, scene.ID)
+		}
+	}
+
+	var sceneAssets []struct {
+		SceneID uint
+		AssetID uint
+	}
+	if len(sceneIDs) > 0 {
+		if err := h.DB.Raw("SELECT scene_id, asset_id FROM scene_assets WHERE scene_id IN ?", sceneIDs).Scan(&sceneAssets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scene assets"})
+			return
+		}
+	}
+	for _, row := range sceneAssets {
+		input.SceneAssetIDs[row.SceneID] = append(input.SceneAssetIDs[row.SceneID], row.AssetID)
+	}
+
+	if err := h.DB.Raw("SELECT asset_id FROM adventure_assets WHERE adventure_id = ?", adventureID).Scan(&input.AdventureAssetIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch adventure assets"})
+		return
+	}
+
+	assetIDs := append([]uint{}, input.AdventureAssetIDs...)
+	for _, row := range sceneAssets {
+		assetIDs = append(assetIDs, row.AssetID)
+	}
+	if len(assetIDs) > 0 {
+		var assets []models.Asset
+		if err := h.DB.Unscoped().Where("id IN ?", assetIDs).Find(&assets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assets"})
+			return
+		}
+		for _, asset := range assets {
+			input.Assets[asset.ID] = asset
+		}
+	}
+
+	c.JSON(http.StatusOK, lint.LintAdventure(input))
+}
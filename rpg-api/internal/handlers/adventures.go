@@ -9,19 +9,20 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/naetharu/rpg-api/internal/middleware"
 	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/query"
 	"github.com/naetharu/rpg-api/internal/services"
 	"gorm.io/gorm"
 )
 
 type AdventureHandler struct {
-	DB                *gorm.DB
-	CloudflareService *services.CloudflareImagesService
+	DB     *gorm.DB
+	Images services.ImageStore
 }
 
-func NewAdventureHandler(db *gorm.DB) *AdventureHandler {
+func NewAdventureHandler(db *gorm.DB, images services.ImageStore) *AdventureHandler {
 	return &AdventureHandler{
-		DB:                db,
-		CloudflareService: services.NewCloudflareImagesService(),
+		DB:     db,
+		Images: images,
 	}
 }
 
@@ -115,25 +116,44 @@ func (h *AdventureHandler) CreateAdventure(c *gin.Context) {
 	c.JSON(http.StatusCreated, adventure)
 }
 
+// Sorting and filters for GET /adventures
+var adventureList = query.List{
+	Sorts:        map[string]string{"title": "title", "created_at": "created_at"},
+	DefaultSort:  "created_at",
+	DefaultOrder: "desc",
+	Filters: map[string]query.Filter{
+		"genre":       {Column: "genres", Kind: query.Contains},
+		"age_rating":  {Column: "age_rating", Kind: query.Equal},
+		"is_official": {Column: "is_official", Kind: query.Bool},
+	},
+}
+
 // GET /adventures
 func (h *AdventureHandler) GetAdventures(c *gin.Context) {
+	params, err := adventureList.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var adventures []models.Adventure
-	query := h.DB.Preload("Episodes").Preload("TitlePage").Preload("Epilogue")
+	db := h.DB
 
 	user, isAuthenticated := middleware.GetCurrentUser(c)
 	if isAuthenticated {
-		query = query.Where("user_id = ?", user.ID)
+		db = db.Where("user_id = ?", user.ID)
 	} else {
 		// For non-authenticated users, only show official adventures
-		query = query.Where("user_id IS NULL")
+		db = db.Where("user_id IS NULL")
 	}
 
-	if err := query.Find(&adventures).Error; err != nil {
+	meta, err := params.Find(db, &adventures, "Episodes", "TitlePage", "Epilogue")
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch adventures"})
 		return
 	}
 
-	c.JSON(http.StatusOK, adventures)
+	c.JSON(http.StatusOK, query.Response(adventures, meta))
 }
 
 // GET /adventures/:id
@@ -215,87 +235,47 @@ func (h *AdventureHandler) DeleteAdventure(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Adventure not found or access denied"})
 		return
 	}
-	// Start a transaction to ensure all deletes succeed or none do
-	tx := h.DB.Begin()
-	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+	// Soft delete only - the trash purger performs the full cascade and image cleanup later
+	if err := h.DB.Delete(&adventure).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete adventure"})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Adventure moved to trash"})
+}
 
-	// Collect all Cloudflare image IDs that need to be deleted
-	var imageIDsToDelete []string
-
-	// Adventure images
-	if adventure.BannerImageID != "" {
-		imageIDsToDelete = append(imageIDsToDelete, adventure.BannerImageID)
-	}
-	if adventure.CardImageID != "" {
-		imageIDsToDelete = append(imageIDsToDelete, adventure.CardImageID)
-	}
-
-	// Title page images
-	var titlePage models.TitlePage
-	if err := tx.Where("adventure_id = ?", id).First(&titlePage).Error; err == nil {
-		if titlePage.BannerImageID != "" {
-			imageIDsToDelete = append(imageIDsToDelete, titlePage.BannerImageID)
-		}
-	}
-
-	// Scene images
-	var scenes []models.Scene
-	if err := tx.Joins("JOIN episodes ON scenes.episode_id = episodes.id").
-		Where("episodes.adventure_id = ?", id).Find(&scenes).Error; err == nil {
-		for _, scene := range scenes {
-			if scene.ImageID != "" {
-				imageIDsToDelete = append(imageIDsToDelete, scene.ImageID)
-			}
-		}
-	}
-
-	// Delete images from Cloudflare (do this before database cleanup)
-	for _, imageID := range imageIDsToDelete {
-		if err := h.CloudflareService.DeleteImage(imageID); err != nil {
-			// Log the error but don't fail the deletion
-			fmt.Printf("Warning: Failed to delete image from Cloudflare: %v\n", err)
-		}
-	}
-
-	// Remove the asset assocations from the scenes
-	if err := tx.Exec("DELETE FROM scene_assets WHERE scene_id IN (SELECT id FROM scenes WHERE episode_id IN (SELECT id FROM episodes WHERE adventure_id = ?))", id).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete scene associations"})
+// POST /adventures/:id/restore
+func (h *AdventureHandler) RestoreAdventure(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
-	// Delete scenes first (they reference episodes)
-	if err := tx.Exec("DELETE FROM scenes WHERE episode_id IN (SELECT id FROM episodes WHERE adventure_id = ?)", id).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete scenes"})
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 		return
 	}
-	// Delete episodes
-	if err := tx.Where("adventure_id = ?", id).Delete(&models.Episode{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete episodes"})
+
+	var adventure models.Adventure
+	if err := h.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&adventure).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adventure not found in trash"})
 		return
 	}
-	// Delete title page if exists
-	tx.Where("adventure_id = ?", id).Delete(&models.TitlePage{})
-	// Delete epilogue and related data if exists
-	tx.Exec("DELETE FROM epilogue_outcomes WHERE epilogue_id IN (SELECT id FROM epilogues WHERE adventure_id = ?)", id)
-	tx.Exec("DELETE FROM follow_up_hooks WHERE epilogue_id IN (SELECT id FROM epilogues WHERE adventure_id = ?)", id)
-	tx.Where("adventure_id = ?", id).Delete(&models.Epilogue{})
-	// Finally delete the adventure
-	if err := tx.Delete(&adventure).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete adventure"})
+
+	// Check if user owns this adventure or is admin
+	if adventure.UserID == nil || *adventure.UserID != user.ID && !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+
+	if err := h.DB.Unscoped().Model(&adventure).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore adventure"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Adventure deleted successfully"})
+
+	h.DB.Preload("Episodes").Preload("TitlePage").Preload("Epilogue").First(&adventure, adventure.ID)
+	c.JSON(http.StatusOK, adventure)
 }
 
 // TITLE PAGE ENDPOINTS
@@ -0,0 +1,618 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/services"
+)
+
+// Import limits
+const (
+	maxImportBytes         = 100 << 20 // Whole request, zip included
+	maxImportRows          = 500
+	maxImportImageBytes    = 10 << 20
+	maxImportZipEntries    = 2 * maxImportRows // A manifest and an image per row, with room to spare
+	maxImportUnpackedBytes = 200 << 20         // Every zip entry together, uncompressed
+)
+
+// Import modes
+const (
+	importModeAtomic     = "atomic"      // Create every row or none
+	importModeBestEffort = "best_effort" // Create the rows that work, report the rest
+)
+
+// Row statuses in the import report
+const (
+	importRowValid   = "valid" // Dry run: would be created
+	importRowCreated = "created"
+	importRowInvalid = "invalid" // Failed validation
+	importRowFailed  = "failed"  // Valid, but the image upload or insert failed
+	importRowSkipped = "skipped" // Atomic import stopped before reaching it
+)
+
+// One row of an import file. CSV columns use the same names; genres are
+// separated with "|" and stat_block holds JSON.
+type importRow struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Type        string            `json:"type"`
+	Genres      []string          `json:"genres"`
+	Image       string            `json:"image"`     // File name inside the uploaded zip
+	ImageURL    string            `json:"image_url"` // Or a public URL for the image service to fetch
+	StatBlock   *models.StatBlock `json:"stat_block"`
+
+	parseError string // Set when a CSV cell couldn't be read
+}
+
+type ImportRowResult struct {
+	Row     int      `json:"row"` // 1-based, not counting the CSV header
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	AssetID *uint    `json:"asset_id,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Mode    string            `json:"mode"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// POST /assets/import?mode=atomic|best_effort&dry_run=true
+//
+// Accepts a multipart upload with a "file" field holding one of:
+//   - assets.csv or assets.json
+//   - a .zip with assets.csv or assets.json at the top level, plus the images its rows name
+//
+// A JSON body of the form {"rows": [...]} also works when there are no image files.
+func (h *AssetHandler) ImportAssets(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	mode := c.DefaultQuery("mode", importModeAtomic)
+	if mode != importModeAtomic && mode != importModeBestEffort {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be atomic or best_effort"})
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	rows, images, err := readImport(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Import has no rows"})
+		return
+	}
+	if len(rows) > maxImportRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Imports are limited to %d rows", maxImportRows)})
+		return
+	}
+
+	fetchesURLs := h.importsURLs()
+
+	report := ImportReport{DryRun: dryRun, Mode: mode, Total: len(rows)}
+	invalid := 0
+	for i := range rows {
+		result := ImportRowResult{Row: i + 1, Name: rows[i].Name, Status: importRowValid}
+		if errs := validateImportRow(&rows[i], images, h.Rules, fetchesURLs); len(errs) > 0 {
+			result.Status = importRowInvalid
+			result.Errors = errs
+			invalid++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+	report.Failed = invalid
+
+	// Every image the import would store counts against the quota. The size
+	// of images fetched from URLs isn't known up front, so each is checked
+	// again once it has been fetched.
+	imageCount, imageBytes := 0, int64(0)
+	for i, row := range rows {
+		if report.Rows[i].Status == importRowInvalid {
+			continue
+		}
+		if row.Image != "" {
+			imageCount++
+			imageBytes += int64(len(images[row.Image]))
+		} else if row.ImageURL != "" {
+			imageCount++
+		}
+	}
+	if imageCount > 0 {
+		if err := h.Quota.Check(h.DB, user, imageCount, imageBytes); err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": uploadErrorMessage(err)})
+			return
+		}
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	if mode == importModeAtomic {
+		if invalid > 0 {
+			c.JSON(http.StatusUnprocessableEntity, report)
+			return
+		}
+		h.importAtomic(c, user, rows, images, &report)
+		return
+	}
+
+	h.importBestEffort(c, user, rows, images, &report)
+}
+
+// Uploads every image first, then inserts every asset in one transaction.
+// Any failure rolls the whole import back and removes uploaded images.
+func (h *AssetHandler) importAtomic(c *gin.Context, user *models.User, rows []importRow, images map[string][]byte, report *ImportReport) {
+	assets := make([]models.Asset, len(rows))
+	var uploaded []string
+
+	fail := func(index int, message string) {
+		h.deleteImportedImages(uploaded)
+		for i := range report.Rows {
+			report.Rows[i].Status = importRowSkipped
+		}
+		report.Rows[index].Status = importRowFailed
+		report.Rows[index].Errors = []string{message}
+		report.Created = 0
+		report.Failed = 1
+		c.JSON(http.StatusUnprocessableEntity, report)
+	}
+
+	for i := range rows {
+		asset, err := h.buildImportedAsset(user, &rows[i], images)
+		if err != nil {
+			fail(i, err.Error())
+			return
+		}
+		if asset.ImageID != "" {
+			uploaded = append(uploaded, asset.ImageID)
+		}
+		assets[i] = asset
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		h.deleteImportedImages(uploaded)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	for i := range assets {
+		if err := tx.Create(&assets[i]).Error; err != nil {
+			tx.Rollback()
+			fail(i, "Failed to create asset")
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		h.deleteImportedImages(uploaded)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save imported assets"})
+		return
+	}
+
+	for i := range assets {
+		report.Rows[i].Status = importRowCreated
+		report.Rows[i].AssetID = &assets[i].ID
+	}
+	report.Created = len(assets)
+	report.Failed = 0
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// Creates each valid row on its own and reports the rest
+func (h *AssetHandler) importBestEffort(c *gin.Context, user *models.User, rows []importRow, images map[string][]byte, report *ImportReport) {
+	for i := range rows {
+		if report.Rows[i].Status == importRowInvalid {
+			continue
+		}
+
+		asset, err := h.buildImportedAsset(user, &rows[i], images)
+		if err != nil {
+			report.Rows[i].Status = importRowFailed
+			report.Rows[i].Errors = []string{err.Error()}
+			report.Failed++
+			continue
+		}
+
+		if err := h.DB.Create(&asset).Error; err != nil {
+			if asset.ImageID != "" {
+				h.deleteImportedImages([]string{asset.ImageID})
+			}
+			report.Rows[i].Status = importRowFailed
+			report.Rows[i].Errors = []string{"Failed to create asset"}
+			report.Failed++
+			continue
+		}
+
+		report.Rows[i].Status = importRowCreated
+		report.Rows[i].AssetID = &asset.ID
+		report.Created++
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Uploads the row's image, if any, and builds the asset to insert
+func (h *AssetHandler) buildImportedAsset(user *models.User, row *importRow, images map[string][]byte) (models.Asset, error) {
+	asset := models.Asset{
+		Name:        row.Name,
+		Description: row.Description,
+		Type:        row.Type,
+		Genres:      row.Genres,
+		StatBlock:   row.StatBlock,
+		UserID:      &user.ID,
+	}
+
+	var stored *services.StoredImage
+	var err error
+	size := 0
+	switch {
+	case row.Image != "":
+		var image *services.ProcessedImage
+		image, err = services.PrepareImage(bytes.NewReader(images[row.Image]), path.Base(row.Image), h.Rules)
+		if err != nil {
+			return asset, fmt.Errorf("image: %v", err)
+		}
+		size = len(image.Data)
+		stored, err = h.Images.Upload(bytes.NewReader(image.Data), image.Filename, nil)
+	case row.ImageURL != "":
+		stored, size, err = h.uploadImageURL(user, row.ImageURL)
+		if err != nil {
+			return asset, err
+		}
+	default:
+		return asset, nil
+	}
+	if err != nil {
+		return asset, fmt.Errorf("image upload failed: %v", err)
+	}
+	if err := recordUpload(h.DB, user, stored, size); err != nil {
+		log.Printf("Warning: Failed to record upload %s: %v", stored.ID, err)
+	}
+
+	urls := services.VariantURLs(h.Images, stored.ID)
+	asset.ImageID = stored.ID
+	asset.ImageURL = urls.Medium
+	asset.ImageVariants = models.ImageVariants{
+		Thumbnail: urls.Thumbnail,
+		Medium:    urls.Medium,
+		Large:     urls.Large,
+		Original:  urls.Original,
+	}
+	return asset, nil
+}
+
+// Whether the image store can fetch image_url itself and hand the file back
+// to be checked
+func (h *AssetHandler) importsURLs() bool {
+	_, fetches := h.Images.(services.URLUploader)
+	_, checks := h.Images.(services.ImageFetcher)
+	return fetches && checks
+}
+
+// Has the image store fetch an image, then checks the file like any other
+// upload and stores the re-encoded copy in its place. Images that break the
+// rules or don't fit the user's quota are deleted again.
+func (h *AssetHandler) uploadImageURL(user *models.User, imageURL string) (*services.StoredImage, int, error) {
+	if !h.importsURLs() {
+		return nil, 0, fmt.Errorf("this image store can't import from URLs")
+	}
+	stored, err := h.Images.(services.URLUploader).UploadFromURL(imageURL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("image upload failed: %v", err)
+	}
+
+	size, err := h.checkFetchedImage(user, stored)
+	if err != nil {
+		if err := h.Images.Delete(stored.ID); err != nil {
+			// Recorded anyway, so the image GC can find and retry it
+			log.Printf("Warning: Failed to delete rejected image %s: %v", stored.ID, err)
+			if err := recordUpload(h.DB, user, stored, 0); err != nil {
+				log.Printf("Warning: Failed to record upload %s: %v", stored.ID, err)
+			}
+		}
+		return nil, 0, err
+	}
+	return stored, size, nil
+}
+
+func (h *AssetHandler) checkFetchedImage(user *models.User, stored *services.StoredImage) (int, error) {
+	fetcher := h.Images.(services.ImageFetcher)
+	data, err := fetcher.Fetch(stored.ID, h.Rules.MaxBytes)
+	if err != nil {
+		return 0, fmt.Errorf("image could not be checked: %v", err)
+	}
+	image, err := services.PrepareImage(bytes.NewReader(data), path.Base(stored.Filename), h.Rules)
+	if err != nil {
+		return 0, fmt.Errorf("image: %v", err)
+	}
+	if err := h.Quota.Check(h.DB, user, 0, int64(len(image.Data))); err != nil {
+		return 0, fmt.Errorf("image: %v", err)
+	}
+	if _, err := fetcher.Replace(stored.ID, bytes.NewReader(image.Data), image.Filename); err != nil {
+		return 0, fmt.Errorf("image upload failed: %v", err)
+	}
+	stored.Filename = image.Filename
+	return len(image.Data), nil
+}
+
+func (h *AssetHandler) deleteImportedImages(imageIDs []string) {
+	for _, imageID := range imageIDs {
+		// An image that can't be deleted keeps its upload record, so the
+		// image GC can find and retry it
+		if err := h.Images.Delete(imageID); err != nil {
+			log.Printf("Warning: Failed to delete imported image %s: %v", imageID, err)
+			continue
+		}
+		if err := h.DB.Where("image_id = ?", imageID).Delete(&models.Upload{}).Error; err != nil {
+			log.Printf("Warning: Failed to delete upload record for image %s: %v", imageID, err)
+		}
+	}
+}
+
+// Checks one row and normalises it in place. Returns every problem found.
+// fetchesURLs is false when the image store can't import from image_url.
+func validateImportRow(row *importRow, images map[string][]byte, rules services.ImageRules, fetchesURLs bool) []string {
+	var errs []string
+
+	row.Name = strings.TrimSpace(row.Name)
+	row.Type = strings.ToLower(strings.TrimSpace(row.Type))
+
+	if row.parseError != "" {
+		errs = append(errs, row.parseError)
+	}
+	if row.Name == "" {
+		errs = append(errs, "name is required")
+	}
+
+	switch row.Type {
+	case models.AssetTypeCharacter, models.AssetTypeCreature, models.AssetTypeLocation, models.AssetTypeItem:
+		if err := models.ValidateStatBlock(row.Type, row.StatBlock); err != nil {
+			errs = append(errs, "stat_block: "+err.Error())
+		}
+	case "":
+		errs = append(errs, "type is required")
+	default:
+		errs = append(errs, "type must be character, creature, location or item")
+	}
+
+	var genres []string
+	for _, genre := range row.Genres {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			genres = append(genres, genre)
+		}
+	}
+	row.Genres = genres
+
+	row.Image = strings.TrimSpace(row.Image)
+	row.ImageURL = strings.TrimSpace(row.ImageURL)
+
+	if row.Image != "" && row.ImageURL != "" {
+		errs = append(errs, "use either image or image_url, not both")
+	}
+	if row.Image != "" {
+		if _, ok := images[row.Image]; !ok {
+			errs = append(errs, fmt.Sprintf("image %q is not in the zip", row.Image))
+		} else if !isValidImageType(row.Image) {
+			errs = append(errs, "image must be JPEG, PNG or WebP")
+		} else if _, _, err := services.InspectImage(images[row.Image], rules); err != nil {
+			errs = append(errs, "image: "+err.Error())
+		}
+	}
+	if row.ImageURL != "" {
+		parsed, err := url.Parse(row.ImageURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs = append(errs, "image_url must be an http or https URL")
+		} else if !fetchesURLs {
+			errs = append(errs, "image_url is not supported by this server's image store, put the image in the zip instead")
+		}
+	}
+
+	return errs
+}
+
+// Reads rows and any zipped images from the request
+func readImport(c *gin.Context) ([]importRow, map[string][]byte, error) {
+	images := map[string][]byte{}
+
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		var body struct {
+			Rows []importRow `json:"rows"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		return body.Rows, images, nil
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		return nil, nil, fmt.Errorf("No file provided")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read upload")
+	}
+
+	switch strings.ToLower(filepath.Ext(header.Filename)) {
+	case ".csv":
+		rows, err := parseImportCSV(data)
+		return rows, images, err
+	case ".json":
+		rows, err := parseImportJSON(data)
+		return rows, images, err
+	case ".zip":
+		return readImportZip(data)
+	default:
+		return nil, nil, fmt.Errorf("file must be .csv, .json or .zip")
+	}
+}
+
+func readImportZip(data []byte) ([]importRow, map[string][]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid zip file")
+	}
+
+	if len(archive.File) > maxImportZipEntries {
+		return nil, nil, fmt.Errorf("zip can have at most %d files", maxImportZipEntries)
+	}
+
+	images := map[string][]byte{}
+	var rows []importRow
+	manifestFound := false
+	unpacked := 0
+
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || strings.HasPrefix(path.Base(entry.Name), ".") {
+			continue
+		}
+
+		contents, err := readZipEntry(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		if unpacked += len(contents); unpacked > maxImportUnpackedBytes {
+			return nil, nil, fmt.Errorf("zip contents are larger than %d MB unpacked", maxImportUnpackedBytes>>20)
+		}
+
+		switch entry.Name {
+		case "assets.csv", "assets.json":
+			if manifestFound {
+				return nil, nil, fmt.Errorf("zip must contain only one of assets.csv or assets.json")
+			}
+			manifestFound = true
+			if entry.Name == "assets.csv" {
+				rows, err = parseImportCSV(contents)
+			} else {
+				rows, err = parseImportJSON(contents)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		default:
+			images[entry.Name] = contents
+		}
+	}
+
+	if !manifestFound {
+		return nil, nil, fmt.Errorf("zip must contain assets.csv or assets.json at the top level")
+	}
+
+	return rows, images, nil
+}
+
+// Reads a zip entry without trusting its declared size
+func readZipEntry(entry *zip.File) ([]byte, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from zip", entry.Name)
+	}
+	defer reader.Close()
+
+	contents, err := io.ReadAll(io.LimitReader(reader, maxImportImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from zip", entry.Name)
+	}
+	if len(contents) > maxImportImageBytes {
+		return nil, fmt.Errorf("%s is larger than %d MB", entry.Name, maxImportImageBytes>>20)
+	}
+	return contents, nil
+}
+
+func parseImportJSON(data []byte) ([]importRow, error) {
+	var rows []importRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		// Also accept the {"rows": [...]} form used for JSON bodies
+		var body struct {
+			Rows []importRow `json:"rows"`
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, fmt.Errorf("invalid JSON: expected an array of rows")
+		}
+		rows = body.Rows
+	}
+	return rows, nil
+}
+
+// CSV imports need a header row; unknown columns are ignored
+func parseImportCSV(data []byte) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("CSV header must include a name column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	rows := make([]importRow, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := importRow{
+			Name:        field(record, "name"),
+			Description: field(record, "description"),
+			Type:        field(record, "type"),
+			Image:       field(record, "image"),
+			ImageURL:    field(record, "image_url"),
+		}
+		if genres := field(record, "genres"); genres != "" {
+			row.Genres = strings.Split(genres, "|")
+		}
+		if statBlock := strings.TrimSpace(field(record, "stat_block")); statBlock != "" {
+			row.StatBlock = &models.StatBlock{}
+			if err := json.Unmarshal([]byte(statBlock), row.StatBlock); err != nil {
+				row.StatBlock = nil
+				row.parseError = "stat_block is not valid JSON"
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
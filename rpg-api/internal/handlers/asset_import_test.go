@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/services"
+)
+
+func TestImportAssetsFromURL(t *testing.T) {
+	uploads, store, user := newTestUploadHandler(t)
+	if err := uploads.DB.AutoMigrate(&models.Asset{}); err != nil {
+		t.Fatal(err)
+	}
+	h := &AssetHandler{DB: uploads.DB, Images: store, Rules: uploads.Rules, Quota: uploads.Quota}
+
+	picture := testPNG(t, 40, 30)
+	store.Remote["https://example.com/sword.png"] = picture
+	store.Remote["https://example.com/notes.png"] = []byte("not an image")
+	store.Remote["https://example.com/huge.png"] = testPNG(t, 1200, 10)
+
+	importRows := func(rows ...importRow) ImportReport {
+		body, _ := json.Marshal(map[string]interface{}{"rows": rows})
+		w := serve(h.ImportAssets, user, http.MethodPost, "/assets/import?mode=best_effort", "application/json", bytes.NewReader(body))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Import returned %d: %s", w.Code, w.Body.String())
+		}
+		return decode[ImportReport](t, w)
+	}
+
+	report := importRows(
+		importRow{Name: "Sword", Type: models.AssetTypeItem, ImageURL: "https://example.com/sword.png"},
+		importRow{Name: "Notes", Type: models.AssetTypeItem, ImageURL: "https://example.com/notes.png"},
+		importRow{Name: "Banner", Type: models.AssetTypeItem, ImageURL: "https://example.com/huge.png"},
+	)
+	if report.Created != 1 || report.Rows[0].Status != importRowCreated {
+		t.Fatalf("Got report %+v", report)
+	}
+	for _, row := range report.Rows[1:] {
+		if row.Status != importRowFailed {
+			t.Errorf("Row %d was %s, want failed", row.Row, row.Status)
+		}
+	}
+
+	// The fetched image is stored re-encoded and counts against the quota
+	var asset models.Asset
+	h.DB.First(&asset, *report.Rows[0].AssetID)
+	var upload models.Upload
+	if err := h.DB.Where("image_id = ?", asset.ImageID).First(&upload).Error; err != nil {
+		t.Fatal(err)
+	}
+	if upload.Bytes <= 0 || upload.Bytes != int64(len(store.Images[asset.ImageID])) {
+		t.Errorf("Recorded %d bytes for a %d byte image", upload.Bytes, len(store.Images[asset.ImageID]))
+	}
+	if _, _, err := services.InspectImage(store.Images[asset.ImageID], h.Rules); err != nil {
+		t.Errorf("Stored image doesn't pass the rules: %v", err)
+	}
+
+	// Rejected images are deleted and never recorded
+	if len(store.Images) != 1 {
+		t.Errorf("Store holds %d images, want 1", len(store.Images))
+	}
+	var recorded int64
+	h.DB.Model(&models.Upload{}).Count(&recorded)
+	if recorded != 1 {
+		t.Errorf("%d uploads recorded, want 1", recorded)
+	}
+
+	// Images over the storage quota are rejected once their size is known
+	h.Quota.MaxBytes = upload.Bytes + 10
+	report = importRows(importRow{Name: "Shield", Type: models.AssetTypeItem, ImageURL: "https://example.com/sword.png"})
+	if report.Created != 0 || report.Rows[0].Status != importRowFailed {
+		t.Errorf("Import over the quota gave %+v", report)
+	}
+	if len(store.Images) != 1 {
+		t.Errorf("Store holds %d images after a rejected import, want 1", len(store.Images))
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type AdventureUsage struct {
+	AdventureID uint   `json:"adventure_id"`
+	Title       string `json:"title"`
+}
+
+type SceneUsage struct {
+	SceneID        uint   `json:"scene_id"`
+	SceneTitle     string `json:"scene_title"`
+	EpisodeID      uint   `json:"episode_id"`
+	EpisodeTitle   string `json:"episode_title"`
+	AdventureID    uint   `json:"adventure_id"`
+	AdventureTitle string `json:"adventure_title"`
+}
+
+type CollectionUsage struct {
+	CollectionID uint   `json:"collection_id"`
+	Name         string `json:"name"`
+}
+
+// AssetUsages lists everything that references an asset. References from
+// adventures and collections the viewer can't open are only counted, so
+// their titles aren't leaked.
+type AssetUsages struct {
+	AssetID     uint              `json:"asset_id"`
+	Adventures  []AdventureUsage  `json:"adventures"`
+	Scenes      []SceneUsage      `json:"scenes"`
+	Collections []CollectionUsage `json:"collections"`
+	Hidden      int64             `json:"hidden"` // References in other users' private content
+	Total       int64             `json:"total"`
+}
+
+// GET /assets/:id/usages - every adventure, scene and collection using the asset
+func (h *AssetHandler) GetAssetUsages(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var asset models.Asset
+	query := h.DB.Where("id = ?", id)
+
+	user, isAuthenticated := middleware.GetCurrentUser(c)
+	if isAuthenticated {
+		query = query.Where("is_official = ? OR user_id = ?", true, user.ID)
+	} else {
+		query = query.Where("is_official = ?", true)
+	}
+
+	if err := query.First(&asset).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+
+	usages, err := findAssetUsages(h.DB, asset.ID, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch asset usages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usages)
+}
+
+// Collects every reference to an asset. viewer is nil for anonymous requests.
+func findAssetUsages(db *gorm.DB, assetID uint, viewer *models.User) (AssetUsages, error) {
+	usages := AssetUsages{
+		AssetID:     assetID,
+		Adventures:  []AdventureUsage{},
+		Scenes:      []SceneUsage{},
+		Collections: []CollectionUsage{},
+	}
+
+	// Official adventures or the viewer's own, as in GET /adventures/:id
+	adventureVisible := "adventures.user_id IS NULL"
+	var adventureArgs []interface{}
+	if viewer != nil {
+		adventureVisible = "(adventures.user_id IS NULL OR adventures.user_id = ?)"
+		adventureArgs = []interface{}{viewer.ID}
+	}
+
+	var adventures []struct {
+		AdventureID uint
+		Title       string
+		Visible     bool
+	}
+	if err := db.Raw(`SELECT adventures.id AS adventure_id, adventures.title, `+adventureVisible+` AS visible
+		FROM adventure_assets JOIN adventures ON adventures.id = adventure_assets.adventure_id
+		WHERE adventure_assets.asset_id = ? AND adventures.deleted_at IS NULL
+		ORDER BY adventures.title`, append(adventureArgs, assetID)...).Scan(&adventures).Error; err != nil {
+		return usages, err
+	}
+	for _, row := range adventures {
+		if row.Visible {
+			usages.Adventures = append(usages.Adventures, AdventureUsage{AdventureID: row.AdventureID, Title: row.Title})
+		} else {
+			usages.Hidden++
+		}
+	}
+
+	var scenes []struct {
+		SceneUsage
+		Visible bool
+	}
+	if err := db.Raw(`SELECT scenes.id AS scene_id, scenes.title AS scene_title,
+			episodes.id AS episode_id, episodes.title AS episode_title,
+			adventures.id AS adventure_id, adventures.title AS adventure_title, `+adventureVisible+` AS visible
+		FROM scene_assets
+		JOIN scenes ON scenes.id = scene_assets.scene_id
+		JOIN episodes ON episodes.id = scenes.episode_id
+		JOIN adventures ON adventures.id = episodes.adventure_id
+		WHERE scene_assets.asset_id = ? AND adventures.deleted_at IS NULL
+		ORDER BY adventures.title, episodes."order", scenes."order"`, append(adventureArgs, assetID)...).Scan(&scenes).Error; err != nil {
+		return usages, err
+	}
+	for _, row := range scenes {
+		if row.Visible {
+			usages.Scenes = append(usages.Scenes, row.SceneUsage)
+		} else {
+			usages.Hidden++
+		}
+	}
+
+	// Collections the viewer owns or was shared
+	viewerID := uint(0)
+	if viewer != nil {
+		viewerID = viewer.ID
+	}
+	var collections []struct {
+		CollectionID uint
+		Name         string
+		Visible      bool
+	}
+	if err := db.Raw(`SELECT collections.id AS collection_id, collections.name,
+			(collections.user_id = ? OR EXISTS (SELECT 1 FROM collection_collaborators
+				WHERE collection_collaborators.collection_id = collections.id AND collection_collaborators.user_id = ?)) AS visible
+		FROM collection_items JOIN collections ON collections.id = collection_items.collection_id
+		WHERE collection_items.asset_id = ?
+		ORDER BY collections.name`, viewerID, viewerID, assetID).Scan(&collections).Error; err != nil {
+		return usages, err
+	}
+	for _, row := range collections {
+		if row.Visible {
+			usages.Collections = append(usages.Collections, CollectionUsage{CollectionID: row.CollectionID, Name: row.Name})
+		} else {
+			usages.Hidden++
+		}
+	}
+
+	usages.Total = int64(len(usages.Adventures)+len(usages.Scenes)+len(usages.Collections)) + usages.Hidden
+	return usages, nil
+}
@@ -1,26 +1,30 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/naetharu/rpg-api/internal/middleware"
 	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/query"
 	"github.com/naetharu/rpg-api/internal/services"
 	"gorm.io/gorm"
 )
 
 type AssetHandler struct {
-	DB                *gorm.DB
-	CloudflareService *services.CloudflareImagesService
+	DB     *gorm.DB
+	Images services.ImageStore
+	Rules  services.ImageRules
+	Quota  UploadQuota
 }
 
-func NewAssetHandler(db *gorm.DB) *AssetHandler {
+func NewAssetHandler(db *gorm.DB, images services.ImageStore) *AssetHandler {
 	return &AssetHandler{
-		DB:                db,
-		CloudflareService: services.NewCloudflareImagesService(),
+		DB:     db,
+		Images: images,
+		Rules:  services.ImageRulesFromEnv(),
+		Quota:  UploadQuotaFromEnv(),
 	}
 }
 
@@ -39,6 +43,11 @@ func (h *AssetHandler) CreateAsset(c *gin.Context) {
 		return
 	}
 
+	if err := models.ValidateStatBlock(asset.Type, asset.StatBlock); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stat block: " + err.Error()})
+		return
+	}
+
 	// Set user ownership and ensure it's not official
 	asset.UserID = &user.ID
 	if !user.IsAdmin {
@@ -53,35 +62,59 @@ func (h *AssetHandler) CreateAsset(c *gin.Context) {
 	c.JSON(http.StatusCreated, asset)
 }
 
+// Sorting and filters for GET /assets
+var assetList = query.List{
+	Sorts:        map[string]string{"name": "name", "type": "type", "created_at": "created_at"},
+	DefaultSort:  "created_at",
+	DefaultOrder: "desc",
+	Filters: map[string]query.Filter{
+		"type":        {Column: "type", Kind: query.Equal},
+		"genre":       {Column: "genres", Kind: query.Contains},
+		"is_official": {Column: "is_official", Kind: query.Bool},
+	},
+}
+
 // GET /assets - returns official assets + user's assets if authenticated
 func (h *AssetHandler) GetAssets(c *gin.Context) {
+	params, err := assetList.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var assets []models.Asset
-	query := h.DB
+	db := h.DB
 
 	// Get current user if authenticated (optional auth)
 	user, isAuthenticated := middleware.GetCurrentUser(c)
 
 	if isAuthenticated {
 		// For authenticated users: show official assets + their own assets
-		query = query.Where("is_official = ? OR user_id = ?", true, user.ID)
+		db = db.Where("is_official = ? OR user_id = ?", true, user.ID)
 		if user.IsAdmin {
 			// Admins see official assets + ALL their own assets (official and personal)
-			query = query.Where("is_official = ? OR user_id = ?", true, user.ID)
+			db = db.Where("is_official = ? OR user_id = ?", true, user.ID)
 		} else {
 			// Regular users see official assets + their own personal assets
-			query = query.Where("is_official = ? OR user_id = ?", true, user.ID)
+			db = db.Where("is_official = ? OR user_id = ?", true, user.ID)
 		}
 	} else {
 		// For anonymous users: only show official assets
-		query = query.Where("is_official = ?", true)
+		db = db.Where("is_official = ?", true)
+	}
+
+	// Tags are personal, so ?tag= only narrows results for signed-in users
+	if tag := c.Query("tag"); tag != "" && isAuthenticated {
+		db = db.Where("id IN (SELECT asset_tags.asset_id FROM asset_tags JOIN tags ON tags.id = asset_tags.tag_id WHERE tags.user_id = ? AND tags.name = ?)", user.ID, tag)
 	}
 
-	if err := query.Find(&assets).Error; err != nil {
+	meta, err := params.Find(db, &assets)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assets"})
 		return
 	}
 
-	c.JSON(http.StatusOK, assets)
+	c.JSON(http.StatusOK, query.Response(assets, meta))
 }
 
 // GET /assets/:id - can view official assets or own assets
@@ -150,6 +183,11 @@ func (h *AssetHandler) UpdateAsset(c *gin.Context) {
 		return
 	}
 
+	if err := models.ValidateStatBlock(asset.Type, asset.StatBlock); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stat block: " + err.Error()})
+		return
+	}
+
 	// Ensure they can't change ownership or make it official
 	asset.UserID = &user.ID
 	asset.IsOfficial = false
@@ -162,7 +200,10 @@ func (h *AssetHandler) UpdateAsset(c *gin.Context) {
 	c.JSON(http.StatusOK, asset)
 }
 
-// DELETE /assets/:id - requires authentication and ownership
+// DELETE /assets/:id - requires authentication and ownership. Refuses with
+// 409 and the usage list while anything references the asset, unless ?force=true.
+// Trashed assets drop out of the adventures, scenes and collections using them
+// until they are restored.
 func (h *AssetHandler) DeleteAsset(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -188,19 +229,66 @@ func (h *AssetHandler) DeleteAsset(c *gin.Context) {
 		return
 	}
 
-	// Delete from Cloudflare if it has an ImageID
-	if asset.ImageID != "" {
-		if err := h.CloudflareService.DeleteImage(asset.ImageID); err != nil {
-			// Log the error but don't fail the deletion - we still want to clean up the DB
-			// You might want to add proper logging here
-			fmt.Printf("Warning: Failed to delete image from Cloudflare: %v\n", err)
-		}
+	usages, err := findAssetUsages(h.DB, asset.ID, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check asset usages"})
+		return
 	}
 
+	// Assets still in use are only trashed when the caller asks. Their links
+	// stay so a restore brings them back, and go when the trash is purged.
+	force := c.Query("force") == "true"
+	if usages.Total > 0 && !force {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Asset is still in use. Pass ?force=true to move it to the trash anyway",
+			"usages": usages,
+		})
+		return
+	}
+
+	// Soft delete only - the image and links are removed when the trash is purged
 	if err := h.DB.Delete(&asset).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete asset"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Asset deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Asset moved to trash", "in_use": usages.Total})
+}
+
+// POST /assets/:id/restore - requires authentication and ownership
+func (h *AssetHandler) RestoreAsset(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var asset models.Asset
+	if err := h.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&asset).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found in trash"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	// Check if user owns this asset or is admin
+	if asset.UserID == nil || *asset.UserID != user.ID && !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	if err := h.DB.Unscoped().Model(&asset).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore asset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
 }
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/calendar"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type CalendarHandler struct {
+	DB *gorm.DB
+}
+
+func NewCalendarHandler(db *gorm.DB) *CalendarHandler {
+	return &CalendarHandler{DB: db}
+}
+
+// UnparsedEra is an existing era whose dates the new calendar can't read.
+// It stops containing events until its dates are fixed.
+type UnparsedEra struct {
+	EraID uint   `json:"era_id"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// EraConflict is a pair of existing eras the new calendar would make overlap
+type EraConflict struct {
+	EraID      uint   `json:"era_id"`
+	Name       string `json:"name"`
+	OtherEraID uint   `json:"other_era_id"`
+	OtherName  string `json:"other_name"`
+}
+
+// UnparsedEvent is an existing event whose dates the new calendar can't read.
+// Its day numbers are cleared until the dates are fixed.
+type UnparsedEvent struct {
+	EventID uint   `json:"event_id"`
+	Title   string `json:"title"`
+	Error   string `json:"error"`
+}
+
+// GET /worlds/:id/calendar
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	var worldCalendar models.WorldCalendar
+	if err := h.DB.Where("world_id = ?", worldID).First(&worldCalendar).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This world has no calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, worldCalendar)
+}
+
+// PUT /worlds/:id/calendar - creates or replaces the calendar and re-dates
+// every event in the world
+func (h *CalendarHandler) SaveCalendar(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var world models.World
+	if err := h.DB.First(&world, worldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+	if !canEditWorld(&world, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var request models.WorldCalendar
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Weekdays == nil {
+		request.Weekdays = []string{}
+	}
+	if request.Epochs == nil {
+		request.Epochs = []calendar.Epoch{}
+	}
+	if err := request.Calendar().Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A world without a calendar yet gets a new one
+	var worldCalendar models.WorldCalendar
+	if err := h.DB.Where("world_id = ?", world.ID).First(&worldCalendar).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar"})
+		return
+	}
+	worldCalendar.WorldID = world.ID
+	worldCalendar.Name = strings.TrimSpace(request.Name)
+	worldCalendar.Months = request.Months
+	worldCalendar.Weekdays = request.Weekdays
+	worldCalendar.FirstWeekday = request.FirstWeekday
+	worldCalendar.Epochs = request.Epochs
+	worldCalendar.Leap = request.Leap
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Save(&worldCalendar).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save calendar"})
+		return
+	}
+	cal := worldCalendar.Calendar()
+	unparsed, err := redateEvents(tx, world.ID, &cal)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event dates"})
+		return
+	}
+	unparsedEras, conflicts, err := redateEras(tx, world.ID, &cal)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update era dates"})
+		return
+	}
+	if len(conflicts) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "The calendar would make eras overlap", "conflicts": conflicts})
+		return
+	}
+	if err := assignEventEras(tx, world.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign events to eras"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"calendar": worldCalendar, "unparsed_events": unparsed, "unparsed_eras": unparsedEras})
+}
+
+// DELETE /worlds/:id/calendar - event dates go back to free text
+func (h *CalendarHandler) DeleteCalendar(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var world models.World
+	if err := h.DB.First(&world, worldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+	if !canEditWorld(&world, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Where("world_id = ?", world.ID).Delete(&models.WorldCalendar{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar"})
+		return
+	}
+	if _, err := redateEvents(tx, world.ID, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event dates"})
+		return
+	}
+	if _, _, err := redateEras(tx, world.ID, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update era dates"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar deleted successfully"})
+}
+
+// GET /worlds/:id/calendar/parse?date=15 Frostfall 1203 - checks a date and
+// shows how the calendar reads it
+func (h *CalendarHandler) ParseDate(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	cal, err := loadCalendar(h.DB, uint(worldID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load calendar"})
+		return
+	}
+	if cal == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This world has no calendar"})
+		return
+	}
+
+	date, err := cal.Parse(c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	first, last := cal.Span(date)
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":      date,
+		"formatted": cal.Format(date),
+		"first_day": first,
+		"last_day":  last,
+		"weekday":   cal.Weekday(first),
+		"leap_year": cal.IsLeap(date.Year),
+	})
+}
+
+// Loads a world's calendar, nil when it has none
+func loadCalendar(db *gorm.DB, worldID uint) (*calendar.Calendar, error) {
+	var worldCalendar models.WorldCalendar
+	err := db.Where("world_id = ?", worldID).First(&worldCalendar).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cal := worldCalendar.Calendar()
+	return &cal, nil
+}
+
+// Sets an event's day numbers from its dates. Without a calendar they are
+// cleared and the dates stay free text.
+func dateEvent(cal *calendar.Calendar, event *models.TimelineEvent) error {
+	event.StartDay, event.EndDay, event.DurationDays = nil, nil, nil
+	if cal == nil {
+		return nil
+	}
+
+	first, last, err := cal.Range(event.StartDate, event.EndDate)
+	if err != nil {
+		return err
+	}
+	duration := last - first + 1
+	event.StartDay, event.EndDay, event.DurationDays = &first, &last, &duration
+	return nil
+}
+
+// Recomputes the day numbers of every event in a world, returning the events
+// the calendar can't read
+func redateEvents(tx *gorm.DB, worldID uint, cal *calendar.Calendar) ([]UnparsedEvent, error) {
+	var events []models.TimelineEvent
+	if err := tx.Where("world_id = ?", worldID).Order("id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	unparsed := []UnparsedEvent{}
+	for i := range events {
+		event := &events[i]
+		if err := dateEvent(cal, event); err != nil {
+			unparsed = append(unparsed, UnparsedEvent{EventID: event.ID, Title: event.Title, Error: err.Error()})
+		}
+		if err := tx.Model(event).UpdateColumns(map[string]interface{}{
+			"start_day":     event.StartDay,
+			"end_day":       event.EndDay,
+			"duration_days": event.DurationDays,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return unparsed, nil
+}
+
+// Recomputes the day numbers of every dated era in a world, returning the
+// eras the calendar can't read and the pairs it would make overlap
+func redateEras(tx *gorm.DB, worldID uint, cal *calendar.Calendar) ([]UnparsedEra, []EraConflict, error) {
+	var eras []models.WorldEra
+	if err := tx.Where("world_id = ?", worldID).Order("id").Find(&eras).Error; err != nil {
+		return nil, nil, err
+	}
+
+	unparsed := []UnparsedEra{}
+	for i := range eras {
+		era := &eras[i]
+		era.StartDay, era.EndDay = nil, nil
+		if cal != nil && era.StartDate != "" {
+			first, last, err := cal.Range(era.StartDate, era.EndDate)
+			if err != nil {
+				unparsed = append(unparsed, UnparsedEra{EraID: era.ID, Name: era.Name, Error: err.Error()})
+			} else {
+				era.StartDay = &first
+				if era.EndDate != nil && *era.EndDate != "" {
+					era.EndDay = &last
+				}
+			}
+		}
+		if err := tx.Model(era).UpdateColumns(map[string]interface{}{
+			"start_day": era.StartDay,
+			"end_day":   era.EndDay,
+		}).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Events go in the one era containing them, so eras must stay apart
+	conflicts := []EraConflict{}
+	for _, pair := range models.OverlappingEras(eras) {
+		conflicts = append(conflicts, EraConflict{EraID: pair[0].ID, Name: pair[0].Name, OtherEraID: pair[1].ID, OtherName: pair[1].Name})
+	}
+	return unparsed, conflicts, nil
+}
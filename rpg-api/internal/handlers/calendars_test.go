@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/testdb"
+)
+
+func TestSaveCalendarRejectsOverlappingEras(t *testing.T) {
+	db := testdb.Open(t, &models.User{}, &models.World{}, &models.WorldCalendar{}, &models.WorldEra{}, &models.TimelineEvent{})
+	user := models.User{Email: "gm@example.com", Name: "GM", Provider: "email"}
+	db.Create(&user)
+	world := models.World{Title: "Varn", UserID: &user.ID}
+	db.Create(&world)
+
+	// Free-text dates aren't checked, so these were saved before the world
+	// had a calendar
+	end := "31 Frostfall 1202"
+	founding := models.WorldEra{WorldID: world.ID, Name: "Founding", SortOrder: 1, StartDate: "1 Thawmoon 1200", EndDate: &end}
+	war := models.WorldEra{WorldID: world.ID, Name: "Long War", SortOrder: 2, StartDate: "1 Thawmoon 1202"}
+	db.Create(&founding)
+	db.Create(&war)
+
+	h := &CalendarHandler{DB: db}
+	w := serveID(h.SaveCalendar, &user, world.ID, http.MethodPut, "/worlds/1/calendar", "application/json", bytes.NewBufferString(
+		`{"name":"Reckoning","months":[{"name":"Thawmoon","days":30},{"name":"Frostfall","days":31}]}`))
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Got %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+	}
+	response := decode[struct{ Conflicts []EraConflict }](t, w)
+	want := EraConflict{EraID: founding.ID, Name: "Founding", OtherEraID: war.ID, OtherName: "Long War"}
+	if len(response.Conflicts) != 1 || response.Conflicts[0] != want {
+		t.Errorf("Got conflicts %+v, want %+v", response.Conflicts, want)
+	}
+
+	// Nothing is saved
+	var calendars int64
+	db.Model(&models.WorldCalendar{}).Count(&calendars)
+	var dated int64
+	db.Model(&models.WorldEra{}).Where("start_day IS NOT NULL").Count(&dated)
+	if calendars != 0 || dated != 0 {
+		t.Errorf("Rejected calendar left %d calendars and %d dated eras", calendars, dated)
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/dice"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/realtime"
+)
+
+// POST /sessions/:id/rolls - roll on the server and record it in the session's roll log
+func (h *PlaySessionHandler) RollDice(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var session models.PlaySession
+	if err := h.DB.First(&session, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	roll := models.DiceRoll{SessionID: session.ID}
+	if !h.identifyRoller(c, &session, &roll) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if session.Status != models.SessionStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session has ended"})
+		return
+	}
+
+	var request struct {
+		Expression string `json:"expression"`
+		Label      string `json:"label"`
+		Seed       *int64 `json:"seed"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A plain Simple D6 check is the default
+	if request.Expression == "" {
+		request.Expression = "1d6"
+	}
+
+	expr, err := dice.Parse(request.Expression)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dice expression: " + err.Error()})
+		return
+	}
+
+	seed := time.Now().UnixNano()
+	if request.Seed != nil {
+		seed = *request.Seed
+		roll.SeedProvided = true
+	}
+
+	result := dice.Roll(expr, seed)
+
+	roll.Label = request.Label
+	roll.Expression = result.Expression
+	roll.Seed = result.Seed
+	roll.Terms = result.Terms
+	roll.Natural = result.Natural
+	roll.Modifier = result.Modifier
+	roll.Total = result.Total
+	roll.Outcome = result.Outcome
+
+	if err := h.DB.Create(&roll).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record roll"})
+		return
+	}
+
+	h.PubSub.Publish(session.ID, realtime.Message{Type: realtime.MessageDice, Payload: roll})
+
+	c.JSON(http.StatusCreated, roll)
+}
+
+// GET /sessions/:id/rolls - the full roll log, visible to every participant
+func (h *PlaySessionHandler) GetRolls(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var session models.PlaySession
+	if err := h.DB.First(&session, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if !h.canWatchSession(c, &session) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	var rolls []models.DiceRoll
+	if err := h.DB.Where("session_id = ?", session.ID).Order("created_at ASC, id ASC").Find(&rolls).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rolls"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rolls)
+}
+
+// Fills in who is rolling: a player holding a token, a signed-in player, or the GM
+func (h *PlaySessionHandler) identifyRoller(c *gin.Context, session *models.PlaySession, roll *models.DiceRoll) bool {
+	if token := c.GetHeader(playerTokenHeader); token != "" {
+		var player models.PlaySessionPlayer
+		if err := h.DB.Where("session_id = ? AND token = ?", session.ID, token).First(&player).Error; err == nil {
+			roll.PlayerID = &player.ID
+			roll.UserID = player.UserID
+			roll.RollerName = player.Name
+			return true
+		}
+	}
+
+	user, isAuthenticated := middleware.GetCurrentUser(c)
+	if !isAuthenticated {
+		return false
+	}
+
+	if session.GMID == user.ID {
+		roll.UserID = &user.ID
+		roll.RollerName = user.Name
+		return true
+	}
+
+	var player models.PlaySessionPlayer
+	if err := h.DB.Where("session_id = ? AND user_id = ?", session.ID, user.ID).First(&player).Error; err != nil {
+		return false
+	}
+	roll.PlayerID = &player.ID
+	roll.UserID = &user.ID
+	roll.RollerName = player.Name
+	return true
+}
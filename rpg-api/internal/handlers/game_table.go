@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/auth"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/realtime"
+)
+
+// How often an idle stream is pinged so proxies don't close it
+const streamHeartbeatInterval = 25 * time.Second
+
+// Players send the token from the join response in this header
+const playerTokenHeader = "X-Player-Token"
+
+// What players see when the GM moves to a scene - never the GM notes
+type ScenePayload struct {
+	SceneID  uint   `json:"scene_id"`
+	Title    string `json:"title"`
+	ImageURL string `json:"image_url"`
+	Prose    string `json:"prose"`
+}
+
+func newScenePayload(scene *models.Scene) ScenePayload {
+	return ScenePayload{
+		SceneID:  scene.ID,
+		Title:    scene.Title,
+		ImageURL: scene.ImageURL,
+		Prose:    scene.Prose,
+	}
+}
+
+// POST /play/join - players join a running session with its join code
+func (h *PlaySessionHandler) JoinSession(c *gin.Context) {
+	var request struct {
+		Code          string `json:"code" binding:"required"`
+		Name          string `json:"name" binding:"required"`
+		CharacterName string `json:"character_name"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var session models.PlaySession
+	if err := h.DB.Where("join_code = ? AND status = ?", strings.ToUpper(strings.TrimSpace(request.Code)), models.SessionStatusActive).
+		First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No running session with that code"})
+		return
+	}
+
+	token, err := models.GenerateSecureToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate player token"})
+		return
+	}
+
+	player := models.PlaySessionPlayer{
+		SessionID:     session.ID,
+		Name:          request.Name,
+		CharacterName: request.CharacterName,
+		Token:         token,
+		JoinedAt:      time.Now(),
+	}
+
+	// Signed-in players rejoining keep their existing seat
+	if user, isAuthenticated := middleware.GetCurrentUser(c); isAuthenticated {
+		player.UserID = &user.ID
+		var existing models.PlaySessionPlayer
+		if err := h.DB.Where("session_id = ? AND user_id = ?", session.ID, user.ID).First(&existing).Error; err == nil {
+			player.ID = existing.ID
+			player.JoinedAt = existing.JoinedAt
+		}
+	}
+
+	if err := h.DB.Save(&player).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join session"})
+		return
+	}
+
+	h.PubSub.Publish(session.ID, realtime.Message{Type: realtime.MessagePlayerJoined, Payload: player})
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": session.ID,
+		"player":     player,
+		"token":      token,
+	})
+}
+
+// POST /sessions/:id/stream-ticket - a short-lived ticket for opening the
+// stream, since browser EventSource can't send an Authorization or player
+// token header and those shouldn't end up in URLs and access logs
+func (h *PlaySessionHandler) CreateStreamTicket(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var session models.PlaySession
+	if err := h.DB.First(&session, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if !h.canWatchSession(c, &session) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if session.Status != models.SessionStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session has ended"})
+		return
+	}
+
+	ticket, expiresAt, err := auth.GenerateStreamTicket(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate stream ticket"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+	})
+}
+
+// GET /sessions/:id/stream - server-sent events for the game table.
+// Browsers open it with ?ticket= from POST /sessions/:id/stream-ticket;
+// other clients can send their credentials as headers instead.
+func (h *PlaySessionHandler) StreamSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var session models.PlaySession
+	if err := h.DB.First(&session, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	// A ticket stays valid for its whole lifetime so EventSource can reconnect
+	// with the same URL after a dropped connection
+	var allowed bool
+	if ticket := c.Query("ticket"); ticket != "" {
+		allowed = auth.ValidateStreamTicket(ticket, session.ID) == nil
+	} else {
+		allowed = h.canWatchSession(c, &session)
+	}
+	if !allowed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if session.Status != models.SessionStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session has ended"})
+		return
+	}
+
+	messages, unsubscribe := h.PubSub.Subscribe(session.ID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Late joiners start on the current scene
+	if session.CurrentSceneID != nil {
+		var scene models.Scene
+		if err := h.DB.First(&scene, *session.CurrentSceneID).Error; err == nil {
+			c.SSEvent(realtime.MessageScene, realtime.Message{
+				Type:      realtime.MessageScene,
+				SessionID: session.ID,
+				Payload:   newScenePayload(&scene),
+				SentAt:    time.Now(),
+			})
+			c.Writer.Flush()
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return false
+			}
+			c.SSEvent(message.Type, message)
+			// Nothing more will happen once the session is over
+			return message.Type != realtime.MessageEnded
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"time": time.Now()})
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// POST /sessions/:id/broadcast - GM pushes something to every player screen
+func (h *PlaySessionHandler) Broadcast(c *gin.Context) {
+	session, ok := h.loadSession(c, true)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.SessionStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session has ended"})
+		return
+	}
+
+	var request struct {
+		Type    string `json:"type" binding:"required"` // reveal_asset, handout, dice, message
+		AssetID *uint  `json:"asset_id"`
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		// Image for handouts
+		ImageURL string `json:"image_url"`
+		// Dice results rolled at the table
+		Dice []int `json:"dice"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := realtime.Message{Type: request.Type}
+
+	switch request.Type {
+	case realtime.MessageRevealAsset:
+		if request.AssetID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "asset_id is required"})
+			return
+		}
+		// Only assets the GM could see themselves can be revealed
+		var asset models.Asset
+		if err := h.DB.Where("(is_official = ? OR user_id = ?) AND id = ?", true, session.GMID, *request.AssetID).First(&asset).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+			return
+		}
+		message.Payload = asset
+	case realtime.MessageHandout:
+		if request.Title == "" && request.Body == "" && request.ImageURL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Handout needs a title, body or image"})
+			return
+		}
+		message.Payload = gin.H{"title": request.Title, "body": request.Body, "image_url": request.ImageURL}
+	case realtime.MessageDice:
+		if len(request.Dice) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dice is required"})
+			return
+		}
+		message.Payload = gin.H{"title": request.Title, "dice": request.Dice}
+	case realtime.MessageText:
+		if request.Body == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "body is required"})
+			return
+		}
+		message.Payload = gin.H{"title": request.Title, "body": request.Body}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown broadcast type"})
+		return
+	}
+
+	h.PubSub.Publish(session.ID, message)
+	c.JSON(http.StatusOK, gin.H{"message": "Broadcast sent"})
+}
+
+// The GM, signed-in players, and anyone holding a player token can watch
+func (h *PlaySessionHandler) canWatchSession(c *gin.Context, session *models.PlaySession) bool {
+	if token := c.GetHeader(playerTokenHeader); token != "" {
+		var count int64
+		h.DB.Model(&models.PlaySessionPlayer{}).Where("session_id = ? AND token = ?", session.ID, token).Count(&count)
+		if count > 0 {
+			return true
+		}
+	}
+
+	user, isAuthenticated := middleware.GetCurrentUser(c)
+	if !isAuthenticated {
+		return false
+	}
+	if session.GMID == user.ID {
+		return true
+	}
+
+	var count int64
+	h.DB.Model(&models.PlaySessionPlayer{}).Where("session_id = ? AND user_id = ?", session.ID, user.ID).Count(&count)
+	return count > 0
+}
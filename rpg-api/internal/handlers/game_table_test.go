@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/auth"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/realtime"
+	"github.com/naetharu/rpg-api/internal/testdb"
+)
+
+// gin streams need a writer that can report the client going away
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (streamRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+// Runs a game table handler for a player without an account
+func serveGuest(handler gin.HandlerFunc, sessionID uint, method, target string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(streamRecorder{w})
+	// Streams stop as soon as the client has gone
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Request = httptest.NewRequest(method, target, nil).WithContext(ctx)
+	for key, values := range header {
+		c.Request.Header[key] = values
+	}
+	c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(sessionID), 10)}}
+	handler(c)
+	return w
+}
+
+func TestStreamTickets(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	db := testdb.Open(t, &models.User{}, &models.PlaySession{}, &models.PlaySessionPlayer{}, &models.Scene{})
+	gm := models.User{Email: "gm@example.com", Name: "GM", Provider: "email", IsActive: true}
+	db.Create(&gm)
+	session := models.PlaySession{AdventureID: 1, GMID: gm.ID, Status: models.SessionStatusActive}
+	other := models.PlaySession{AdventureID: 1, GMID: gm.ID, Status: models.SessionStatusActive}
+	db.Create(&session)
+	db.Create(&other)
+	player := models.PlaySessionPlayer{SessionID: session.ID, Name: "Ren", Token: "player-token"}
+	db.Create(&player)
+
+	h := &PlaySessionHandler{DB: db, PubSub: realtime.NewHub()}
+	withToken := http.Header{playerTokenHeader: {player.Token}}
+
+	// The player token only works as a header, never in the URL
+	if w := serveGuest(h.CreateStreamTicket, session.ID, http.MethodPost, "/sessions/1/stream-ticket?token="+player.Token, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Ticket for ?token=: got %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := serveGuest(h.StreamSession, session.ID, http.MethodGet, "/sessions/1/stream?token="+player.Token, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Stream with ?token=: got %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := serveGuest(h.CreateStreamTicket, other.ID, http.MethodPost, "/sessions/2/stream-ticket", withToken); w.Code != http.StatusNotFound {
+		t.Errorf("Ticket for another session: got %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w := serveGuest(h.CreateStreamTicket, session.ID, http.MethodPost, "/sessions/1/stream-ticket", withToken)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Got %d: %s", w.Code, w.Body.String())
+	}
+	ticket := decode[struct{ Ticket string }](t, w).Ticket
+
+	login, err := auth.GenerateJWT(&gm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherTicket, _, err := auth.GenerateStreamTicket(other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name   string
+		ticket string
+		status int
+	}{
+		{"the ticket", ticket, http.StatusOK},
+		{"another session's ticket", otherTicket, http.StatusNotFound},
+		{"a login token", login, http.StatusNotFound},
+		{"the player token", player.Token, http.StatusNotFound},
+	} {
+		if w := serveGuest(h.StreamSession, session.ID, http.MethodGet, "/sessions/1/stream?ticket="+test.ticket, nil); w.Code != test.status {
+			t.Errorf("Stream with %s: got %d, want %d: %s", test.name, w.Code, test.status, w.Body.String())
+		}
+	}
+
+	// Tickets can't be used to sign in
+	if _, err := auth.ValidateJWT(ticket); err == nil {
+		t.Error("A stream ticket passed as a login token")
+	}
+}
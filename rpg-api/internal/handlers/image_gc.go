@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/jobs"
+	"github.com/naetharu/rpg-api/internal/middleware"
+)
+
+// ImageGCHandler lets admins see and run orphaned image collection
+type ImageGCHandler struct {
+	Collector *jobs.ImageCollector
+}
+
+func NewImageGCHandler(collector *jobs.ImageCollector) *ImageGCHandler {
+	return &ImageGCHandler{Collector: collector}
+}
+
+// GET /admin/images/orphans - dry run listing the images collection would delete
+func (h *ImageGCHandler) GetOrphans(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists || !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	report, err := h.Collector.Collect(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find orphaned images"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// POST /admin/images/collect - delete orphaned images now; ?dry_run=true only reports
+func (h *ImageGCHandler) CollectOrphans(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists || !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	report, err := h.Collector.Collect(c.Query("dry_run") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect orphaned images"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/services"
+)
+
+// ImageFileHandler serves images kept by the local image store and accepts
+// their direct uploads. Cloudflare does both itself, so this is only
+// registered for IMAGE_STORE=local.
+type ImageFileHandler struct {
+	Store *services.LocalImageStore
+	Rules services.ImageRules
+}
+
+func NewImageFileHandler(store *services.LocalImageStore) *ImageFileHandler {
+	return &ImageFileHandler{Store: store, Rules: services.ImageRulesFromEnv()}
+}
+
+// GET /images/:id/:variant - one variant of a stored image
+func (h *ImageFileHandler) GetImage(c *gin.Context) {
+	path, err := h.Store.Path(c.Param("id"), c.Param("variant"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	// Image IDs are never reused, so variants can be cached forever
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.File(path)
+}
+
+// POST /images/upload/:id?expires=&signature= - the signed URL handed out by
+// POST /api/upload/url. The signature stands in for auth.
+func (h *ImageFileHandler) DirectUpload(c *gin.Context) {
+	imageID := c.Param("id")
+	if err := h.Store.VerifyDirectUpload(imageID, c.Query("expires"), c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Upload URL is invalid or has expired"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Rules.MaxBytes+1<<20)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Image too large: the limit is %d MB", h.Rules.MaxBytes>>20)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		return
+	}
+	defer file.Close()
+
+	// Same checks as uploads through the API
+	image, err := services.PrepareImage(file, header.Filename, h.Rules)
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": uploadErrorMessage(err)})
+		return
+	}
+
+	stored, err := h.Store.UploadAs(imageID, bytes.NewReader(image.Data), image.Filename)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to store image: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"image_id": stored.ID, "filename": stored.Filename})
+}
@@ -0,0 +1,565 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/query"
+	"github.com/naetharu/rpg-api/internal/wiki"
+	"gorm.io/gorm"
+)
+
+type LoreHandler struct {
+	DB *gorm.DB
+}
+
+func NewLoreHandler(db *gorm.DB) *LoreHandler {
+	return &LoreHandler{DB: db}
+}
+
+// Sorting and filters for GET /worlds/:id/lore
+var loreList = query.List{
+	Sorts:        map[string]string{"title": "title", "created_at": "created_at", "updated_at": "updated_at"},
+	DefaultSort:  "title",
+	DefaultOrder: "asc",
+	Filters: map[string]query.Filter{
+		"category": {Column: "categories", Kind: query.Contains},
+		"title":    {Column: "title", Kind: query.Like},
+	},
+}
+
+// ResolvedLink is a link in an article body and what it points at. Target
+// is nil when the link is broken.
+type ResolvedLink struct {
+	wiki.Link
+	Target *wiki.Target `json:"target"`
+}
+
+type LoreArticleResponse struct {
+	models.LoreArticle
+	Links []ResolvedLink `json:"links"`
+}
+
+type LoreBacklink struct {
+	ArticleID uint   `json:"article_id"`
+	Title     string `json:"title"`
+	Summary   string `json:"summary"`
+	Label     string `json:"label"`
+}
+
+type BrokenLoreLink struct {
+	ArticleID    uint      `json:"article_id"`
+	ArticleTitle string    `json:"article_title"`
+	Link         wiki.Link `json:"link"`
+}
+
+// GET /worlds/:id/lore - articles without their bodies
+func (h *LoreHandler) GetArticles(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	params, err := loreList.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var articles []models.LoreArticle
+	meta, err := params.Find(h.DB.Omit("body").Where("world_id = ?", worldID), &articles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lore articles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, query.Response(articles, meta))
+}
+
+// GET /worlds/:id/lore/categories - every category in use with its article count
+func (h *LoreHandler) GetCategories(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	categories := []struct {
+		Name  string `json:"name"`
+		Count int64  `json:"count"`
+	}{}
+	if err := h.DB.Raw(`SELECT category AS name, COUNT(*) AS count
+		FROM lore_articles, unnest(categories) AS category
+		WHERE world_id = ?
+		GROUP BY category ORDER BY category`, worldID).Scan(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// GET /worlds/:id/lore/:articleId - an article with its links resolved
+func (h *LoreHandler) GetArticle(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	articleID, err := strconv.Atoi(c.Param("articleId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	var article models.LoreArticle
+	if err := h.DB.Preload("User").Where("world_id = ? AND id = ?", worldID, articleID).First(&article).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+
+	response, err := h.resolveArticle(article)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve links"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// POST /worlds/:id/lore
+func (h *LoreHandler) CreateArticle(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var world models.World
+	if err := h.DB.First(&world, worldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+	if !canEditWorld(&world, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var article models.LoreArticle
+	if err := c.ShouldBindJSON(&article); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	article.ID = 0
+	article.WorldID = world.ID
+	article.UserID = &user.ID
+	article.Title = strings.TrimSpace(article.Title)
+	article.Categories = cleanCategories(article.Categories)
+	if article.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+
+	// Links find articles by title, so titles are unique within a world
+	if h.titleTaken(world.ID, article.Title, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An article with this title already exists"})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Create(&article).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create article"})
+		return
+	}
+	if err := wiki.SaveLinks(tx, article); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save links"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create article"})
+		return
+	}
+
+	response, err := h.resolveArticle(article)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve links"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// PATCH /worlds/:id/lore/:articleId
+func (h *LoreHandler) UpdateArticle(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	articleID, err := strconv.Atoi(c.Param("articleId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var world models.World
+	if err := h.DB.First(&world, worldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+	if !canEditWorld(&world, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var article models.LoreArticle
+	if err := h.DB.Where("world_id = ? AND id = ?", worldID, articleID).First(&article).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+
+	var updates struct {
+		Title      *string   `json:"title"`
+		Summary    *string   `json:"summary"`
+		Body       *string   `json:"body"`
+		Categories *[]string `json:"categories"`
+		ImageURL   *string   `json:"image_url"`
+		ImageID    *string   `json:"image_id"`
+	}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if updates.Title != nil {
+		title := strings.TrimSpace(*updates.Title)
+		if title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+			return
+		}
+		if h.titleTaken(article.WorldID, title, article.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "An article with this title already exists"})
+			return
+		}
+		article.Title = title
+	}
+	if updates.Summary != nil {
+		article.Summary = *updates.Summary
+	}
+	if updates.Body != nil {
+		article.Body = *updates.Body
+	}
+	if updates.Categories != nil {
+		article.Categories = cleanCategories(*updates.Categories)
+	}
+	if updates.ImageURL != nil {
+		article.ImageURL = *updates.ImageURL
+	}
+	if updates.ImageID != nil {
+		article.ImageID = *updates.ImageID
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Save(&article).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update article"})
+		return
+	}
+	if updates.Body != nil {
+		if err := wiki.SaveLinks(tx, article); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save links"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update article"})
+		return
+	}
+
+	response, err := h.resolveArticle(article)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve links"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DELETE /worlds/:id/lore/:articleId - links to it from other articles become broken
+func (h *LoreHandler) DeleteArticle(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	articleID, err := strconv.Atoi(c.Param("articleId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var world models.World
+	if err := h.DB.First(&world, worldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+	if !canEditWorld(&world, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var article models.LoreArticle
+	if err := h.DB.Where("world_id = ? AND id = ?", worldID, articleID).First(&article).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Where("article_id = ?", article.ID).Delete(&models.LoreLink{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete article"})
+		return
+	}
+	if err := tx.Delete(&article).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete article"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete article"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Article deleted successfully"})
+}
+
+// GET /worlds/:id/lore/backlinks?type=npc&id=12 - articles linking to something
+func (h *LoreHandler) GetBacklinks(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	targetType := c.Query("type")
+	targetID, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		return
+	}
+	valid := false
+	for _, linkType := range wiki.Types() {
+		valid = valid || linkType == targetType
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be one of " + strings.Join(wiki.Types(), ", ")})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	resolver, err := wiki.NewResolver(h.DB, uint(worldID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve links"})
+		return
+	}
+
+	backlinks := []LoreBacklink{}
+	target, ok := resolver.Lookup(targetType, uint(targetID))
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"type": targetType, "id": targetID, "backlinks": backlinks})
+		return
+	}
+
+	// Candidates are links by this name; resolving them weeds out the ones an
+	// unqualified link sends to something else with the same name
+	var links []models.LoreLink
+	if err := h.DB.Where("world_id = ? AND target_name = ? AND target_type IN ?", worldID,
+		strings.ToLower(strings.TrimSpace(target.Name)), []string{"", targetType}).
+		Order("article_id").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch backlinks"})
+		return
+	}
+
+	labels := make(map[uint]string)
+	var articleIDs []uint
+	for _, link := range links {
+		target, ok := resolver.Resolve(wiki.Link{Type: link.TargetType, Name: link.TargetName})
+		if !ok || target.Type != targetType || target.ID != uint(targetID) {
+			continue
+		}
+		if _, seen := labels[link.ArticleID]; !seen {
+			articleIDs = append(articleIDs, link.ArticleID)
+		}
+		labels[link.ArticleID] = link.Label
+	}
+
+	if len(articleIDs) > 0 {
+		var articles []models.LoreArticle
+		if err := h.DB.Select("id, title, summary").Where("id IN ?", articleIDs).Order("title").Find(&articles).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch backlinks"})
+			return
+		}
+		for _, article := range articles {
+			backlinks = append(backlinks, LoreBacklink{ArticleID: article.ID, Title: article.Title, Summary: article.Summary, Label: labels[article.ID]})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"type": targetType, "id": targetID, "backlinks": backlinks})
+}
+
+// GET /worlds/:id/lore/broken-links - every link that doesn't resolve
+func (h *LoreHandler) GetBrokenLinks(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	resolver, err := wiki.NewResolver(h.DB, uint(worldID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve links"})
+		return
+	}
+
+	var rows []struct {
+		models.LoreLink
+		ArticleTitle string
+	}
+	if err := h.DB.Table("lore_links").
+		Select("lore_links.*, lore_articles.title AS article_title").
+		Joins("JOIN lore_articles ON lore_articles.id = lore_links.article_id").
+		Where("lore_links.world_id = ?", worldID).
+		Order("lore_articles.title, lore_links.id").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch links"})
+		return
+	}
+
+	broken := []BrokenLoreLink{}
+	for _, row := range rows {
+		link := wiki.Link{Raw: row.Raw, Type: row.TargetType, Name: row.TargetName, Label: row.Label}
+		if _, ok := resolver.Resolve(link); !ok {
+			broken = append(broken, BrokenLoreLink{ArticleID: row.ArticleID, ArticleTitle: row.ArticleTitle, Link: link})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"broken": broken, "total": len(broken)})
+}
+
+// Resolves every link in an article's body
+func (h *LoreHandler) resolveArticle(article models.LoreArticle) (LoreArticleResponse, error) {
+	response := LoreArticleResponse{LoreArticle: article, Links: []ResolvedLink{}}
+
+	links := wiki.ParseLinks(article.Body)
+	if len(links) == 0 {
+		return response, nil
+	}
+
+	resolver, err := wiki.NewResolver(h.DB, article.WorldID)
+	if err != nil {
+		return response, err
+	}
+	for _, link := range links {
+		resolved := ResolvedLink{Link: link}
+		if target, ok := resolver.Resolve(link); ok {
+			resolved.Target = &target
+		}
+		response.Links = append(response.Links, resolved)
+	}
+	return response, nil
+}
+
+func (h *LoreHandler) titleTaken(worldID uint, title string, exceptID uint) bool {
+	var count int64
+	h.DB.Model(&models.LoreArticle{}).
+		Where("world_id = ? AND LOWER(title) = LOWER(?) AND id <> ?", worldID, title, exceptID).
+		Count(&count)
+	return count > 0
+}
+
+// Trims categories and drops blanks and repeats
+func cleanCategories(categories []string) pq.StringArray {
+	cleaned := pq.StringArray{}
+	seen := map[string]bool{}
+	for _, category := range categories {
+		category = strings.TrimSpace(category)
+		if category == "" || seen[strings.ToLower(category)] {
+			continue
+		}
+		seen[strings.ToLower(category)] = true
+		cleaned = append(cleaned, category)
+	}
+	return cleaned
+}
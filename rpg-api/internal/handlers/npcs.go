@@ -3,12 +3,14 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/naetharu/rpg-api/internal/generators"
 	"github.com/naetharu/rpg-api/internal/middleware"
 	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/query"
 	"gorm.io/gorm"
 )
 
@@ -20,7 +22,21 @@ func NewNPCHandler(db *gorm.DB) *NPCHandler {
 	return &NPCHandler{DB: db}
 }
 
-// GET /worlds/:id/npcs
+// Sorting and filters for GET /worlds/:id/npcs
+var npcList = query.List{
+	Sorts:        map[string]string{"name": "name", "profession": "profession", "social_class": "social_class", "created_at": "created_at"},
+	DefaultSort:  "name",
+	DefaultOrder: "asc",
+	Filters: map[string]query.Filter{
+		"profession":   {Column: "profession", Kind: query.Equal},
+		"social_class": {Column: "social_class", Kind: query.Equal},
+		"location":     {Column: "location_id", Kind: query.ID},
+		"name":         {Column: "name", Kind: query.Like},
+	},
+}
+
+// GET /worlds/:id/npcs - pass ?include=memberships,relationships for the
+// heavier associations, which are left out of the list by default
 func (h *NPCHandler) GetNPCs(c *gin.Context) {
 	worldID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -32,34 +48,45 @@ func (h *NPCHandler) GetNPCs(c *gin.Context) {
 	var world models.World
 	user, _ := middleware.GetCurrentUser(c)
 
-	query := h.DB
+	db := h.DB
 	if user == nil {
-		query = query.Where("id = ? AND (is_official = ? OR reviewed = ?)", worldID, true, true)
+		db = db.Where("id = ? AND (is_official = ? OR reviewed = ?)", worldID, true, true)
 	} else if !user.IsAdmin {
-		query = query.Where("id = ? AND (is_official = ? OR reviewed = ? OR user_id = ?)", worldID, true, true, user.ID)
+		db = db.Where("id = ? AND (is_official = ? OR reviewed = ? OR user_id = ?)", worldID, true, true, user.ID)
 	} else {
-		query = query.Where("id = ?", worldID)
+		db = db.Where("id = ?", worldID)
 	}
 
-	if err := query.First(&world).Error; err != nil {
+	if err := db.First(&world).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
 		return
 	}
 
+	params, err := npcList.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preloads := []string{"Location"}
+	for _, include := range strings.Split(c.Query("include"), ",") {
+		switch strings.TrimSpace(include) {
+		case "memberships":
+			preloads = append(preloads, "Memberships.Organization", "Memberships.Rank")
+		case "relationships":
+			preloads = append(preloads, "FromRelationships.ToNPC", "ToRelationships.FromNPC")
+		}
+	}
+
 	// Get NPCs for this world
 	var npcs []models.NPC
-	if err := h.DB.Where("world_id = ?", worldID).
-		Preload("Location").
-		Preload("Memberships.Organization").
-		Preload("Memberships.Rank").
-		Preload("FromRelationships.ToNPC").
-		Preload("ToRelationships.FromNPC").
-		Find(&npcs).Error; err != nil {
+	meta, err := params.Find(h.DB.Where("world_id = ?", worldID), &npcs, preloads...)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch NPCs"})
 		return
 	}
 
-	c.JSON(http.StatusOK, npcs)
+	c.JSON(http.StatusOK, query.Response(npcs, meta))
 }
 
 // GET /worlds/:id/npcs/:npcId
@@ -109,6 +136,42 @@ func (h *NPCHandler) GetNPC(c *gin.Context) {
 	c.JSON(http.StatusOK, npc)
 }
 
+// GET /worlds/:id/npcs/:npcId/history - the events the NPC took part in,
+// in chronological order, with their role in each
+func (h *NPCHandler) GetNPCHistory(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	npcID, err := strconv.Atoi(c.Param("npcId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid NPC ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	var npc models.NPC
+	if err := h.DB.Where("world_id = ? AND id = ?", worldID, npcID).First(&npc).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "NPC not found"})
+		return
+	}
+
+	history, err := eventHistory(h.DB, "timeline_event_npcs", "npc_id", npc.ID, "timeline_events.*, timeline_event_npcs.role")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch NPC history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
 // POST /worlds/:id/npcs
 func (h *NPCHandler) CreateNPC(c *gin.Context) {
 	worldID, err := strconv.Atoi(c.Param("id"))
@@ -251,8 +314,24 @@ func (h *NPCHandler) DeleteNPC(c *gin.Context) {
 		return
 	}
 
-	// Delete NPC (this will cascade to relationships due to foreign keys)
-	if err := h.DB.Where("world_id = ? AND id = ?", worldID, npcID).Delete(&models.NPC{}).Error; err != nil {
+	// Delete NPC (this will cascade to relationships due to foreign keys).
+	// The events they took part in stay, without them.
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Exec("DELETE FROM timeline_event_npcs WHERE npc_id IN (SELECT id FROM npcs WHERE world_id = ? AND id = ?)", worldID, npcID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete NPC"})
+		return
+	}
+	if err := tx.Where("world_id = ? AND id = ?", worldID, npcID).Delete(&models.NPC{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete NPC"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete NPC"})
 		return
 	}
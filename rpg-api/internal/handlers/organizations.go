@@ -102,6 +102,42 @@ func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
 	c.JSON(http.StatusOK, org)
 }
 
+// GET /worlds/:id/organizations/:orgId/history - the events the
+// organization took part in, in chronological order
+func (h *OrganizationHandler) GetOrganizationHistory(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	orgID, err := strconv.Atoi(c.Param("orgId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	var org models.Organization
+	if err := h.DB.Where("world_id = ? AND id = ?", worldID, orgID).First(&org).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	history, err := eventHistory(h.DB, "timeline_event_organizations", "organization_id", org.ID, "timeline_events.*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organization history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
 // POST /worlds/:id/organizations
 func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
 	worldID, err := strconv.Atoi(c.Param("id"))
@@ -238,8 +274,24 @@ func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
 		return
 	}
 
-	// Delete organization (this will cascade to ranks and memberships due to foreign keys)
-	if err := h.DB.Where("world_id = ? AND id = ?", worldID, orgID).Delete(&models.Organization{}).Error; err != nil {
+	// Delete organization (this will cascade to ranks and memberships due to foreign keys).
+	// The events it took part in stay, without it.
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Exec("DELETE FROM timeline_event_organizations WHERE organization_id IN (SELECT id FROM organizations WHERE world_id = ? AND id = ?)", worldID, orgID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+	if err := tx.Where("world_id = ? AND id = ?", worldID, orgID).Delete(&models.Organization{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
 		return
 	}
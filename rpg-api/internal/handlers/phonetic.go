@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/naetharu/rpg-api/internal/middleware"
 	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/query"
 	"gorm.io/gorm"
 )
 
@@ -20,24 +21,41 @@ func NewPhoneticHandler(db *gorm.DB) *PhoneticHandler {
 	return &PhoneticHandler{DB: db}
 }
 
+// Sorting and filters for GET /phonetics
+var phoneticList = query.List{
+	Sorts:        map[string]string{"name": "name", "created_at": "created_at"},
+	DefaultSort:  "name",
+	DefaultOrder: "asc",
+	Filters: map[string]query.Filter{
+		"is_official": {Column: "is_official", Kind: query.Bool},
+	},
+}
+
 // GET /phonetics
 func (h *PhoneticHandler) GetTables(c *gin.Context) {
+	params, err := phoneticList.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var tables []models.PhoneticTable
-	query := h.DB.Preload("User").Preload("Syllables")
+	db := h.DB
 
 	user, isAuthenticated := middleware.GetCurrentUser(c)
 	if isAuthenticated {
-		query = query.Where("user_id = ? OR is_official = ?", user.ID, true)
+		db = db.Where("user_id = ? OR is_official = ?", user.ID, true)
 	} else {
-		query = query.Where("is_official = ?", true)
+		db = db.Where("is_official = ?", true)
 	}
 
-	if err := query.Find(&tables).Error; err != nil {
+	meta, err := params.Find(db, &tables, "User", "Syllables")
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tables"})
 		return
 	}
 
-	c.JSON(http.StatusOK, tables)
+	c.JSON(http.StatusOK, query.Response(tables, meta))
 }
 
 // GET /phonetics/:id
@@ -0,0 +1,562 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/realtime"
+	"gorm.io/gorm"
+)
+
+type PlaySessionHandler struct {
+	DB     *gorm.DB
+	PubSub realtime.PubSub
+}
+
+type OutcomeStat struct {
+	OutcomeID  uint    `json:"outcome_id"`
+	Title      string  `json:"title"`
+	Count      int64   `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+type SessionStats struct {
+	AdventureID       uint          `json:"adventure_id"`
+	TotalSessions     int64         `json:"total_sessions"`
+	CompletedSessions int64         `json:"completed_sessions"`
+	NoOutcome         int64         `json:"no_outcome"` // Ended without recording an outcome
+	Outcomes          []OutcomeStat `json:"outcomes"`
+}
+
+func NewPlaySessionHandler(db *gorm.DB, pubsub realtime.PubSub) *PlaySessionHandler {
+	return &PlaySessionHandler{DB: db, PubSub: pubsub}
+}
+
+// POST /adventures/:id/sessions - start running an adventure
+func (h *PlaySessionHandler) StartSession(c *gin.Context) {
+	adventureID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid adventure ID"})
+		return
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	// GMs can run their own adventures or official ones
+	var adventure models.Adventure
+	if err := h.DB.Where("(user_id = ? OR user_id IS NULL) AND id = ?", user.ID, adventureID).First(&adventure).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adventure not found or access denied"})
+		return
+	}
+
+	var request struct {
+		Name    string `json:"name"`
+		Players []struct {
+			UserID        *uint  `json:"user_id"`
+			Name          string `json:"name" binding:"required"`
+			CharacterName string `json:"character_name"`
+		} `json:"players"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sceneIDs, err := h.orderedSceneIDs(adventure.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scenes"})
+		return
+	}
+
+	joinCode, err := h.uniqueJoinCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate join code"})
+		return
+	}
+
+	now := time.Now()
+	session := models.PlaySession{
+		AdventureID: adventure.ID,
+		GMID:        user.ID,
+		Name:        request.Name,
+		JoinCode:    joinCode,
+		Status:      models.SessionStatusActive,
+		StartedAt:   now,
+	}
+	if session.Name == "" {
+		session.Name = adventure.Title
+	}
+	if len(sceneIDs) > 0 {
+		session.CurrentSceneID = &sceneIDs[0]
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	if err := tx.Create(&session).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
+
+	for _, p := range request.Players {
+		player := models.PlaySessionPlayer{
+			SessionID:     session.ID,
+			UserID:        p.UserID,
+			Name:          p.Name,
+			CharacterName: p.CharacterName,
+			JoinedAt:      now,
+		}
+		if err := tx.Create(&player).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add players"})
+			return
+		}
+	}
+
+	events := []models.PlaySessionEvent{{SessionID: session.ID, Type: models.SessionEventStarted}}
+	if session.CurrentSceneID != nil {
+		events = append(events, models.PlaySessionEvent{SessionID: session.ID, Type: models.SessionEventScene, SceneID: session.CurrentSceneID})
+	}
+	if err := tx.Create(&events).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write session log"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	h.preloadSession(&session)
+	c.JSON(http.StatusCreated, session)
+}
+
+// GET /adventures/:id/sessions - play history the current user took part in
+func (h *PlaySessionHandler) GetAdventureSessions(c *gin.Context) {
+	adventureID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid adventure ID"})
+		return
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var sessions []models.PlaySession
+	if err := h.DB.Where("adventure_id = ?", adventureID).
+		Where("gm_id = ? OR id IN (SELECT session_id FROM play_session_players WHERE user_id = ?)", user.ID, user.ID).
+		Preload("GM").
+		Preload("Players").
+		Preload("Outcome").
+		Order("started_at DESC").
+		Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// GET /adventures/:id/sessions/stats - how often each epilogue outcome is reached
+func (h *PlaySessionHandler) GetAdventureSessionStats(c *gin.Context) {
+	adventureID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid adventure ID"})
+		return
+	}
+
+	// Stats are visible to anyone who can see the adventure
+	user, isAuthenticated := middleware.GetCurrentUser(c)
+	query := h.DB.Model(&models.Adventure{}).Where("id = ?", adventureID)
+	if isAuthenticated {
+		query = query.Where("user_id = ? OR user_id IS NULL", user.ID)
+	} else {
+		query = query.Where("user_id IS NULL")
+	}
+	var count int64
+	query.Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adventure not found or access denied"})
+		return
+	}
+
+	stats := SessionStats{
+		AdventureID: uint(adventureID),
+		Outcomes:    []OutcomeStat{},
+	}
+	h.DB.Model(&models.PlaySession{}).Where("adventure_id = ?", adventureID).Count(&stats.TotalSessions)
+	h.DB.Model(&models.PlaySession{}).Where("adventure_id = ? AND status = ?", adventureID, models.SessionStatusEnded).Count(&stats.CompletedSessions)
+	h.DB.Model(&models.PlaySession{}).Where("adventure_id = ? AND status = ? AND outcome_id IS NULL", adventureID, models.SessionStatusEnded).Count(&stats.NoOutcome)
+
+	if err := h.DB.Table("play_sessions").
+		Select("epilogue_outcomes.id AS outcome_id, epilogue_outcomes.title AS title, COUNT(*) AS count").
+		Joins("JOIN epilogue_outcomes ON epilogue_outcomes.id = play_sessions.outcome_id").
+		Where("play_sessions.adventure_id = ? AND play_sessions.status = ?", adventureID, models.SessionStatusEnded).
+		Group("epilogue_outcomes.id, epilogue_outcomes.title").
+		Order("count DESC").
+		Scan(&stats.Outcomes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch outcome statistics"})
+		return
+	}
+
+	for i := range stats.Outcomes {
+		if stats.CompletedSessions > 0 {
+			stats.Outcomes[i].Percentage = float64(stats.Outcomes[i].Count) / float64(stats.CompletedSessions) * 100
+		}
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GET /sessions/:id
+func (h *PlaySessionHandler) GetSession(c *gin.Context) {
+	session, ok := h.loadSession(c, false)
+	if !ok {
+		return
+	}
+
+	h.preloadSession(session)
+	c.JSON(http.StatusOK, session)
+}
+
+// POST /sessions/:id/advance - move to the next scene, or jump to a given scene
+func (h *PlaySessionHandler) AdvanceScene(c *gin.Context) {
+	session, ok := h.loadSession(c, true)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.SessionStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session has ended"})
+		return
+	}
+
+	var request struct {
+		SceneID *uint `json:"scene_id"`
+	}
+	// Body is optional - no scene ID means "next scene"
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	sceneIDs, err := h.orderedSceneIDs(session.AdventureID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scenes"})
+		return
+	}
+
+	var nextSceneID uint
+	if request.SceneID != nil {
+		found := false
+		for _, id := range sceneIDs {
+			if id == *request.SceneID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scene does not belong to this adventure"})
+			return
+		}
+		nextSceneID = *request.SceneID
+	} else {
+		index := -1
+		if session.CurrentSceneID != nil {
+			for i, id := range sceneIDs {
+				if id == *session.CurrentSceneID {
+					index = i
+					break
+				}
+			}
+		}
+		if index+1 >= len(sceneIDs) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Already at the last scene"})
+			return
+		}
+		nextSceneID = sceneIDs[index+1]
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	if err := tx.Model(session).Update("current_scene_id", nextSceneID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to advance scene"})
+		return
+	}
+
+	event := models.PlaySessionEvent{SessionID: session.ID, Type: models.SessionEventScene, SceneID: &nextSceneID}
+	if err := tx.Create(&event).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write session log"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	h.preloadSession(session)
+	if session.CurrentScene != nil {
+		h.PubSub.Publish(session.ID, realtime.Message{Type: realtime.MessageScene, Payload: newScenePayload(session.CurrentScene)})
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// POST /sessions/:id/notes - GM notes taken during play
+func (h *PlaySessionHandler) AddNote(c *gin.Context) {
+	session, ok := h.loadSession(c, true)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Content string `json:"content" binding:"required"`
+		SceneID *uint  `json:"scene_id"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Notes attach to the current scene unless told otherwise
+	sceneID := request.SceneID
+	if sceneID == nil {
+		sceneID = session.CurrentSceneID
+	} else {
+		sceneIDs, err := h.orderedSceneIDs(session.AdventureID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scenes"})
+			return
+		}
+		if !slices.Contains(sceneIDs, *sceneID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scene does not belong to this adventure"})
+			return
+		}
+	}
+
+	event := models.PlaySessionEvent{
+		SessionID: session.ID,
+		Type:      models.SessionEventNote,
+		SceneID:   sceneID,
+		Content:   request.Content,
+	}
+	if err := h.DB.Create(&event).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add note"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
+
+// POST /sessions/:id/end - finish the session, optionally recording the outcome reached
+func (h *PlaySessionHandler) EndSession(c *gin.Context) {
+	session, ok := h.loadSession(c, true)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.SessionStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session has already ended"})
+		return
+	}
+
+	var request struct {
+		OutcomeID *uint  `json:"outcome_id"`
+		Notes     string `json:"notes"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// The outcome must come from this adventure's epilogue
+	if request.OutcomeID != nil {
+		var count int64
+		h.DB.Model(&models.EpilogueOutcome{}).
+			Joins("JOIN epilogues ON epilogues.id = epilogue_outcomes.epilogue_id").
+			Where("epilogue_outcomes.id = ? AND epilogues.adventure_id = ?", *request.OutcomeID, session.AdventureID).
+			Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Outcome does not belong to this adventure"})
+			return
+		}
+	}
+
+	now := time.Now()
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	if err := tx.Model(session).Updates(map[string]interface{}{
+		"status":     models.SessionStatusEnded,
+		"outcome_id": request.OutcomeID,
+		"ended_at":   now,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end session"})
+		return
+	}
+
+	event := models.PlaySessionEvent{
+		SessionID: session.ID,
+		Type:      models.SessionEventEnded,
+		OutcomeID: request.OutcomeID,
+		Content:   request.Notes,
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write session log"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	h.preloadSession(session)
+	h.PubSub.Publish(session.ID, realtime.Message{Type: realtime.MessageEnded, Payload: gin.H{"outcome": session.Outcome}})
+	c.JSON(http.StatusOK, session)
+}
+
+// GET /sessions/:id/log
+func (h *PlaySessionHandler) GetSessionLog(c *gin.Context) {
+	session, ok := h.loadSession(c, false)
+	if !ok {
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+
+	query := h.DB.Where("session_id = ?", session.ID)
+	// GM notes stay private to the GM
+	if session.GMID != user.ID {
+		query = query.Where("type != ?", models.SessionEventNote)
+	}
+
+	var events []models.PlaySessionEvent
+	if err := query.Preload("Scene", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "episode_id", "title", "order")
+	}).Order("created_at ASC, id ASC").Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch session log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session": session,
+		"events":  events,
+	})
+}
+
+// HELPER METHODS
+
+// Loads the session from the :id param, writing the error response when the
+// user can't see it. Players can read a session; only the GM can change it.
+func (h *PlaySessionHandler) loadSession(c *gin.Context, gmOnly bool) (*models.PlaySession, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return nil, false
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	}
+
+	var session models.PlaySession
+	if err := h.DB.First(&session, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return nil, false
+	}
+
+	if session.GMID == user.ID {
+		return &session, true
+	}
+
+	if gmOnly {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the GM can do that"})
+		return nil, false
+	}
+
+	var count int64
+	h.DB.Model(&models.PlaySessionPlayer{}).Where("session_id = ? AND user_id = ?", session.ID, user.ID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return nil, false
+	}
+
+	return &session, true
+}
+
+func (h *PlaySessionHandler) preloadSession(session *models.PlaySession) {
+	h.DB.Preload("GM").
+		Preload("Players").
+		Preload("CurrentScene", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "episode_id", "title", "order", "image_url", "prose")
+		}).
+		Preload("Outcome").
+		First(session, session.ID)
+}
+
+// Join codes only need to be unique among sessions that are still running
+func (h *PlaySessionHandler) uniqueJoinCode() (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := models.GenerateJoinCode()
+		if err != nil {
+			return "", err
+		}
+
+		var count int64
+		h.DB.Model(&models.PlaySession{}).Where("join_code = ? AND status = ?", code, models.SessionStatusActive).Count(&count)
+		if count == 0 {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("could not find an unused join code")
+}
+
+// Scene IDs in play order: episodes by order, then scenes by order
+func (h *PlaySessionHandler) orderedSceneIDs(adventureID uint) ([]uint, error) {
+	var sceneIDs []uint
+	err := h.DB.Model(&models.Scene{}).
+		Joins("JOIN episodes ON scenes.episode_id = episodes.id").
+		Where("episodes.adventure_id = ?", adventureID).
+		Order("episodes.\"order\" ASC, scenes.\"order\" ASC, scenes.id ASC").
+		Pluck("scenes.id", &sceneIDs).Error
+	return sceneIDs, err
+}
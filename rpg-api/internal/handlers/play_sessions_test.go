@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/testdb"
+)
+
+func TestAddNoteChecksScene(t *testing.T) {
+	db := testdb.Open(t, &models.User{}, &models.Adventure{}, &models.Episode{}, &models.Scene{},
+		&models.PlaySession{}, &models.PlaySessionPlayer{}, &models.PlaySessionEvent{})
+	gm := models.User{Email: "gm@example.com", Name: "GM", Provider: "email"}
+	db.Create(&gm)
+
+	// One scene in the session's adventure and one in another
+	var scenes [2]models.Scene
+	for i := range scenes {
+		adventure := models.Adventure{Title: fmt.Sprintf("Adventure %d", i+1), UserID: &gm.ID}
+		db.Create(&adventure)
+		episode := models.Episode{AdventureID: adventure.ID, Order: 1}
+		db.Create(&episode)
+		scenes[i] = models.Scene{EpisodeID: episode.ID, Order: 1, Title: "Gate"}
+		db.Create(&scenes[i])
+	}
+	var episode models.Episode
+	db.First(&episode, scenes[0].EpisodeID)
+	session := models.PlaySession{AdventureID: episode.AdventureID, GMID: gm.ID, Status: "active"}
+	db.Create(&session)
+
+	h := &PlaySessionHandler{DB: db}
+	for _, test := range []struct {
+		sceneID uint
+		status  int
+	}{
+		{scenes[0].ID, http.StatusCreated},
+		{scenes[1].ID, http.StatusBadRequest},
+		{9999, http.StatusBadRequest},
+	} {
+		body := fmt.Sprintf(`{"content":"The guard lies","scene_id":%d}`, test.sceneID)
+		w := serveID(h.AddNote, &gm, session.ID, http.MethodPost, "/sessions/1/notes", "application/json", bytes.NewBufferString(body))
+		if w.Code != test.status {
+			t.Errorf("Note on scene %d: got %d, want %d: %s", test.sceneID, w.Code, test.status, w.Body.String())
+		}
+	}
+
+	var notes int64
+	db.Model(&models.PlaySessionEvent{}).Count(&notes)
+	if notes != 1 {
+		t.Errorf("%d notes saved, want 1", notes)
+	}
+}
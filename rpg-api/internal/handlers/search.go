@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/search"
+	"gorm.io/gorm"
+)
+
+type SearchHandler struct {
+	DB *gorm.DB
+}
+
+func NewSearchHandler(db *gorm.DB) *SearchHandler {
+	return &SearchHandler{DB: db}
+}
+
+// GET /search?q=dragon&types=adventure,npc&limit=20 - ranked matches across
+// adventures, scenes, title pages, worlds, timeline events, assets and NPCs.
+// q accepts web search syntax: "quoted phrases", -excluded and or.
+func (h *SearchHandler) Search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	opts := search.Options{Limit: search.DefaultLimit}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		opts.Limit = limit
+	}
+
+	if value := c.Query("types"); value != "" {
+		known := map[string]bool{}
+		for _, t := range search.Types() {
+			known[t] = true
+		}
+		for _, t := range strings.Split(value, ",") {
+			t = strings.TrimSpace(t)
+			if !known[t] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown search type: " + t, "types": search.Types()})
+				return
+			}
+			opts.Types = append(opts.Types, t)
+		}
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+
+	results, err := search.Search(h.DB, q, user, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   q,
+		"results": results,
+	})
+}
@@ -0,0 +1,668 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/query"
+	"gorm.io/gorm"
+)
+
+var errUnknownTimelineEvent = errors.New("timeline events must belong to the story's world")
+
+type StoryHandler struct {
+	DB *gorm.DB
+}
+
+func NewStoryHandler(db *gorm.DB) *StoryHandler {
+	return &StoryHandler{DB: db}
+}
+
+// Sorting and filters for GET /worlds/:id/stories
+var storyList = query.List{
+	Sorts:        map[string]string{"title": "title", "created_at": "created_at", "published_at": "published_at", "word_count": "word_count"},
+	DefaultSort:  "created_at",
+	DefaultOrder: "desc",
+	Filters: map[string]query.Filter{
+		"category": {Column: "category", Kind: query.Equal},
+		"status":   {Column: "status", Kind: query.Equal},
+		"title":    {Column: "title", Kind: query.Like},
+	},
+}
+
+// GET /worlds/:id/stories - stories without their chapters
+func (h *StoryHandler) GetStories(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	params, err := storyList.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var stories []models.Story
+	meta, err := params.Find(models.VisibleStories(h.DB.Where("world_id = ?", worldID), user), &stories, "User")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, query.Response(stories, meta))
+}
+
+// GET /worlds/:id/stories/:storyId - a story with its chapters and timeline events
+func (h *StoryHandler) GetStory(c *gin.Context) {
+	story, ok := h.visibleStory(c)
+	if !ok {
+		return
+	}
+
+	if err := h.loadStory(story); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch story"})
+		return
+	}
+
+	c.JSON(http.StatusOK, story)
+}
+
+// POST /worlds/:id/stories
+func (h *StoryHandler) CreateStory(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var world models.World
+	if err := h.DB.First(&world, worldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+	if !canEditWorld(&world, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var story models.Story
+	if err := c.ShouldBindJSON(&story); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	story.ID = 0
+	story.WorldID = world.ID
+	story.UserID = &user.ID
+	story.Title = strings.TrimSpace(story.Title)
+	story.WordCount = 0
+	story.ReadingMinutes = 0
+	story.Chapters = nil
+	story.TimelineEvents = nil
+	if story.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+	if story.Status == "" {
+		story.Status = models.StoryDraft
+	}
+	if !validStoryStatus(story.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be draft or published"})
+		return
+	}
+	story.PublishedAt = nil
+	if story.Status == models.StoryPublished {
+		now := time.Now()
+		story.PublishedAt = &now
+	}
+
+	// Ensure user can't set official status
+	if !user.IsAdmin {
+		story.IsOfficial = false
+		story.Reviewed = false
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Create(&story).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create story"})
+		return
+	}
+	if err := setStoryEvents(tx, &story, story.TimelineEventIDs); err != nil {
+		tx.Rollback()
+		if errors.Is(err, errUnknownTimelineEvent) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link timeline events"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create story"})
+		return
+	}
+
+	if err := h.loadStory(&story); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch story"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, story)
+}
+
+// PATCH /worlds/:id/stories/:storyId
+func (h *StoryHandler) UpdateStory(c *gin.Context) {
+	story, user, ok := h.editableStory(c)
+	if !ok {
+		return
+	}
+
+	var updates struct {
+		Title            *string `json:"title"`
+		Category         *string `json:"category"`
+		Excerpt          *string `json:"excerpt"`
+		CoverImageURL    *string `json:"cover_image_url"`
+		CoverImageID     *string `json:"cover_image_id"`
+		Status           *string `json:"status"`
+		IsOfficial       *bool   `json:"is_official"`
+		Reviewed         *bool   `json:"reviewed"`
+		TimelineEventIDs *[]uint `json:"timeline_event_ids"`
+	}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if updates.Title != nil {
+		title := strings.TrimSpace(*updates.Title)
+		if title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+			return
+		}
+		story.Title = title
+	}
+	if updates.Category != nil {
+		story.Category = *updates.Category
+	}
+	if updates.Excerpt != nil {
+		story.Excerpt = *updates.Excerpt
+	}
+	if updates.CoverImageURL != nil {
+		story.CoverImageURL = *updates.CoverImageURL
+	}
+	if updates.CoverImageID != nil {
+		story.CoverImageID = *updates.CoverImageID
+	}
+	if updates.Status != nil {
+		if !validStoryStatus(*updates.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be draft or published"})
+			return
+		}
+		// Publishing again after going back to draft counts as a new publish date
+		if *updates.Status == models.StoryPublished && story.Status != models.StoryPublished {
+			now := time.Now()
+			story.PublishedAt = &now
+		} else if *updates.Status == models.StoryDraft {
+			story.PublishedAt = nil
+		}
+		story.Status = *updates.Status
+	}
+
+	// Prevent non-admin users from setting official status
+	if user.IsAdmin {
+		if updates.IsOfficial != nil {
+			story.IsOfficial = *updates.IsOfficial
+		}
+		if updates.Reviewed != nil {
+			story.Reviewed = *updates.Reviewed
+		}
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Omit("User", "Chapters", "TimelineEvents").Save(story).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update story"})
+		return
+	}
+	if updates.TimelineEventIDs != nil {
+		if err := setStoryEvents(tx, story, *updates.TimelineEventIDs); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errUnknownTimelineEvent) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link timeline events"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update story"})
+		return
+	}
+
+	if err := h.loadStory(story); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch story"})
+		return
+	}
+
+	c.JSON(http.StatusOK, story)
+}
+
+// DELETE /worlds/:id/stories/:storyId - deletes the story and its chapters
+func (h *StoryHandler) DeleteStory(c *gin.Context) {
+	story, _, ok := h.editableStory(c)
+	if !ok {
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Exec("DELETE FROM story_timeline_events WHERE story_id = ?", story.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete story"})
+		return
+	}
+	if err := tx.Where("story_id = ?", story.ID).Delete(&models.StoryChapter{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete story"})
+		return
+	}
+	if err := tx.Delete(story).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete story"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete story"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Story deleted successfully"})
+}
+
+// GET /worlds/:id/stories/:storyId/chapters/:chapterId
+func (h *StoryHandler) GetChapter(c *gin.Context) {
+	story, ok := h.visibleStory(c)
+	if !ok {
+		return
+	}
+
+	chapter, ok := h.findChapter(c, story)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, chapter)
+}
+
+// POST /worlds/:id/stories/:storyId/chapters - adds a chapter at the end
+func (h *StoryHandler) CreateChapter(c *gin.Context) {
+	story, _, ok := h.editableStory(c)
+	if !ok {
+		return
+	}
+
+	var chapter models.StoryChapter
+	if err := c.ShouldBindJSON(&chapter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chapter.ID = 0
+	chapter.StoryID = story.ID
+	chapter.Title = strings.TrimSpace(chapter.Title)
+	if chapter.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+	chapter.WordCount = models.CountWords(chapter.Body)
+	chapter.ReadingMinutes = models.ReadingMinutes(chapter.WordCount)
+
+	// Get next order number within this story
+	var maxOrder int
+	h.DB.Model(&models.StoryChapter{}).Where("story_id = ?", story.ID).Select("COALESCE(MAX(sort_order), 0)").Scan(&maxOrder)
+	chapter.SortOrder = maxOrder + 1
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Create(&chapter).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chapter"})
+		return
+	}
+	if err := updateStoryTotals(tx, story.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading time"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chapter"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, chapter)
+}
+
+// PATCH /worlds/:id/stories/:storyId/chapters/:chapterId
+func (h *StoryHandler) UpdateChapter(c *gin.Context) {
+	story, _, ok := h.editableStory(c)
+	if !ok {
+		return
+	}
+
+	chapter, ok := h.findChapter(c, story)
+	if !ok {
+		return
+	}
+
+	var updates struct {
+		Title *string `json:"title"`
+		Body  *string `json:"body"`
+	}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if updates.Title != nil {
+		title := strings.TrimSpace(*updates.Title)
+		if title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+			return
+		}
+		chapter.Title = title
+	}
+	if updates.Body != nil {
+		chapter.Body = *updates.Body
+		chapter.WordCount = models.CountWords(chapter.Body)
+		chapter.ReadingMinutes = models.ReadingMinutes(chapter.WordCount)
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Save(chapter).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chapter"})
+		return
+	}
+	if err := updateStoryTotals(tx, story.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading time"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chapter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, chapter)
+}
+
+// DELETE /worlds/:id/stories/:storyId/chapters/:chapterId
+func (h *StoryHandler) DeleteChapter(c *gin.Context) {
+	story, _, ok := h.editableStory(c)
+	if !ok {
+		return
+	}
+
+	chapter, ok := h.findChapter(c, story)
+	if !ok {
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Delete(chapter).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete chapter"})
+		return
+	}
+	if err := updateStoryTotals(tx, story.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading time"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete chapter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chapter deleted successfully"})
+}
+
+// POST /worlds/:id/stories/:storyId/chapters/reorder - chapter_ids lists every
+// chapter in its new order
+func (h *StoryHandler) ReorderChapters(c *gin.Context) {
+	story, _, ok := h.editableStory(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		ChapterIDs []uint `json:"chapter_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing []uint
+	if err := h.DB.Model(&models.StoryChapter{}).Where("story_id = ?", story.ID).Pluck("id", &existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chapters"})
+		return
+	}
+	if !sameIDs(existing, request.ChapterIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chapter_ids must list every chapter of the story exactly once"})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	for i, chapterID := range request.ChapterIDs {
+		if err := tx.Model(&models.StoryChapter{}).Where("id = ? AND story_id = ?", chapterID, story.ID).Update("sort_order", i+1).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chapter order"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	var chapters []models.StoryChapter
+	if err := h.DB.Omit("body").Where("story_id = ?", story.ID).Order("sort_order ASC").Find(&chapters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated chapters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, chapters)
+}
+
+// Loads the story in the URL if the current user can read it, writing the
+// error response otherwise
+func (h *StoryHandler) visibleStory(c *gin.Context) (*models.Story, bool) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return nil, false
+	}
+
+	storyID, err := strconv.Atoi(c.Param("storyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid story ID"})
+		return nil, false
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return nil, false
+	}
+
+	var story models.Story
+	if err := models.VisibleStories(h.DB.Where("world_id = ? AND id = ?", worldID, storyID), user).First(&story).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Story not found"})
+		return nil, false
+	}
+	return &story, true
+}
+
+// Loads the story in the URL if the current user wrote it or is an admin,
+// writing the error response otherwise
+func (h *StoryHandler) editableStory(c *gin.Context) (*models.Story, *models.User, bool) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return nil, nil, false
+	}
+
+	storyID, err := strconv.Atoi(c.Param("storyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid story ID"})
+		return nil, nil, false
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, nil, false
+	}
+
+	var story models.Story
+	if err := h.DB.Where("world_id = ? AND id = ?", worldID, storyID).First(&story).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Story not found"})
+		return nil, nil, false
+	}
+
+	// Check if user wrote this story or is admin
+	if story.UserID == nil || *story.UserID != user.ID && !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, nil, false
+	}
+	return &story, user, true
+}
+
+func (h *StoryHandler) findChapter(c *gin.Context, story *models.Story) (*models.StoryChapter, bool) {
+	chapterID, err := strconv.Atoi(c.Param("chapterId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chapter ID"})
+		return nil, false
+	}
+
+	var chapter models.StoryChapter
+	if err := h.DB.Where("story_id = ? AND id = ?", story.ID, chapterID).First(&chapter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
+		return nil, false
+	}
+	return &chapter, true
+}
+
+// Fills in the author, chapters in reading order and linked timeline events
+func (h *StoryHandler) loadStory(story *models.Story) error {
+	err := h.DB.Preload("User").
+		Preload("Chapters", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order ASC") }).
+		Preload("TimelineEvents", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order ASC") }).
+		First(story, story.ID).Error
+	if err != nil {
+		return err
+	}
+
+	story.TimelineEventIDs = make([]uint, len(story.TimelineEvents))
+	for i, event := range story.TimelineEvents {
+		story.TimelineEventIDs[i] = event.ID
+	}
+	return nil
+}
+
+// Replaces the timeline events a story covers. Every event must be in the
+// story's world.
+func setStoryEvents(tx *gorm.DB, story *models.Story, eventIDs []uint) error {
+	if len(eventIDs) == 0 {
+		return tx.Model(story).Association("TimelineEvents").Clear()
+	}
+
+	var events []models.TimelineEvent
+	if err := tx.Where("world_id = ? AND id IN ?", story.WorldID, eventIDs).Find(&events).Error; err != nil {
+		return err
+	}
+	if len(events) != len(uniqueIDs(eventIDs)) {
+		return errUnknownTimelineEvent
+	}
+	return tx.Model(story).Omit("TimelineEvents.*").Association("TimelineEvents").Replace(events)
+}
+
+// Recomputes a story's word count and reading time from its chapters
+func updateStoryTotals(tx *gorm.DB, storyID uint) error {
+	var words int
+	if err := tx.Model(&models.StoryChapter{}).Where("story_id = ?", storyID).
+		Select("COALESCE(SUM(word_count), 0)").Scan(&words).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Story{}).Where("id = ?", storyID).
+		Updates(map[string]interface{}{"word_count": words, "reading_minutes": models.ReadingMinutes(words)}).Error
+}
+
+func validStoryStatus(status string) bool {
+	return status == models.StoryDraft || status == models.StoryPublished
+}
+
+// Whether two ID lists hold the same IDs, each exactly once
+func sameIDs(existing, requested []uint) bool {
+	if len(existing) != len(requested) || len(uniqueIDs(requested)) != len(requested) {
+		return false
+	}
+	want := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		want[id] = true
+	}
+	for _, id := range requested {
+		if !want[id] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagHandler struct {
+	DB *gorm.DB
+}
+
+func NewTagHandler(db *gorm.DB) *TagHandler {
+	return &TagHandler{DB: db}
+}
+
+// GET /tags - the current user's tags with how many assets carry each
+func (h *TagHandler) GetTags(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var tags []models.Tag
+	if err := h.DB.Where("user_id = ?", user.ID).Order("name ASC").Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	var counts []struct {
+		TagID uint
+		Count int64
+	}
+	h.DB.Raw(`SELECT asset_tags.tag_id, COUNT(*) AS count FROM asset_tags
+		JOIN tags ON tags.id = asset_tags.tag_id
+		JOIN assets ON assets.id = asset_tags.asset_id AND assets.deleted_at IS NULL
+		WHERE tags.user_id = ? GROUP BY asset_tags.tag_id`, user.ID).Scan(&counts)
+
+	countByTag := make(map[uint]int64)
+	for _, row := range counts {
+		countByTag[row.TagID] = row.Count
+	}
+	for i := range tags {
+		tags[i].AssetCount = countByTag[tags[i].ID]
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// POST /tags
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		Name  string `json:"name" binding:"required"`
+		Color string `json:"color"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag name is required"})
+		return
+	}
+
+	var count int64
+	h.DB.Model(&models.Tag{}).Where("user_id = ? AND name = ?", user.ID, name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a tag with that name"})
+		return
+	}
+
+	tag := models.Tag{UserID: user.ID, Name: name, Color: request.Color}
+	if err := h.DB.Create(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// PATCH /tags/:id
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	tag, ok := h.loadTag(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Name  *string `json:"name"`
+		Color *string `json:"color"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Name != nil {
+		name := strings.TrimSpace(*request.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tag name is required"})
+			return
+		}
+		var count int64
+		h.DB.Model(&models.Tag{}).Where("user_id = ? AND name = ? AND id <> ?", tag.UserID, name, tag.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have a tag with that name"})
+			return
+		}
+		tag.Name = name
+	}
+	if request.Color != nil {
+		tag.Color = *request.Color
+	}
+
+	if err := h.DB.Save(tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DELETE /tags/:id - removes the tag from every asset
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	tag, ok := h.loadTag(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	if err := tx.Where("tag_id = ?", tag.ID).Delete(&models.AssetTag{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to untag assets"})
+		return
+	}
+
+	if err := tx.Delete(tag).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
+
+// POST /assets/:id/tags - tag an asset with existing tags by ID or by name,
+// creating any named tags that don't exist yet
+func (h *TagHandler) TagAsset(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	asset, ok := h.loadVisibleAsset(c, user)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		TagIDs []uint   `json:"tag_ids"`
+		Names  []string `json:"names"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(request.TagIDs) == 0 && len(request.Names) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag_ids or names is required"})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	var tags []models.Tag
+	if len(request.TagIDs) > 0 {
+		if err := tx.Where("user_id = ? AND id IN ?", user.ID, request.TagIDs).Find(&tags).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+			return
+		}
+		if len(tags) != len(uniqueIDs(request.TagIDs)) {
+			tx.Rollback()
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+			return
+		}
+	}
+
+	for _, name := range request.Names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		tag := models.Tag{UserID: user.ID, Name: name}
+		if err := tx.Where("user_id = ? AND name = ?", user.ID, name).FirstOrCreate(&tag).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
+			return
+		}
+		tags = append(tags, tag)
+	}
+
+	for _, tag := range tags {
+		link := models.AssetTag{TagID: tag.ID, AssetID: asset.ID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&link).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to tag asset"})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to tag asset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"asset_id": asset.ID, "tags": h.assetTags(user.ID, asset.ID)})
+}
+
+// DELETE /assets/:id/tags/:tagId
+func (h *TagHandler) UntagAsset(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	asset, ok := h.loadVisibleAsset(c, user)
+	if !ok {
+		return
+	}
+
+	tag, ok := h.loadTag(c, c.Param("tagId"))
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Where("tag_id = ? AND asset_id = ?", tag.ID, asset.ID).Delete(&models.AssetTag{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to untag asset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"asset_id": asset.ID, "tags": h.assetTags(user.ID, asset.ID)})
+}
+
+// GET /assets/:id/tags - the current user's tags on an asset
+func (h *TagHandler) GetAssetTags(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	asset, ok := h.loadVisibleAsset(c, user)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, h.assetTags(user.ID, asset.ID))
+}
+
+// Loads one of the current user's tags
+func (h *TagHandler) loadTag(c *gin.Context, param string) (*models.Tag, bool) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return nil, false
+	}
+
+	var tag models.Tag
+	if err := h.DB.Where("id = ? AND user_id = ?", id, user.ID).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return nil, false
+	}
+
+	return &tag, true
+}
+
+// Users can tag official assets and their own
+func (h *TagHandler) loadVisibleAsset(c *gin.Context, user *models.User) (*models.Asset, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid asset ID"})
+		return nil, false
+	}
+
+	var asset models.Asset
+	if err := h.DB.Where("(is_official = ? OR user_id = ?) AND id = ?", true, user.ID, id).First(&asset).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return nil, false
+	}
+
+	return &asset, true
+}
+
+func (h *TagHandler) assetTags(userID uint, assetID uint) []models.Tag {
+	tags := []models.Tag{}
+	h.DB.Joins("JOIN asset_tags ON asset_tags.tag_id = tags.id").
+		Where("tags.user_id = ? AND asset_tags.asset_id = ?", userID, assetID).
+		Order("tags.name ASC").
+		Find(&tags)
+	return tags
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool)
+	var unique []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
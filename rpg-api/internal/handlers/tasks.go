@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/naetharu/rpg-api/internal/middleware"
 	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/query"
 	"gorm.io/gorm"
 )
 
@@ -18,6 +19,16 @@ func NewTaskHandler(db *gorm.DB) *TaskHandler {
 	return &TaskHandler{DB: db}
 }
 
+// Sorting and filters for GET /tasks
+var taskList = query.List{
+	Sorts:        map[string]string{"name": "name", "status": "status", "created_at": "created_at", "updated_at": "updated_at"},
+	DefaultSort:  "created_at",
+	DefaultOrder: "desc",
+	Filters: map[string]query.Filter{
+		"status": {Column: "status", Kind: query.Equal},
+	},
+}
+
 // GET /tasks
 func (h *TaskHandler) GetTasks(c *gin.Context) {
 	user, exists := middleware.GetCurrentUser(c)
@@ -26,13 +37,20 @@ func (h *TaskHandler) GetTasks(c *gin.Context) {
 		return
 	}
 
+	params, err := taskList.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var tasks []models.Task
-	if err := h.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&tasks).Error; err != nil {
+	meta, err := params.Find(h.DB.Where("user_id = ?", user.ID), &tasks)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
 		return
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, query.Response(tasks, meta))
 }
 
 // POST /tasks
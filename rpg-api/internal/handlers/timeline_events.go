@@ -1,24 +1,71 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/calendar"
 	"github.com/naetharu/rpg-api/internal/middleware"
 	"github.com/naetharu/rpg-api/internal/models"
 	"gorm.io/gorm"
 )
 
+var errUnknownParticipant = errors.New("NPCs, organizations and locations must belong to the event's world")
+
+// HistoryEntry is an event on an NPC's or organization's timeline, with the
+// part an NPC played in it
+type HistoryEntry struct {
+	models.TimelineEvent
+	Role string `json:"role,omitempty"`
+}
+
+// Batch operations
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// Keeps a batch to what one transaction handles comfortably
+const MaxBatchOperations = 500
+
+// TimelineBatchOperation creates Event, or updates or deletes the event with
+// ID. An update's Event holds the changes, as in a PATCH.
+type TimelineBatchOperation struct {
+	Op    string               `json:"op"`
+	ID    uint                 `json:"id"`
+	Event models.TimelineEvent `json:"event"`
+}
+
+type TimelineBatchRequest struct {
+	Operations []TimelineBatchOperation `json:"operations" binding:"required"`
+}
+
+// ItemError reports why one item in a bulk request failed
+type ItemError struct {
+	Index int    `json:"index"`
+	ID    uint   `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
 type TimelineEventHandler struct {
 	DB *gorm.DB
 }
 
+// Undated events keep their manual place after the dated ones
+const eventOrder = "start_day ASC NULLS LAST, sort_order ASC, id ASC"
+
 func NewTimelineEventHandler(db *gorm.DB) *TimelineEventHandler {
 	return &TimelineEventHandler{DB: db}
 }
 
-// GET /worlds/:id/timeline-events
+// GET /worlds/:id/timeline-events?from=&to= - in chronological order when the
+// world has a calendar. from and to keep events that overlap the range.
 func (h *TimelineEventHandler) GetTimelineEvents(c *gin.Context) {
 	worldID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -44,8 +91,41 @@ func (h *TimelineEventHandler) GetTimelineEvents(c *gin.Context) {
 		return
 	}
 
+	cal, err := loadCalendar(h.DB, world.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load calendar"})
+		return
+	}
+
+	db := h.DB.Where("world_id = ?", worldID)
+	from, to := c.Query("from"), c.Query("to")
+	if from != "" || to != "" {
+		if cal == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This world has no calendar, so events can't be filtered by date"})
+			return
+		}
+		if from != "" {
+			date, err := cal.Parse(from)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			first, _ := cal.Span(date)
+			db = db.Where("end_day >= ?", first)
+		}
+		if to != "" {
+			date, err := cal.Parse(to)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			_, last := cal.Span(date)
+			db = db.Where("start_day <= ?", last)
+		}
+	}
+
 	var events []models.TimelineEvent
-	if err := h.DB.Where("world_id = ?", worldID).Order("sort_order ASC").Find(&events).Error; err != nil {
+	if err := preloadParticipants(db).Order(eventOrder).Find(&events).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline events"})
 		return
 	}
@@ -89,11 +169,37 @@ func (h *TimelineEventHandler) CreateTimelineEvent(c *gin.Context) {
 	event.WorldID = uint(worldID)
 	event.UserID = &user.ID
 
-	if err := h.DB.Create(&event).Error; err != nil {
+	cal, err := loadCalendar(h.DB, world.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load calendar"})
+		return
+	}
+	if err := prepareNewEvent(h.DB, cal, &event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := createEvent(tx, &event); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create timeline event"})
+		return
+	}
+	if err := assignEventEras(tx, event.WorldID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign event to an era"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create timeline event"})
 		return
 	}
 
+	preloadParticipants(h.DB).First(&event, event.ID)
 	c.JSON(http.StatusCreated, event)
 }
 
@@ -124,7 +230,7 @@ func (h *TimelineEventHandler) UpdateTimelineEvent(c *gin.Context) {
 	}
 
 	// Check if user owns this event or is admin
-	if event.UserID == nil || *event.UserID != user.ID && !user.IsAdmin {
+	if !canEditEvent(&event, user) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
@@ -135,11 +241,37 @@ func (h *TimelineEventHandler) UpdateTimelineEvent(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Model(&event).Updates(updates).Error; err != nil {
+	cal, err := loadCalendar(h.DB, event.WorldID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load calendar"})
+		return
+	}
+	if err := prepareEventUpdate(h.DB, cal, event, &updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := updateEvent(tx, &event, updates); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update timeline event"})
+		return
+	}
+	if err := assignEventEras(tx, event.WorldID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign event to an era"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update timeline event"})
 		return
 	}
 
+	preloadParticipants(h.DB).First(&event, event.ID)
 	c.JSON(http.StatusOK, event)
 }
 
@@ -170,15 +302,465 @@ func (h *TimelineEventHandler) DeleteTimelineEvent(c *gin.Context) {
 	}
 
 	// Check if user owns this event or is admin
-	if event.UserID == nil || *event.UserID != user.ID && !user.IsAdmin {
+	if !canEditEvent(&event, user) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
-	if err := h.DB.Delete(&event).Error; err != nil {
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := deleteEvent(tx, &event); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete timeline event"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete timeline event"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Timeline event deleted successfully"})
 }
+
+// POST /worlds/:id/timeline-events/reorder - the listed events trade the
+// sort order positions they hold between them into the order given, so a
+// subset such as the events on one day can be reordered alone
+func (h *TimelineEventHandler) ReorderTimelineEvents(c *gin.Context) {
+	world, _, ok := h.editableWorld(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		EventIDs []uint `json:"event_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var events []models.TimelineEvent
+	if err := h.DB.Where("world_id = ? AND id IN ?", world.ID, request.EventIDs).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline events"})
+		return
+	}
+	sortOrders := make(map[uint]int, len(events))
+	for _, event := range events {
+		sortOrders[event.ID] = event.SortOrder
+	}
+
+	itemErrors := []ItemError{}
+	seen := make(map[uint]bool)
+	for i, id := range request.EventIDs {
+		if _, ok := sortOrders[id]; !ok {
+			itemErrors = append(itemErrors, ItemError{Index: i, ID: id, Error: "Timeline event not found"})
+		} else if seen[id] {
+			itemErrors = append(itemErrors, ItemError{Index: i, ID: id, Error: "Event is listed more than once"})
+		}
+		seen[id] = true
+	}
+	if len(itemErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No events were reordered", "errors": itemErrors})
+		return
+	}
+
+	// Positions in use are handed out in the new order. Events sharing a
+	// position are spread out so the new order sticks.
+	positions := make([]int, 0, len(events))
+	for _, event := range events {
+		positions = append(positions, event.SortOrder)
+	}
+	slices.Sort(positions)
+	for i := 1; i < len(positions); i++ {
+		if positions[i] <= positions[i-1] {
+			positions[i] = positions[i-1] + 1
+		}
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	for i, id := range request.EventIDs {
+		if positions[i] == sortOrders[id] {
+			continue
+		}
+		if err := tx.Model(&models.TimelineEvent{}).Where("id = ?", id).UpdateColumn("sort_order", positions[i]).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event order"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event order"})
+		return
+	}
+
+	var updated []models.TimelineEvent
+	if err := preloadParticipants(h.DB).Where("world_id = ?", world.ID).Order(eventOrder).Find(&updated).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// POST /worlds/:id/timeline-events/batch - creates, updates and deletes
+// events in one transaction. Every operation is checked first; if any fail,
+// nothing changes and each failure is reported by its index.
+func (h *TimelineEventHandler) BatchTimelineEvents(c *gin.Context) {
+	world, user, ok := h.editableWorld(c)
+	if !ok {
+		return
+	}
+
+	var request TimelineBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request.Operations) > MaxBatchOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A batch can have at most %d operations", MaxBatchOperations)})
+		return
+	}
+
+	cal, err := loadCalendar(h.DB, world.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load calendar"})
+		return
+	}
+
+	var ids []uint
+	for _, operation := range request.Operations {
+		if operation.ID != 0 {
+			ids = append(ids, operation.ID)
+		}
+	}
+	existing := make(map[uint]models.TimelineEvent)
+	if len(ids) > 0 {
+		var events []models.TimelineEvent
+		if err := h.DB.Where("world_id = ? AND id IN ?", world.ID, ids).Find(&events).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline events"})
+			return
+		}
+		for _, event := range events {
+			existing[event.ID] = event
+		}
+	}
+
+	// Check everything before changing anything
+	itemErrors := []ItemError{}
+	touched := make(map[uint]bool)
+	for i := range request.Operations {
+		operation := &request.Operations[i]
+		fail := func(message string) {
+			itemErrors = append(itemErrors, ItemError{Index: i, ID: operation.ID, Error: message})
+		}
+
+		if operation.Op == BatchCreate {
+			operation.Event.ID = 0
+			operation.Event.WorldID = world.ID
+			operation.Event.UserID = &user.ID
+			if err := prepareNewEvent(h.DB, cal, &operation.Event); err != nil {
+				fail(err.Error())
+			}
+			continue
+		}
+		if operation.Op != BatchUpdate && operation.Op != BatchDelete {
+			fail(fmt.Sprintf("op must be %s, %s or %s", BatchCreate, BatchUpdate, BatchDelete))
+			continue
+		}
+
+		event, found := existing[operation.ID]
+		switch {
+		case !found:
+			fail("Timeline event not found")
+		case !canEditEvent(&event, user):
+			fail("Access denied")
+		case touched[operation.ID]:
+			fail("Event is changed by an earlier operation in this batch")
+		case operation.Op == BatchUpdate:
+			if err := prepareEventUpdate(h.DB, cal, event, &operation.Event); err != nil {
+				fail(err.Error())
+			}
+		}
+		touched[operation.ID] = true
+	}
+	if len(itemErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No changes were made", "errors": itemErrors})
+		return
+	}
+
+	results := make([]models.TimelineEvent, len(request.Operations))
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	for i := range request.Operations {
+		operation := &request.Operations[i]
+		event := existing[operation.ID]
+		switch operation.Op {
+		case BatchCreate:
+			event = operation.Event
+			err = createEvent(tx, &event)
+		case BatchUpdate:
+			err = updateEvent(tx, &event, operation.Event)
+		case BatchDelete:
+			err = deleteEvent(tx, &event)
+		}
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to %s timeline event at index %d", operation.Op, i)})
+			return
+		}
+		results[i] = event
+	}
+	if err := assignEventEras(tx, world.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign events to eras"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save timeline events"})
+		return
+	}
+
+	// Created and updated events come back as saved, deleted ones as they were
+	for i, operation := range request.Operations {
+		if operation.Op != BatchDelete {
+			preloadParticipants(h.DB).First(&results[i], results[i].ID)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// Loads the world in the URL if the current user can edit it, writing the
+// error response otherwise
+func (h *TimelineEventHandler) editableWorld(c *gin.Context) (*models.World, *models.User, bool) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return nil, nil, false
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, nil, false
+	}
+
+	var world models.World
+	if err := h.DB.First(&world, worldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return nil, nil, false
+	}
+	if !canEditWorld(&world, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, nil, false
+	}
+	return &world, user, true
+}
+
+// Only whoever added an event, or an admin, can change it
+func canEditEvent(event *models.TimelineEvent, user *models.User) bool {
+	return user.IsAdmin || (event.UserID != nil && *event.UserID == user.ID)
+}
+
+// Checks a new event and fills in its day numbers and era. Errors are
+// problems with the event itself.
+func prepareNewEvent(db *gorm.DB, cal *calendar.Calendar, event *models.TimelineEvent) error {
+	if err := dateEvent(cal, event); err != nil {
+		return err
+	}
+	if err := resolveEventEra(db, event); err != nil {
+		return err
+	}
+	return checkParticipants(db, event.WorldID, event.NPCs, event.OrganizationIDs, event.LocationIDs)
+}
+
+// Checks changes to an event as the event will be after them, and fills in
+// the day numbers and era it will have. Errors are problems with the changes.
+func prepareEventUpdate(db *gorm.DB, cal *calendar.Calendar, event models.TimelineEvent, updates *models.TimelineEvent) error {
+	dated := event
+	if updates.StartDate != "" {
+		dated.StartDate = updates.StartDate
+	}
+	if updates.EndDate != nil {
+		dated.EndDate = updates.EndDate
+	}
+	if updates.EraID != nil || updates.Era != "" {
+		dated.EraID, dated.Era = updates.EraID, updates.Era
+		if err := resolveEventEra(db, &dated); err != nil {
+			return err
+		}
+		updates.EraID, updates.Era = dated.EraID, dated.Era
+	}
+	if err := dateEvent(cal, &dated); err != nil {
+		return err
+	}
+	updates.StartDay, updates.EndDay, updates.DurationDays = dated.StartDay, dated.EndDay, dated.DurationDays
+	return checkParticipants(db, event.WorldID, updates.NPCs, updates.OrganizationIDs, updates.LocationIDs)
+}
+
+// Saves a checked new event with who and what took part in it
+func createEvent(tx *gorm.DB, event *models.TimelineEvent) error {
+	npcs := event.NPCs
+	event.NPCs, event.Organizations, event.Locations = nil, nil, nil
+	if err := tx.Create(event).Error; err != nil {
+		return err
+	}
+	return setEventParticipants(tx, event, npcs, event.OrganizationIDs, event.LocationIDs)
+}
+
+// Saves checked changes to an event. Day numbers are written even when they
+// become nil, which Updates would skip.
+func updateEvent(tx *gorm.DB, event *models.TimelineEvent, updates models.TimelineEvent) error {
+	days := map[string]interface{}{
+		"start_day":     updates.StartDay,
+		"end_day":       updates.EndDay,
+		"duration_days": updates.DurationDays,
+	}
+	npcs := updates.NPCs
+	updates.ID, updates.WorldID = 0, 0
+	updates.StartDay, updates.EndDay, updates.DurationDays = nil, nil, nil
+	updates.NPCs, updates.Organizations, updates.Locations = nil, nil, nil
+
+	if err := tx.Model(event).Updates(updates).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(event).UpdateColumns(days).Error; err != nil {
+		return err
+	}
+	return setEventParticipants(tx, event, npcs, updates.OrganizationIDs, updates.LocationIDs)
+}
+
+// Deletes an event. Stories that covered it stop linking to it, and its
+// links to other events and its participants go with it.
+func deleteEvent(tx *gorm.DB, event *models.TimelineEvent) error {
+	for _, table := range []string{"story_timeline_events", "timeline_event_npcs", "timeline_event_organizations", "timeline_event_locations"} {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE timeline_event_id = ?", event.ID).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("from_event_id = ? OR to_event_id = ?", event.ID, event.ID).Delete(&models.TimelineEventLink{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(event).Error
+}
+
+// Loads the NPCs, organizations and locations taking part in events
+func preloadParticipants(db *gorm.DB) *gorm.DB {
+	return db.Preload("NPCs.NPC").
+		Preload("Organizations", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Preload("Locations", func(db *gorm.DB) *gorm.DB { return db.Order("name") })
+}
+
+// Checks the NPCs, organizations and locations given for an event are all in
+// its world. Nil lists aren't being changed.
+func checkParticipants(db *gorm.DB, worldID uint, npcs []models.TimelineEventNPC, organizationIDs, locationIDs []uint) error {
+	npcIDs := make([]uint, len(npcs))
+	for i, npc := range npcs {
+		npcIDs[i] = npc.NPCID
+	}
+	for _, check := range []struct {
+		model interface{}
+		ids   []uint
+	}{
+		{&models.NPC{}, npcIDs},
+		{&models.Organization{}, organizationIDs},
+		{&models.NPCLocation{}, locationIDs},
+	} {
+		ids := uniqueIDs(check.ids)
+		if len(ids) == 0 {
+			continue
+		}
+		var count int64
+		if err := db.Model(check.model).Where("world_id = ? AND id IN ?", worldID, ids).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(ids) {
+			return errUnknownParticipant
+		}
+	}
+	return nil
+}
+
+// Replaces who and what took part in a checked event. A nil list leaves that
+// kind of participant as it is.
+func setEventParticipants(tx *gorm.DB, event *models.TimelineEvent, npcs []models.TimelineEventNPC, organizationIDs, locationIDs []uint) error {
+	if npcs != nil {
+		// A repeated NPC keeps the last role given
+		roles := make(map[uint]string)
+		var npcIDs []uint
+		for _, npc := range npcs {
+			if _, seen := roles[npc.NPCID]; !seen {
+				npcIDs = append(npcIDs, npc.NPCID)
+			}
+			roles[npc.NPCID] = strings.TrimSpace(npc.Role)
+		}
+
+		if err := tx.Where("timeline_event_id = ?", event.ID).Delete(&models.TimelineEventNPC{}).Error; err != nil {
+			return err
+		}
+		rows := make([]models.TimelineEventNPC, len(npcIDs))
+		for i, id := range npcIDs {
+			rows[i] = models.TimelineEventNPC{TimelineEventID: event.ID, NPCID: id, Role: roles[id]}
+			if rows[i].Role == "" {
+				rows[i].Role = models.DefaultEventRole
+			}
+		}
+		if len(rows) > 0 {
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if organizationIDs != nil {
+		var organizations []models.Organization
+		for _, id := range uniqueIDs(organizationIDs) {
+			organizations = append(organizations, models.Organization{ID: id})
+		}
+		if err := replaceAssociation(tx.Model(event).Omit("Organizations.*").Association("Organizations"), organizations); err != nil {
+			return err
+		}
+	}
+
+	if locationIDs != nil {
+		var locations []models.NPCLocation
+		for _, id := range uniqueIDs(locationIDs) {
+			locations = append(locations, models.NPCLocation{ID: id})
+		}
+		if err := replaceAssociation(tx.Model(event).Omit("Locations.*").Association("Locations"), locations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replace needs at least one row, clearing is how to replace with none
+func replaceAssociation[T any](association *gorm.Association, rows []T) error {
+	if len(rows) == 0 {
+		return association.Clear()
+	}
+	return association.Replace(rows)
+}
+
+// Events joined to one NPC or organization through a participant table, in
+// chronological order
+func eventHistory(db *gorm.DB, table, column string, id uint, columns string) ([]HistoryEntry, error) {
+	entries := []HistoryEntry{}
+	err := db.Model(&models.TimelineEvent{}).
+		Select(columns).
+		Joins("JOIN "+table+" ON "+table+".timeline_event_id = timeline_events.id").
+		Where(table+"."+column+" = ?", id).
+		Order(eventOrder).
+		Scan(&entries).Error
+	return entries, err
+}
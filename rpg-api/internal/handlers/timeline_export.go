@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/timeline"
+)
+
+// Largest CSV file, and most rows, accepted by POST /worlds/:id/timeline-events/import
+const (
+	maxTimelineImportBytes = 5 << 20
+	maxTimelineImportRows  = 1000
+)
+
+// Content types of the timeline export formats, by format
+var timelineExportTypes = map[string]string{
+	"json": "application/json; charset=utf-8",
+	"csv":  "text/csv; charset=utf-8",
+	"md":   "text/markdown; charset=utf-8",
+	"ics":  "text/calendar; charset=utf-8",
+}
+
+// GET /worlds/:id/timeline-events/export?format=json|csv|md|ics&importance= -
+// the timeline as a file. md is a chronicle of the world by era, and ics puts
+// dated events on a calendar, counting how many it left out in the
+// X-Skipped-Events header. importance keeps events at least that important.
+func (h *TimelineEventHandler) ExportTimeline(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	contentType, ok := timelineExportTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, csv, md or ics"})
+		return
+	}
+	minimum := 0
+	if importance := c.Query("importance"); importance != "" {
+		if minimum = slices.Index(models.Importances, importance); minimum < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "importance must be " + strings.Join(models.Importances, ", ")})
+			return
+		}
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	world, err := findVisibleWorld(h.DB, user, worldID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	var all []models.TimelineEvent
+	if err := preloadParticipants(h.DB.Where("world_id = ?", world.ID)).Order(eventOrder).Find(&all).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline events"})
+		return
+	}
+	events := []models.TimelineEvent{}
+	for _, event := range all {
+		// Events with no importance, or one from before it was checked, count as minor
+		if max(slices.Index(models.Importances, event.Importance), 0) >= minimum {
+			events = append(events, event)
+		}
+	}
+
+	var out bytes.Buffer
+	switch format {
+	case "json":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=world-%d-timeline.json", world.ID))
+		c.JSON(http.StatusOK, events)
+		return
+	case "csv":
+		err = timeline.WriteCSV(&out, events)
+	case "md":
+		var eras []models.WorldEra
+		if err := h.DB.Where("world_id = ?", world.ID).Order("sort_order ASC, id ASC").Find(&eras).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch eras"})
+			return
+		}
+		err = timeline.WriteChronicle(&out, world.Title, eras, events)
+	case "ics":
+		var skipped int
+		skipped, err = timeline.WriteICS(&out, world.Title, world.ID, events, time.Now())
+		c.Header("X-Skipped-Events", strconv.Itoa(skipped))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export timeline"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=world-%d-timeline.%s", world.ID, format))
+	c.Data(http.StatusOK, contentType, out.Bytes())
+}
+
+// POST /worlds/:id/timeline-events/import - adds the events in a CSV file,
+// sent as the body or as a multipart "file", with the columns of the CSV
+// export. Every row is checked first; if any fail, nothing is added and each
+// failure is reported by its row. Rows without a sort_order go after the
+// world's existing events in file order.
+func (h *TimelineEventHandler) ImportTimelineCSV(c *gin.Context) {
+	world, user, ok := h.editableWorld(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTimelineImportBytes)
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rows, problems, err := timeline.ReadCSV(body, maxTimelineImportRows)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV: " + err.Error()})
+		return
+	}
+	if len(problems) == 0 && len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The file has no events"})
+		return
+	}
+
+	cal, err := loadCalendar(h.DB, world.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load calendar"})
+		return
+	}
+
+	// Rows that read cleanly still need their dates and era checking
+	invalid := make(map[int]bool)
+	for _, problem := range problems {
+		invalid[problem.Row] = true
+	}
+	for i := range rows {
+		row := &rows[i]
+		if invalid[row.Number] {
+			continue
+		}
+		row.Event.WorldID = world.ID
+		row.Event.UserID = &user.ID
+		if err := prepareNewEvent(h.DB, cal, &row.Event); err != nil {
+			problems = append(problems, timeline.RowError{Row: row.Number, Error: err.Error()})
+		}
+	}
+	if len(problems) > 0 {
+		slices.SortStableFunc(problems, func(a, b timeline.RowError) int { return a.Row - b.Row })
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No events were imported", "errors": problems})
+		return
+	}
+
+	var last int
+	if err := h.DB.Model(&models.TimelineEvent{}).Where("world_id = ?", world.ID).
+		Select("COALESCE(MAX(sort_order), 0)").Scan(&last).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline events"})
+		return
+	}
+
+	events := make([]models.TimelineEvent, len(rows))
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	for i, row := range rows {
+		events[i] = row.Event
+		if !row.HasSortOrder {
+			last++
+			events[i].SortOrder = last
+		}
+		if err := createEvent(tx, &events[i]); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create the timeline event on row %d", row.Number)})
+			return
+		}
+	}
+	if err := assignEventEras(tx, world.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign events to eras"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import timeline events"})
+		return
+	}
+
+	// Eras may have been assigned from the dates
+	ids := make([]uint, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	h.DB.Where("id IN ?", ids).Order(eventOrder).Find(&events)
+
+	c.JSON(http.StatusCreated, gin.H{"events": events})
+}
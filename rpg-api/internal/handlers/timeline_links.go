@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/timeline"
+	"gorm.io/gorm"
+)
+
+type TimelineLinkHandler struct {
+	DB *gorm.DB
+}
+
+func NewTimelineLinkHandler(db *gorm.DB) *TimelineLinkHandler {
+	return &TimelineLinkHandler{DB: db}
+}
+
+type CreateTimelineLinkRequest struct {
+	ToEventID uint   `json:"to_event_id" binding:"required"`
+	Type      string `json:"type" binding:"required"`
+	Note      string `json:"note"`
+}
+
+// GET /worlds/:id/timeline-events/:eventId/links - every link to or from the
+// event, with the event it is part of and its own sub-events
+func (h *TimelineLinkHandler) GetEventLinks(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	event, ok := h.findEvent(c, worldID)
+	if !ok {
+		return
+	}
+
+	var links []models.TimelineEventLink
+	if err := h.DB.Preload("FromEvent").Preload("ToEvent").
+		Where("from_event_id = ? OR to_event_id = ?", event.ID, event.ID).
+		Order("id").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch links"})
+		return
+	}
+
+	var parent *models.TimelineEvent
+	for _, link := range links {
+		if link.Type == models.LinkPartOf && link.FromEventID == event.ID {
+			parent = link.ToEvent
+		}
+	}
+
+	subEvents := []models.TimelineEvent{}
+	if err := h.DB.Where("id IN (?)", h.DB.Model(&models.TimelineEventLink{}).
+		Select("from_event_id").
+		Where("to_event_id = ? AND type = ?", event.ID, models.LinkPartOf)).
+		Order(eventOrder).Find(&subEvents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sub-events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"links": links, "parent": parent, "sub_events": subEvents})
+}
+
+// POST /worlds/:id/timeline-events/:eventId/links - links the event to
+// another. Caused and part-of links can't close a loop; dates that disagree
+// with the link are allowed but come back as warnings.
+func (h *TimelineLinkHandler) CreateEventLink(c *gin.Context) {
+	world, user, ok := h.editableWorld(c)
+	if !ok {
+		return
+	}
+
+	event, ok := h.findEvent(c, int(world.ID))
+	if !ok {
+		return
+	}
+
+	var req CreateTimelineLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !slices.Contains(models.LinkTypes, req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be one of " + strings.Join(models.LinkTypes, ", ")})
+		return
+	}
+	if req.ToEventID == event.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An event can't be linked to itself"})
+		return
+	}
+
+	var other models.TimelineEvent
+	if err := h.DB.Where("id = ? AND world_id = ?", req.ToEventID, world.ID).First(&other).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Linked event not found"})
+		return
+	}
+
+	link := models.TimelineEventLink{
+		WorldID:     world.ID,
+		FromEventID: event.ID,
+		ToEventID:   other.ID,
+		Type:        req.Type,
+		Note:        strings.TrimSpace(req.Note),
+		UserID:      &user.ID,
+	}
+	from, to := *event, other
+	// Links that read the same both ways are stored one way round only
+	if !models.Directed(link.Type) && link.FromEventID > link.ToEventID {
+		link.FromEventID, link.ToEventID = link.ToEventID, link.FromEventID
+		from, to = to, from
+	}
+
+	var count int64
+	h.DB.Model(&models.TimelineEventLink{}).
+		Where("from_event_id = ? AND to_event_id = ? AND type = ?", link.FromEventID, link.ToEventID, link.Type).
+		Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "These events are already linked that way"})
+		return
+	}
+
+	if link.Type == models.LinkPartOf {
+		var whole models.TimelineEventLink
+		if err := h.DB.Preload("ToEvent").
+			Where("from_event_id = ? AND type = ?", link.FromEventID, models.LinkPartOf).
+			First(&whole).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%q is already part of %q", from.Title, whole.ToEvent.Title)})
+			return
+		}
+	}
+
+	if models.Directed(link.Type) {
+		var existing []models.TimelineEventLink
+		if err := h.DB.Where("world_id = ? AND type = ?", world.ID, link.Type).Find(&existing).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check links"})
+			return
+		}
+		if path := timeline.Path(existing, link.Type, link.ToEventID, link.FromEventID); path != nil {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": fmt.Sprintf("%q already leads back to %q, so this link would make a loop", to.Title, from.Title),
+				"path":  path,
+			})
+			return
+		}
+	}
+
+	if err := h.DB.Create(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link"})
+		return
+	}
+
+	warnings := timeline.Check([]models.TimelineEvent{from, to}, []models.TimelineEventLink{link})
+	h.DB.Preload("FromEvent").Preload("ToEvent").First(&link, link.ID)
+	c.JSON(http.StatusCreated, gin.H{"link": link, "warnings": warnings})
+}
+
+// DELETE /worlds/:id/timeline-events/:eventId/links/:linkId
+func (h *TimelineLinkHandler) DeleteEventLink(c *gin.Context) {
+	world, _, ok := h.editableWorld(c)
+	if !ok {
+		return
+	}
+
+	event, ok := h.findEvent(c, int(world.ID))
+	if !ok {
+		return
+	}
+
+	linkID, err := strconv.Atoi(c.Param("linkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	result := h.DB.Where("id = ? AND (from_event_id = ? OR to_event_id = ?)", linkID, event.ID, event.ID).
+		Delete(&models.TimelineEventLink{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete link"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Link deleted successfully"})
+}
+
+// GET /worlds/:id/timeline-events/graph?types=caused,part_of&event_id= - the
+// linked events and their links, with every loop and date problem in them.
+// event_id narrows the graph to the events connected to that one.
+func (h *TimelineLinkHandler) GetGraph(c *gin.Context) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, worldID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	types := models.LinkTypes
+	if param := c.Query("types"); param != "" {
+		types = strings.Split(param, ",")
+		for _, linkType := range types {
+			if !slices.Contains(models.LinkTypes, linkType) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "types must be from " + strings.Join(models.LinkTypes, ", ")})
+				return
+			}
+		}
+	}
+
+	var events []models.TimelineEvent
+	if err := h.DB.Where("world_id = ?", worldID).Order(eventOrder).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline events"})
+		return
+	}
+	var links []models.TimelineEventLink
+	if err := h.DB.Where("world_id = ?", worldID).Order("id").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch links"})
+		return
+	}
+
+	// Problems are found over every link, whichever are shown
+	issues := timeline.Check(events, links)
+
+	edges := []models.TimelineEventLink{}
+	for _, link := range links {
+		if slices.Contains(types, link.Type) {
+			edges = append(edges, link)
+		}
+	}
+
+	included := make(map[uint]bool)
+	if param := c.Query("event_id"); param != "" {
+		eventID, err := strconv.Atoi(param)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			return
+		}
+		if !slices.ContainsFunc(events, func(event models.TimelineEvent) bool { return event.ID == uint(eventID) }) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Timeline event not found"})
+			return
+		}
+		included = connected(edges, uint(eventID))
+		edges = slices.DeleteFunc(edges, func(link models.TimelineEventLink) bool { return !included[link.FromEventID] })
+		issues = slices.DeleteFunc(issues, func(issue timeline.Issue) bool {
+			return !slices.ContainsFunc(issue.EventIDs, func(id uint) bool { return included[id] })
+		})
+	} else {
+		for _, link := range edges {
+			included[link.FromEventID], included[link.ToEventID] = true, true
+		}
+	}
+
+	nodes := []models.TimelineEvent{}
+	for _, event := range events {
+		if included[event.ID] {
+			nodes = append(nodes, event)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"nodes": nodes, "edges": edges, "issues": issues})
+}
+
+// Loads the world in the URL if the current user can edit it, writing the
+// error response otherwise
+func (h *TimelineLinkHandler) editableWorld(c *gin.Context) (*models.World, *models.User, bool) {
+	worldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return nil, nil, false
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, nil, false
+	}
+
+	var world models.World
+	if err := h.DB.First(&world, worldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return nil, nil, false
+	}
+	if !canEditWorld(&world, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, nil, false
+	}
+	return &world, user, true
+}
+
+func (h *TimelineLinkHandler) findEvent(c *gin.Context, worldID int) (*models.TimelineEvent, bool) {
+	eventID, err := strconv.Atoi(c.Param("eventId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return nil, false
+	}
+
+	var event models.TimelineEvent
+	if err := h.DB.Where("id = ? AND world_id = ?", eventID, worldID).First(&event).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Timeline event not found"})
+		return nil, false
+	}
+	return &event, true
+}
+
+// Events reachable from one event through links followed either way round,
+// including the event itself
+func connected(links []models.TimelineEventLink, eventID uint) map[uint]bool {
+	neighbours := make(map[uint][]uint)
+	for _, link := range links {
+		neighbours[link.FromEventID] = append(neighbours[link.FromEventID], link.ToEventID)
+		neighbours[link.ToEventID] = append(neighbours[link.ToEventID], link.FromEventID)
+	}
+
+	seen := map[uint]bool{eventID: true}
+	queue := []uint{eventID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range neighbours[id] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return seen
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/jobs"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type TrashHandler struct {
+	DB        *gorm.DB
+	Retention time.Duration
+}
+
+type TrashItem struct {
+	ID        uint      `json:"id"`
+	Type      string    `json:"type"` // "adventure", "world", "asset"
+	Title     string    `json:"title"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"` // When the background purge removes it for good
+}
+
+func NewTrashHandler(db *gorm.DB) *TrashHandler {
+	return &TrashHandler{
+		DB:        db,
+		Retention: jobs.TrashRetention(),
+	}
+}
+
+// GET /me/trash - lists the current user's soft-deleted content
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var adventures []models.Adventure
+	if err := h.DB.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", user.ID).
+		Order("deleted_at DESC").Find(&adventures).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trashed adventures"})
+		return
+	}
+
+	var worlds []models.World
+	if err := h.DB.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", user.ID).
+		Order("deleted_at DESC").Find(&worlds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trashed worlds"})
+		return
+	}
+
+	var assets []models.Asset
+	if err := h.DB.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", user.ID).
+		Order("deleted_at DESC").Find(&assets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trashed assets"})
+		return
+	}
+
+	items := []TrashItem{}
+	for _, adventure := range adventures {
+		items = append(items, h.newTrashItem(adventure.ID, "adventure", adventure.Title, adventure.DeletedAt))
+	}
+	for _, world := range worlds {
+		items = append(items, h.newTrashItem(world.ID, "world", world.Title, world.DeletedAt))
+	}
+	for _, asset := range assets {
+		items = append(items, h.newTrashItem(asset.ID, "asset", asset.Name, asset.DeletedAt))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":          items,
+		"retention_days": int(h.Retention.Hours() / 24),
+	})
+}
+
+func (h *TrashHandler) newTrashItem(id uint, itemType string, title string, deletedAt gorm.DeletedAt) TrashItem {
+	return TrashItem{
+		ID:        id,
+		Type:      itemType,
+		Title:     title,
+		DeletedAt: deletedAt.Time,
+		PurgeAt:   deletedAt.Time.Add(h.Retention),
+	}
+}
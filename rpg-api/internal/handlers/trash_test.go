@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestRestoreFromTrash(t *testing.T) {
+	db := testdb.Open(t, &models.User{}, &models.World{}, &models.Adventure{}, &models.Episode{},
+		&models.TitlePage{}, &models.Epilogue{}, &models.Asset{})
+	owner := models.User{Email: "owner@example.com", Name: "Owner", Provider: "email"}
+	admin := models.User{Email: "admin@example.com", Name: "Admin", Provider: "email", IsAdmin: true}
+	stranger := models.User{Email: "stranger@example.com", Name: "Stranger", Provider: "email"}
+	for _, user := range []*models.User{&owner, &admin, &stranger} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	worlds := &WorldHandler{DB: db}
+	adventures := &AdventureHandler{DB: db}
+	assets := &AssetHandler{DB: db}
+
+	for _, kind := range []struct {
+		name    string
+		restore gin.HandlerFunc
+		trash   func() (uint, any)
+	}{
+		{"world", worlds.RestoreWorld, func() (uint, any) {
+			world := models.World{Title: "Varn", UserID: &owner.ID}
+			db.Create(&world)
+			return world.ID, &models.World{}
+		}},
+		{"adventure", adventures.RestoreAdventure, func() (uint, any) {
+			adventure := models.Adventure{Title: "Heist", UserID: &owner.ID}
+			db.Create(&adventure)
+			return adventure.ID, &models.Adventure{}
+		}},
+		{"asset", assets.RestoreAsset, func() (uint, any) {
+			asset := models.Asset{Name: "Map", ImageID: "map", UserID: &owner.ID}
+			db.Create(&asset)
+			return asset.ID, &models.Asset{}
+		}},
+	} {
+		for _, test := range []struct {
+			user   *models.User
+			status int
+		}{
+			{&owner, http.StatusOK},
+			{&admin, http.StatusOK},
+			{&stranger, http.StatusForbidden},
+		} {
+			id, model := kind.trash()
+			if err := db.Delete(model, id).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			w := serveID(kind.restore, test.user, id, http.MethodPost, "/trash/restore", "", nil)
+			if w.Code != test.status {
+				t.Errorf("%s restoring the %s: got %d, want %d: %s", test.user.Name, kind.name, w.Code, test.status, w.Body.String())
+			}
+			err := db.First(model, id).Error
+			if restored := err == nil; restored != (test.status == http.StatusOK) {
+				t.Errorf("%s restoring the %s: restored %v (%v)", test.user.Name, kind.name, restored, err)
+			}
+			if err != nil && err != gorm.ErrRecordNotFound {
+				t.Fatal(err)
+			}
+		}
+	}
+}
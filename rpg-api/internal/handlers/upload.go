@@ -2,48 +2,82 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
 	"github.com/naetharu/rpg-api/internal/services"
+	"gorm.io/gorm"
 )
 
 type UploadHandler struct {
-	CloudflareService *services.CloudflareImagesService
+	DB     *gorm.DB
+	Images services.ImageStore
+	Rules  services.ImageRules
+	Quota  UploadQuota
 }
 
 type ImageUploadResponse struct {
-	ImageID  string `json:"image_id"`
-	Filename string `json:"filename"`
-	URLs     struct {
-		Thumbnail string `json:"thumbnail"`
-		Medium    string `json:"medium"`
-		Large     string `json:"large"`
-		Original  string `json:"original"`
-	} `json:"urls"`
+	ImageID  string             `json:"image_id"`
+	Filename string             `json:"filename"`
+	Width    int                `json:"width"`
+	Height   int                `json:"height"`
+	Bytes    int                `json:"bytes"`
+	URLs     services.ImageURLs `json:"urls"`
 }
 
-func NewUploadHandler() *UploadHandler {
+func NewUploadHandler(db *gorm.DB, images services.ImageStore) *UploadHandler {
 	return &UploadHandler{
-		CloudflareService: services.NewCloudflareImagesService(),
+		DB:     db,
+		Images: images,
+		Rules:  services.ImageRulesFromEnv(),
+		Quota:  UploadQuotaFromEnv(),
 	}
 }
 
 // POST /api/upload/image
 func (h *UploadHandler) UploadImage(c *gin.Context) {
+	user, _ := middleware.GetCurrentUser(c)
+
+	// Leave room for the rest of the multipart form
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Rules.MaxBytes+1<<20)
+
 	// Get file from form
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Image too large: the limit is %d MB", h.Rules.MaxBytes>>20)})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
 		return
 	}
 	defer file.Close()
 
-	// Validate file type
+	// Validate file type by name first, the content is sniffed below
 	if !isValidImageType(header.Filename) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only JPEG, PNG, and WebP are allowed"})
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Invalid file type. Only JPEG, PNG, and WebP are allowed"})
+		return
+	}
+
+	// Check the content and strip metadata by re-encoding
+	image, err := services.PrepareImage(file, header.Filename, h.Rules)
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": uploadErrorMessage(err)})
+		return
+	}
+
+	if err := h.Quota.Check(h.DB, user, 1, int64(len(image.Data))); err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": uploadErrorMessage(err)})
 		return
 	}
 
@@ -56,28 +90,225 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 		metadata["caption"] = caption
 	}
 
-	// Upload to Cloudflare
-	cfResp, err := h.CloudflareService.UploadImage(file, header.Filename, metadata)
+	// Upload to the configured image store
+	stored, err := h.Images.Upload(bytes.NewReader(image.Data), image.Filename, metadata)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image: " + err.Error()})
 		return
 	}
 
-	// Build response with different variant URLs
-	response := ImageUploadResponse{
-		ImageID:  cfResp.Result.ID,
-		Filename: cfResp.Result.Filename,
+	// Track the upload for the quota and so it can be collected if nothing ends up using it
+	if err := recordUpload(h.DB, user, stored, len(image.Data)); err != nil {
+		log.Printf("Warning: Failed to record upload %s: %v", stored.ID, err)
 	}
 
-	// Generate URLs for different variants
-	response.URLs.Thumbnail = h.CloudflareService.GetImageURL(cfResp.Result.ID, "thumbnail")
-	response.URLs.Medium = h.CloudflareService.GetImageURL(cfResp.Result.ID, "medium")
-	response.URLs.Large = h.CloudflareService.GetImageURL(cfResp.Result.ID, "large")
-	response.URLs.Original = h.CloudflareService.GetImageURL(cfResp.Result.ID, "public")
+	// Build response with the URL of every variant
+	response := ImageUploadResponse{
+		ImageID:  stored.ID,
+		Filename: stored.Filename,
+		Width:    image.Width,
+		Height:   image.Height,
+		Bytes:    len(image.Data),
+		URLs:     services.VariantURLs(h.Images, stored.ID),
+	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// How long a direct upload URL stays valid
+const directUploadExpiry = 30 * time.Minute
+
+type DirectUploadRequest struct {
+	Filename string `json:"filename"`
+	Alt      string `json:"alt"`
+	Caption  string `json:"caption"`
+}
+
+// POST /api/upload/url - reserve an image ID and a one-time URL the browser
+// uploads the file to directly; finish with POST /api/upload/confirm
+func (h *UploadHandler) CreateUploadURL(c *gin.Context) {
+	user, _ := middleware.GetCurrentUser(c)
+
+	uploader, ok := h.Images.(services.DirectUploader)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Direct uploads are not supported by this image store"})
+		return
+	}
+
+	var req DirectUploadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Filename != "" && !isValidImageType(req.Filename) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Invalid file type. Only JPEG, PNG, and WebP are allowed"})
+		return
+	}
+
+	// The size isn't known yet, so only the daily count is checked here
+	if err := h.Quota.Check(h.DB, user, 1, 0); err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": uploadErrorMessage(err)})
+		return
+	}
+
+	metadata := make(map[string]string)
+	if req.Alt != "" {
+		metadata["alt"] = req.Alt
+	}
+	if req.Caption != "" {
+		metadata["caption"] = req.Caption
+	}
+
+	upload, err := uploader.NewDirectUpload(time.Now().Add(directUploadExpiry), metadata)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload URL: " + err.Error()})
+		return
+	}
+
+	// Pending until confirmed. Abandoned uploads are collected like any
+	// other unused image.
+	record := models.Upload{UserID: &user.ID, ImageID: upload.ImageID, Filename: req.Filename, Pending: true}
+	if err := h.DB.Create(&record).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record upload"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// POST /api/upload/confirm - register a finished direct upload against the user
+func (h *UploadHandler) ConfirmUpload(c *gin.Context) {
+	user, _ := middleware.GetCurrentUser(c)
+
+	uploader, ok := h.Images.(services.DirectUploader)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Direct uploads are not supported by this image store"})
+		return
+	}
+
+	var req struct {
+		ImageID string `json:"image_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only the user the URL was issued to can claim the image
+	var record models.Upload
+	if err := h.DB.Where("image_id = ? AND user_id = ?", req.ImageID, user.ID).First(&record).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+
+	if record.Pending {
+		stored, err := uploader.Lookup(record.ImageID)
+		if errors.Is(err, services.ErrImageNotUploaded) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The image has not been uploaded yet"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check upload: " + err.Error()})
+			return
+		}
+		filename := record.Filename
+		if filename == "" {
+			filename = stored.Filename
+		}
+
+		// Files sent straight to the store get the same checks as any other
+		// upload, and the re-encoded copy is stored in their place
+		var image *services.ProcessedImage
+		if fetcher, ok := h.Images.(services.ImageFetcher); ok {
+			data, err := fetcher.Fetch(record.ImageID, h.Rules.MaxBytes)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check upload: " + err.Error()})
+				return
+			}
+			image, err = services.PrepareImage(bytes.NewReader(data), filename, h.Rules)
+			if err != nil {
+				h.rejectUpload(c, &record, err)
+				return
+			}
+			stored.Bytes = int64(len(image.Data))
+		}
+
+		// The size is only known now. An upload that can't be measured or
+		// doesn't fit is thrown away.
+		err = h.Quota.Check(h.DB, user, 0, stored.Bytes)
+		if stored.Bytes > h.Rules.MaxBytes {
+			err = fmt.Errorf("%w: the limit is %d MB", services.ErrImageTooLarge, h.Rules.MaxBytes>>20)
+		}
+		if stored.Bytes <= 0 {
+			err = fmt.Errorf("%w: its size could not be checked", services.ErrUnsupportedImage)
+		}
+		if err != nil {
+			h.rejectUpload(c, &record, err)
+			return
+		}
+
+		if image != nil {
+			if _, err := h.Images.(services.ImageFetcher).Replace(record.ImageID, bytes.NewReader(image.Data), image.Filename); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store checked image: " + err.Error()})
+				return
+			}
+			filename = image.Filename
+		}
+
+		record.Pending = false
+		record.Bytes = stored.Bytes
+		if record.Filename == "" {
+			record.Filename = filename
+		}
+		if err := h.DB.Model(&record).Select("pending", "bytes", "filename").Updates(&record).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm upload"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, ImageUploadResponse{
+		ImageID:  record.ImageID,
+		Filename: record.Filename,
+		Bytes:    int(record.Bytes),
+		URLs:     services.VariantURLs(h.Images, record.ImageID),
+	})
+}
+
+// Throws away a direct upload that broke the rules
+func (h *UploadHandler) rejectUpload(c *gin.Context, record *models.Upload, err error) {
+	if err := h.Images.Delete(record.ImageID); err != nil {
+		// The record stays so the collector tries again
+		log.Printf("Warning: Failed to delete rejected upload %s: %v", record.ImageID, err)
+	} else {
+		h.DB.Delete(record)
+	}
+	c.JSON(uploadErrorStatus(err), gin.H{"error": uploadErrorMessage(err)})
+}
+
+// GET /api/upload/quota - the current user's image storage and daily uploads
+func (h *UploadHandler) GetQuota(c *gin.Context) {
+	user, _ := middleware.GetCurrentUser(c)
+
+	usage, err := h.Quota.Usage(h.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch upload quota"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// Records an image in the uploads table. user is nil for system uploads.
+func recordUpload(db *gorm.DB, user *models.User, stored *services.StoredImage, size int) error {
+	upload := models.Upload{ImageID: stored.ID, Filename: stored.Filename, Bytes: int64(size)}
+	if user != nil {
+		upload.UserID = &user.ID
+	}
+	return db.Create(&upload).Error
+}
+
 func isValidImageType(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
 	validExts := []string{".jpg", ".jpeg", ".png", ".webp"}
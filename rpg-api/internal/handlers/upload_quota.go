@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/services"
+	"gorm.io/gorm"
+)
+
+// Quotas used when the environment doesn't set them
+const (
+	DefaultUploadQuotaMB = 500
+	DefaultUploadsPerDay = 200
+)
+
+// UploadQuota limits how much each user can store in the image store. It is
+// counted from the uploads table, so images collected as orphans or purged
+// from the trash free their space again. Admins are exempt.
+type UploadQuota struct {
+	MaxBytes  int64 // Total stored per user
+	MaxPerDay int   // Uploads per user in any 24 hours
+}
+
+// UploadUsage is a user's standing against the quota
+type UploadUsage struct {
+	Bytes        int64 `json:"bytes"`
+	MaxBytes     int64 `json:"max_bytes"`
+	UploadsToday int64 `json:"uploads_today"`
+	MaxPerDay    int   `json:"max_per_day"`
+}
+
+var errUploadQuotaExceeded = errors.New("upload quota exceeded")
+
+// UploadQuotaFromEnv reads UPLOAD_QUOTA_MB and UPLOADS_PER_DAY, falling back to the defaults
+func UploadQuotaFromEnv() UploadQuota {
+	quota := UploadQuota{MaxBytes: DefaultUploadQuotaMB << 20, MaxPerDay: DefaultUploadsPerDay}
+	if value, err := strconv.Atoi(os.Getenv("UPLOAD_QUOTA_MB")); err == nil && value > 0 {
+		quota.MaxBytes = int64(value) << 20
+	}
+	if value, err := strconv.Atoi(os.Getenv("UPLOADS_PER_DAY")); err == nil && value > 0 {
+		quota.MaxPerDay = value
+	}
+	return quota
+}
+
+// Usage totals a user's uploads
+func (q UploadQuota) Usage(db *gorm.DB, userID uint) (UploadUsage, error) {
+	usage := UploadUsage{MaxBytes: q.MaxBytes, MaxPerDay: q.MaxPerDay}
+	if err := db.Model(&models.Upload{}).Where("user_id = ?", userID).
+		Select("COALESCE(SUM(bytes), 0)").Scan(&usage.Bytes).Error; err != nil {
+		return usage, err
+	}
+	if err := db.Model(&models.Upload{}).
+		Where("user_id = ? AND created_at > ?", userID, time.Now().Add(-24*time.Hour)).
+		Count(&usage.UploadsToday).Error; err != nil {
+		return usage, err
+	}
+	return usage, nil
+}
+
+// Check fails with errUploadQuotaExceeded when storing count more images
+// totalling bytes would take the user over either limit
+func (q UploadQuota) Check(db *gorm.DB, user *models.User, count int, bytes int64) error {
+	if user.IsAdmin {
+		return nil
+	}
+
+	usage, err := q.Usage(db, user.ID)
+	if err != nil {
+		return err
+	}
+	if usage.UploadsToday+int64(count) > int64(q.MaxPerDay) {
+		return fmt.Errorf("%w: you can upload %d images a day, try again later", errUploadQuotaExceeded, q.MaxPerDay)
+	}
+	if usage.Bytes+bytes > q.MaxBytes {
+		return fmt.Errorf("%w: you are using %d of your %d MB of image storage, delete some images first",
+			errUploadQuotaExceeded, usage.Bytes>>20, q.MaxBytes>>20)
+	}
+	return nil
+}
+
+// Errors from image processing and the quota are written for end users,
+// with the first letter capitalised like the handlers' own messages
+func uploadErrorMessage(err error) string {
+	message := err.Error()
+	if uploadErrorStatus(err) == http.StatusInternalServerError || message == "" {
+		return "Upload failed"
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+// Status code for an error from image processing or the quota check
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUnsupportedImage):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errUploadQuotaExceeded):
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
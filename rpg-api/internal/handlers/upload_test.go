@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/services"
+	"github.com/naetharu/rpg-api/internal/testdb"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// An upload handler over a fresh database and an in-memory image store
+func newTestUploadHandler(t *testing.T) (*UploadHandler, *services.MemoryImageStore, *models.User) {
+	t.Helper()
+
+	db := testdb.Open(t, &models.User{}, &models.Upload{})
+	user := models.User{Email: "gm@example.com", Name: "GM", Provider: "email"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	store := services.NewMemoryImageStore()
+	h := &UploadHandler{
+		DB:     db,
+		Images: store,
+		Rules:  services.ImageRules{MaxBytes: 1 << 20, MaxDimension: 1000, MaxPixels: 1_000_000},
+		Quota:  UploadQuota{MaxBytes: 1 << 20, MaxPerDay: 10},
+	}
+	return h, store, &user
+}
+
+// Runs a handler as user and returns the response
+func serve(handler gin.HandlerFunc, user *models.User, method, target, contentType string, body io.Reader) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		c.Request.Header.Set("Content-Type", contentType)
+	}
+	c.Set("user", user)
+	handler(c)
+	return w
+}
+
+// Runs a handler for the resource with the given :id
+func serveID(handler gin.HandlerFunc, user *models.User, id uint, method, target, contentType string, body io.Reader) *httptest.ResponseRecorder {
+	return serve(func(c *gin.Context) {
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(id), 10)}}
+		handler(c)
+	}, user, method, target, contentType, body)
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: 200, A: 255})
+	}
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var value T
+	if err := json.Unmarshal(w.Body.Bytes(), &value); err != nil {
+		t.Fatalf("Invalid response %q: %v", w.Body.String(), err)
+	}
+	return value
+}
+
+func TestUploadImage(t *testing.T) {
+	h, store, user := newTestUploadHandler(t)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "map.png")
+	part.Write(testPNG(t, 8, 4))
+	form.Close()
+
+	w := serve(h.UploadImage, user, http.MethodPost, "/api/upload/image", form.FormDataContentType(), &body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got %d: %s", w.Code, w.Body.String())
+	}
+	response := decode[ImageUploadResponse](t, w)
+	if response.Width != 8 || response.Height != 4 {
+		t.Errorf("Got %dx%d, want 8x4", response.Width, response.Height)
+	}
+	if !store.Has(response.ImageID) {
+		t.Fatalf("Image %s was not stored", response.ImageID)
+	}
+	if stored := len(store.Images[response.ImageID]); stored != response.Bytes {
+		t.Errorf("Stored %d bytes, reported %d", stored, response.Bytes)
+	}
+
+	var record models.Upload
+	if err := h.DB.Where("image_id = ?", response.ImageID).First(&record).Error; err != nil {
+		t.Fatalf("Upload not recorded: %v", err)
+	}
+	if record.UserID == nil || *record.UserID != user.ID || record.Bytes != int64(response.Bytes) || record.Pending {
+		t.Errorf("Recorded %+v", record)
+	}
+}
+
+func TestUploadImageRejectsContent(t *testing.T) {
+	h, store, user := newTestUploadHandler(t)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "map.png")
+	part.Write([]byte("not an image"))
+	form.Close()
+
+	w := serve(h.UploadImage, user, http.MethodPost, "/api/upload/image", form.FormDataContentType(), &body)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Got %d, want %d", w.Code, http.StatusUnsupportedMediaType)
+	}
+	if len(store.Images) != 0 {
+		t.Errorf("Stored %d images", len(store.Images))
+	}
+}
+
+func TestDirectUpload(t *testing.T) {
+	h, store, user := newTestUploadHandler(t)
+
+	w := serve(h.CreateUploadURL, user, http.MethodPost, "/api/upload/url", "application/json", bytes.NewBufferString(`{"filename":"map.png"}`))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Got %d: %s", w.Code, w.Body.String())
+	}
+	upload := decode[services.DirectUpload](t, w)
+	if upload.ExpiresAt.Before(time.Now()) {
+		t.Errorf("Upload URL already expired at %v", upload.ExpiresAt)
+	}
+
+	confirm := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"image_id": upload.ImageID})
+		return serve(h.ConfirmUpload, user, http.MethodPost, "/api/upload/confirm", "application/json", bytes.NewReader(body))
+	}
+
+	if w := confirm(); w.Code != http.StatusConflict {
+		t.Fatalf("Confirming before the file arrived: got %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	data := testPNG(t, 4, 4)
+	if err := store.Complete(upload.ImageID, data, "map.png"); err != nil {
+		t.Fatal(err)
+	}
+	w = confirm()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got %d: %s", w.Code, w.Body.String())
+	}
+	// The file is checked and swapped for its re-encoded copy
+	stored := store.Images[upload.ImageID]
+	if response := decode[ImageUploadResponse](t, w); response.Bytes != len(stored) || response.Filename != "map.png" {
+		t.Errorf("Got %+v", response)
+	}
+	if _, _, err := services.InspectImage(stored, h.Rules); err != nil {
+		t.Errorf("Stored image doesn't pass the rules: %v", err)
+	}
+
+	var record models.Upload
+	if err := h.DB.Where("image_id = ?", upload.ImageID).First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	if record.Pending || record.Bytes != int64(len(stored)) {
+		t.Errorf("Recorded %+v", record)
+	}
+
+	// Confirming again answers from the record without going back to the store
+	delete(store.Images, upload.ImageID)
+	if w := confirm(); w.Code != http.StatusOK {
+		t.Errorf("Confirming twice: got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestConfirmUploadRejects(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		data   []byte
+		quota  int64
+		status int
+	}{
+		{"over the quota", testPNG(t, 4, 4), 10, http.StatusTooManyRequests},
+		{"over the size limit", bytes.Repeat([]byte{1}, 2<<20), 10 << 20, http.StatusRequestEntityTooLarge},
+		{"over the dimension limit", testPNG(t, 1200, 10), 10 << 20, http.StatusRequestEntityTooLarge},
+		{"not an image", []byte("GIF89a"), 10 << 20, http.StatusUnsupportedMediaType},
+		{"empty", nil, 10 << 20, http.StatusUnsupportedMediaType},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h, store, user := newTestUploadHandler(t)
+			h.Quota.MaxBytes = test.quota
+
+			w := serve(h.CreateUploadURL, user, http.MethodPost, "/api/upload/url", "", nil)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Got %d: %s", w.Code, w.Body.String())
+			}
+			upload := decode[services.DirectUpload](t, w)
+			if err := store.Complete(upload.ImageID, test.data, "map.png"); err != nil {
+				t.Fatal(err)
+			}
+
+			body, _ := json.Marshal(map[string]string{"image_id": upload.ImageID})
+			w = serve(h.ConfirmUpload, user, http.MethodPost, "/api/upload/confirm", "application/json", bytes.NewReader(body))
+			if w.Code != test.status {
+				t.Fatalf("Got %d, want %d: %s", w.Code, test.status, w.Body.String())
+			}
+
+			// The image is thrown away along with its record
+			if store.Has(upload.ImageID) {
+				t.Error("Rejected image was kept")
+			}
+			var count int64
+			h.DB.Model(&models.Upload{}).Count(&count)
+			if count != 0 {
+				t.Errorf("%d upload records left", count)
+			}
+		})
+	}
+}
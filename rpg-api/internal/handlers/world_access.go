@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Loads a world the user can read: official or reviewed worlds, their own,
+// or any world for admins. user is nil for anonymous requests.
+func findVisibleWorld(db *gorm.DB, user *models.User, worldID int) (*models.World, error) {
+	query := db.Where("id = ?", worldID)
+	if user == nil {
+		query = query.Where("is_official = ? OR reviewed = ?", true, true)
+	} else if !user.IsAdmin {
+		query = query.Where("is_official = ? OR reviewed = ? OR user_id = ?", true, true, user.ID)
+	}
+
+	var world models.World
+	if err := query.First(&world).Error; err != nil {
+		return nil, err
+	}
+	return &world, nil
+}
+
+// Only a world's owner or an admin can change what's in it
+func canEditWorld(world *models.World, user *models.User) bool {
+	if user == nil {
+		return false
+	}
+	return user.IsAdmin || (world.UserID != nil && *world.UserID == user.ID)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/archive"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+)
+
+// Largest world archive accepted by POST /worlds/import
+const maxWorldArchiveBytes = 50 << 20
+
+// GET /worlds/:id/export - the whole world as a versioned JSON archive
+func (h *WorldHandler) ExportWorld(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	// Same visibility rules as GET /worlds/:id
+	user, _ := middleware.GetCurrentUser(c)
+	query := h.DB.Model(&models.World{}).Where("id = ?", id)
+	if user == nil {
+		query = query.Where("is_official = ? OR reviewed = ?", true, true)
+	} else if !user.IsAdmin {
+		query = query.Where("is_official = ? OR reviewed = ? OR user_id = ?", true, true, user.ID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil || count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	export, err := archive.ExportWorld(h.DB, uint(id), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export world"})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=world-"+strconv.Itoa(id)+".json")
+	c.JSON(http.StatusOK, export)
+}
+
+// POST /worlds/import - recreate an exported world for the current user.
+// Takes the archive as the JSON body or as a multipart "file".
+func (h *WorldHandler) ImportWorld(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxWorldArchiveBytes)
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	var worldArchive archive.WorldArchive
+	if err := json.NewDecoder(body).Decode(&worldArchive); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid archive: " + err.Error()})
+		return
+	}
+	if err := worldArchive.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid archive: " + err.Error()})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	summary, err := archive.ImportWorld(tx, &worldArchive, user.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import world"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import world"})
+		return
+	}
+
+	var world models.World
+	h.DB.Preload("User").First(&world, summary.WorldID)
+
+	c.JSON(http.StatusCreated, gin.H{"world": world, "imported": summary})
+}
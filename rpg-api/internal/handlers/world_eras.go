@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/naetharu/rpg-api/internal/middleware"
@@ -86,17 +88,39 @@ func (h *WorldEraHandler) CreateEra(c *gin.Context) {
 		return
 	}
 
+	era.ID = 0
 	era.WorldID = uint(worldID)
 
-	// Check for duplicate name
+	// Check for duplicate name. Events find their era by name whatever its
+	// case, so names differing only in case would be ambiguous.
 	var existingCount int64
-	h.DB.Model(&models.WorldEra{}).Where("world_id = ? AND name = ?", worldID, era.Name).Count(&existingCount)
+	h.DB.Model(&models.WorldEra{}).Where("world_id = ? AND LOWER(name) = LOWER(?)", worldID, era.Name).Count(&existingCount)
 	if existingCount > 0 {
 		c.JSON(http.StatusConflict, gin.H{"error": "Era with this name already exists"})
 		return
 	}
 
-	if err := h.DB.Create(&era).Error; err != nil {
+	if status, err := h.dateEra(&era); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Create(&era).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create era"})
+		return
+	}
+	if err := assignEventEras(tx, era.WorldID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign events to eras"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create era"})
 		return
 	}
@@ -152,14 +176,60 @@ func (h *WorldEraHandler) UpdateEra(c *gin.Context) {
 	// Check for duplicate name if name is being changed
 	if updates.Name != "" && updates.Name != era.Name {
 		var existingCount int64
-		h.DB.Model(&models.WorldEra{}).Where("world_id = ? AND name = ? AND id != ?", worldID, updates.Name, eraID).Count(&existingCount)
+		h.DB.Model(&models.WorldEra{}).Where("world_id = ? AND LOWER(name) = LOWER(?) AND id != ?", worldID, updates.Name, eraID).Count(&existingCount)
 		if existingCount > 0 {
 			c.JSON(http.StatusConflict, gin.H{"error": "Era with this name already exists"})
 			return
 		}
 	}
 
-	if err := h.DB.Model(&era).Updates(updates).Error; err != nil {
+	oldName := era.Name
+	if updates.Name != "" {
+		era.Name = updates.Name
+	}
+	if updates.SortOrder != 0 {
+		era.SortOrder = updates.SortOrder
+	}
+	if updates.StartDate != "" {
+		era.StartDate = updates.StartDate
+	}
+	if updates.EndDate != nil {
+		era.EndDate = updates.EndDate
+		if *era.EndDate == "" {
+			era.EndDate = nil // An empty end date makes the era open-ended
+		}
+	}
+	if status, err := h.dateEra(&era); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if err := tx.Save(&era).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update era"})
+		return
+	}
+
+	// Renames reach every event in the era, including ones from before events
+	// referenced eras by ID
+	if err := tx.Model(&models.TimelineEvent{}).
+		Where("era_id = ? OR (world_id = ? AND era_id IS NULL AND LOWER(era) = LOWER(?))", era.ID, era.WorldID, oldName).
+		Updates(map[string]interface{}{"era_id": era.ID, "era": era.Name}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename era on events"})
+		return
+	}
+	if err := assignEventEras(tx, era.WorldID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign events to eras"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update era"})
 		return
 	}
@@ -167,7 +237,8 @@ func (h *WorldEraHandler) UpdateEra(c *gin.Context) {
 	c.JSON(http.StatusOK, era)
 }
 
-// DELETE /worlds/:worldId/eras/:eraId
+// DELETE /worlds/:worldId/eras/:eraId?reassign_to=3 - events in the era move
+// to reassign_to; without it, eras that still have events can't be deleted
 func (h *WorldEraHandler) DeleteEra(c *gin.Context) {
 	worldID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -208,13 +279,50 @@ func (h *WorldEraHandler) DeleteEra(c *gin.Context) {
 
 	// Check if era is being used by timeline events
 	var eventCount int64
-	h.DB.Model(&models.TimelineEvent{}).Where("world_id = ? AND era = ?", worldID, era.Name).Count(&eventCount)
-	if eventCount > 0 {
-		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete era that is used by timeline events"})
+	h.DB.Model(&models.TimelineEvent{}).
+		Where("era_id = ? OR (world_id = ? AND era_id IS NULL AND LOWER(era) = LOWER(?))", era.ID, worldID, era.Name).
+		Count(&eventCount)
+
+	var target *models.WorldEra
+	if reassignTo := c.Query("reassign_to"); reassignTo != "" {
+		target = &models.WorldEra{}
+		if err := h.DB.Where("id = ? AND world_id = ? AND id <> ?", reassignTo, worldID, era.ID).First(target).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reassign_to must be another era in this world"})
+			return
+		}
+	} else if eventCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       "Cannot delete era that is used by timeline events; pass reassign_to to move them to another era",
+			"event_count": eventCount,
+		})
 		return
 	}
 
-	if err := h.DB.Delete(&era).Error; err != nil {
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	if target != nil {
+		if err := tx.Model(&models.TimelineEvent{}).
+			Where("era_id = ? OR (world_id = ? AND era_id IS NULL AND LOWER(era) = LOWER(?))", era.ID, worldID, era.Name).
+			Updates(map[string]interface{}{"era_id": target.ID, "era": target.Name}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reassign events"})
+			return
+		}
+	}
+	if err := tx.Delete(&era).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete era"})
+		return
+	}
+	if err := assignEventEras(tx, era.WorldID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign events to eras"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete era"})
 		return
 	}
@@ -288,3 +396,104 @@ func (h *WorldEraHandler) ReorderEras(c *gin.Context) {
 
 	c.JSON(http.StatusOK, eras)
 }
+
+// Parses an era's dates with the world calendar and checks it doesn't
+// overlap another dated era. Returns the status to respond with on failure.
+// Without a calendar the dates are kept as free text.
+func (h *WorldEraHandler) dateEra(era *models.WorldEra) (int, error) {
+	era.StartDate = strings.TrimSpace(era.StartDate)
+	era.StartDay, era.EndDay = nil, nil
+	if era.StartDate == "" {
+		if era.EndDate != nil && strings.TrimSpace(*era.EndDate) != "" {
+			return http.StatusBadRequest, fmt.Errorf("an era with an end date needs a start date")
+		}
+		return 0, nil
+	}
+
+	cal, err := loadCalendar(h.DB, era.WorldID)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to load calendar")
+	}
+	if cal == nil {
+		return 0, nil
+	}
+
+	first, last, err := cal.Range(era.StartDate, era.EndDate)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	era.StartDay = &first
+	if era.EndDate != nil && strings.TrimSpace(*era.EndDate) != "" {
+		era.EndDay = &last
+	}
+
+	var overlapping models.WorldEra
+	query := h.DB.Where("world_id = ? AND id <> ? AND start_day IS NOT NULL", era.WorldID, era.ID).
+		Where("end_day IS NULL OR end_day >= ?", first)
+	if era.EndDay != nil {
+		query = query.Where("start_day <= ?", *era.EndDay)
+	}
+	if err := query.Order("start_day").Limit(1).Find(&overlapping).Error; err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to check for overlapping eras")
+	}
+	if overlapping.ID != 0 {
+		return http.StatusConflict, fmt.Errorf("%s overlaps the era %q, which starts %s", era.Name, overlapping.Name, overlapping.StartDate)
+	}
+	return 0, nil
+}
+
+// Puts every dated event in the dated era containing its start day, or in no
+// era when none does. Worlds without dated eras are left alone, so their
+// events keep the era they were given.
+func assignEventEras(tx *gorm.DB, worldID uint) error {
+	var dated int64
+	if err := tx.Model(&models.WorldEra{}).Where("world_id = ? AND start_day IS NOT NULL", worldID).Count(&dated).Error; err != nil {
+		return err
+	}
+	if dated == 0 {
+		return nil
+	}
+
+	// Eras don't overlap, so at most one contains each event
+	if err := tx.Exec(`UPDATE timeline_events t SET era_id = e.id, era = e.name
+		FROM world_eras e
+		WHERE t.world_id = ? AND t.start_day IS NOT NULL
+			AND e.world_id = t.world_id AND e.start_day IS NOT NULL
+			AND t.start_day >= e.start_day AND (e.end_day IS NULL OR t.start_day <= e.end_day)`, worldID).Error; err != nil {
+		return err
+	}
+	return tx.Exec(`UPDATE timeline_events t SET era_id = NULL, era = ''
+		WHERE t.world_id = ? AND t.start_day IS NOT NULL AND NOT EXISTS (
+			SELECT 1 FROM world_eras e
+			WHERE e.world_id = t.world_id AND e.start_day IS NOT NULL
+				AND t.start_day >= e.start_day AND (e.end_day IS NULL OR t.start_day <= e.end_day))`, worldID).Error
+}
+
+// Matches an event's era_id, or failing that its era name, to one of the
+// world's eras
+func resolveEventEra(db *gorm.DB, event *models.TimelineEvent) error {
+	var era models.WorldEra
+	switch {
+	case event.EraID != nil:
+		if err := db.Where("id = ? AND world_id = ?", *event.EraID, event.WorldID).First(&era).Error; err != nil {
+			return fmt.Errorf("era %d does not exist in this world", *event.EraID)
+		}
+	case strings.TrimSpace(event.Era) != "":
+		if err := db.Where("world_id = ? AND LOWER(name) = LOWER(?)", event.WorldID, strings.TrimSpace(event.Era)).First(&era).Error; err != nil {
+			return fmt.Errorf("era %q does not exist in this world", event.Era)
+		}
+	default:
+		return nil
+	}
+	event.EraID, event.Era = &era.ID, era.Name
+	return nil
+}
+
+// BackfillEventEras links events from before eras were referenced by ID to
+// the era with their era name, matched whatever its case as resolveEventEra
+// does. Safe to run on every start.
+func BackfillEventEras(db *gorm.DB) error {
+	return db.Exec(`UPDATE timeline_events AS t SET era_id = e.id, era = e.name
+		FROM world_eras e
+		WHERE t.era_id IS NULL AND t.era <> '' AND e.world_id = t.world_id AND LOWER(e.name) = LOWER(t.era)`).Error
+}
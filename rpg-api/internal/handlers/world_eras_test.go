@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/testdb"
+)
+
+func TestEraNamesIgnoreCase(t *testing.T) {
+	db := testdb.Open(t, &models.User{}, &models.World{}, &models.WorldCalendar{}, &models.WorldEra{}, &models.TimelineEvent{})
+	user := models.User{Email: "gm@example.com", Name: "GM", Provider: "email"}
+	db.Create(&user)
+	world := models.World{Title: "Varn", UserID: &user.ID}
+	db.Create(&world)
+	war := models.WorldEra{WorldID: world.ID, Name: "The Long War", SortOrder: 1}
+	db.Create(&war)
+	peace := models.WorldEra{WorldID: world.ID, Name: "Peace", SortOrder: 2}
+	db.Create(&peace)
+
+	h := &WorldEraHandler{DB: db}
+	w := serveID(h.CreateEra, &user, world.ID, http.MethodPost, "/worlds/1/eras", "application/json",
+		bytes.NewBufferString(`{"name":"the long war","sort_order":3}`))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Creating a name differing only in case: got %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	// Events from before eras had IDs find theirs whatever the case
+	event := models.TimelineEvent{WorldID: world.ID, Title: "Siege", StartDate: "1203", Era: "the long war", Importance: models.ImportanceMinor}
+	db.Create(&event)
+	if err := BackfillEventEras(db); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&event, event.ID)
+	if event.EraID == nil || *event.EraID != war.ID || event.Era != "The Long War" {
+		t.Errorf("Backfilled event has era %v %q", event.EraID, event.Era)
+	}
+}
@@ -0,0 +1,483 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/fork"
+	"github.com/naetharu/rpg-api/internal/middleware"
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MergeSelection picks one change from GET /worlds/:id/diff to merge
+type MergeSelection struct {
+	Kind     string `json:"kind"`
+	Change   string `json:"change"`
+	ForkID   *uint  `json:"fork_id"`
+	SourceID *uint  `json:"source_id"`
+}
+
+type MergeRequest struct {
+	Changes []MergeSelection `json:"changes" binding:"required"`
+	Force   bool             `json:"force"` // Merge conflicting changes over the source's own
+}
+
+// Event columns a merged change writes
+var mergedEventColumns = []string{"title", "description", "start_date", "end_date", "start_day", "end_day", "duration_days", "era_id", "era", "importance", "sort_order", "details"}
+
+// NPC columns a merged change writes
+var mergedNPCColumns = []string{"name", "age", "gender", "profession", "social_class", "personality", "is_alive", "location_id"}
+
+// POST /worlds/:id/fork - copies a world the user can see into a new world
+// of their own for what-if play. Takes an optional {"title"}.
+func (h *WorldHandler) ForkWorld(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	source, err := findVisibleWorld(h.DB, user, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	var request struct {
+		Title string `json:"title"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	title := strings.TrimSpace(request.Title)
+	if title == "" {
+		title = source.Title + " (fork)"
+	}
+
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	summary, err := fork.Fork(tx, *source, user, title, time.Now())
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fork world"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fork world"})
+		return
+	}
+
+	var world models.World
+	h.DB.Preload("User").First(&world, summary.WorldID)
+
+	c.JSON(http.StatusCreated, gin.H{"world": world, "copied": summary})
+}
+
+// GET /worlds/:id/forks - the forks of a world the user can see, newest first
+func (h *WorldHandler) GetWorldForks(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	if _, err := findVisibleWorld(h.DB, user, id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return
+	}
+
+	query := h.DB.Where("forked_from_id = ?", id)
+	if user == nil {
+		query = query.Where("is_official = ? OR reviewed = ?", true, true)
+	} else if !user.IsAdmin {
+		query = query.Where("is_official = ? OR reviewed = ? OR user_id = ?", true, true, user.ID)
+	}
+
+	forks := []models.World{}
+	if err := query.Preload("User").Order("forked_at DESC, id DESC").Find(&forks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch forks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, forks)
+}
+
+// GET /worlds/:id/diff - the events and NPCs a fork has added, removed and
+// changed compared with the world it was forked from
+func (h *WorldHandler) DiffWorldFork(c *gin.Context) {
+	forked, _, ok := h.forkAndSource(c)
+	if !ok {
+		return
+	}
+
+	diff, err := fork.Compare(h.DB, *forked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare worlds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// POST /worlds/:id/merge - copies selected changes from a fork back into
+// the world it was forked from. Every selection is checked first; if any
+// fail, nothing changes and each failure is reported by its index.
+// Conflicting changes need force. Event participants come along when the
+// NPCs, organizations and locations they name exist in the source; links,
+// relationships and images stay in the fork.
+func (h *WorldHandler) MergeWorldFork(c *gin.Context) {
+	forked, source, ok := h.forkAndSource(c)
+	if !ok {
+		return
+	}
+	user, _ := middleware.GetCurrentUser(c)
+	if !canEditWorld(source, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the source world's owner can merge into it"})
+		return
+	}
+
+	var request MergeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request.Changes) > MaxBatchOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many changes to merge at once"})
+		return
+	}
+
+	// The selections are checked against the worlds as the merge sees them
+	tx := h.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	diff, err := fork.Compare(tx, *forked)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare worlds"})
+		return
+	}
+	origins, err := fork.LoadOrigins(tx, forked.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare worlds"})
+		return
+	}
+	cal, err := loadCalendar(tx, source.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load calendar"})
+		return
+	}
+
+	// Check everything before changing anything
+	type merge struct {
+		change fork.Change
+		event  models.TimelineEvent // As it will be in the source
+		npc    models.NPC
+	}
+	var merges []merge
+	itemErrors := []ItemError{}
+	picked := make(map[*fork.Change]bool)
+	for i, selection := range request.Changes {
+		fail := func(message string) {
+			itemErrors = append(itemErrors, ItemError{Index: i, ID: selectionID(selection), Error: message})
+		}
+
+		found := -1
+		changes := diff.Events
+		if selection.Kind == models.ForkNPC {
+			changes = diff.NPCs
+		}
+		for j, change := range changes {
+			if change.Kind == selection.Kind && change.Change == selection.Change &&
+				sameID(change.ForkID, selection.ForkID) && sameID(change.SourceID, selection.SourceID) {
+				found = j
+				break
+			}
+		}
+		if found < 0 {
+			fail("Not one of the fork's changes, compare the worlds again")
+			continue
+		}
+		if picked[&changes[found]] {
+			fail("Change is selected more than once")
+			continue
+		}
+		picked[&changes[found]] = true
+		change := changes[found]
+		if change.Conflict && !request.Force {
+			fail("The source world changed this too since the fork, merge with force to overwrite it")
+			continue
+		}
+
+		m := merge{change: change}
+		switch {
+		case change.Kind == models.ForkEvent && change.Change != fork.Removed:
+			var forkEvent models.TimelineEvent
+			if err := tx.First(&forkEvent, *change.ForkID).Error; err != nil {
+				fail("Timeline event not found")
+				continue
+			}
+			if change.Change == fork.Changed {
+				if err := tx.First(&m.event, *change.SourceID).Error; err != nil {
+					fail("Timeline event not found in the source world")
+					continue
+				}
+			} else {
+				m.event = models.TimelineEvent{WorldID: source.ID, UserID: &user.ID}
+			}
+			m.event.Title, m.event.Description = forkEvent.Title, forkEvent.Description
+			m.event.StartDate, m.event.EndDate = forkEvent.StartDate, forkEvent.EndDate
+			m.event.EraID, m.event.Era = nil, forkEvent.Era
+			m.event.Importance, m.event.SortOrder, m.event.Details = forkEvent.Importance, forkEvent.SortOrder, forkEvent.Details
+			if err := dateEvent(cal, &m.event); err != nil {
+				fail(err.Error())
+				continue
+			}
+			if err := resolveEventEra(tx, &m.event); err != nil {
+				fail(err.Error())
+				continue
+			}
+		case change.Kind == models.ForkNPC && change.Change != fork.Removed:
+			var forkNPC models.NPC
+			if err := tx.First(&forkNPC, *change.ForkID).Error; err != nil {
+				fail("NPC not found")
+				continue
+			}
+			m.npc = forkNPC
+			m.npc.ID, m.npc.WorldID = 0, source.ID
+			if change.Change == fork.Changed {
+				m.npc.ID = *change.SourceID
+			}
+		}
+		merges = append(merges, m)
+	}
+	if len(itemErrors) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No changes were merged", "errors": itemErrors})
+		return
+	}
+
+	fail := func() {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge changes"})
+	}
+
+	// NPCs go first so events can bring along the NPCs merged with them
+	var merged []models.WorldForkOrigin
+	for _, kind := range []string{models.ForkNPC, models.ForkEvent} {
+		for _, m := range merges {
+			change := m.change
+			if change.Kind != kind {
+				continue
+			}
+
+			if change.Change == fork.Removed {
+				if kind == models.ForkNPC {
+					err = deleteMergedNPC(tx, source.ID, *change.SourceID)
+				} else {
+					err = deleteEvent(tx, &models.TimelineEvent{ID: *change.SourceID})
+				}
+				if err == nil {
+					err = tx.Where("world_id = ? AND kind = ? AND source_id = ?", forked.ID, kind, *change.SourceID).Delete(&models.WorldForkOrigin{}).Error
+				}
+				if err != nil {
+					fail()
+					return
+				}
+				continue
+			}
+
+			var sourceID uint
+			if kind == models.ForkNPC {
+				npc := m.npc
+				npc.LocationID = mergedID(tx, origins, models.ForkLocation, &models.NPCLocation{}, source.ID, npc.LocationID)
+				if change.Change == fork.Changed {
+					err = tx.Model(&npc).Select(mergedNPCColumns).Updates(&npc).Error
+				} else if err = tx.Omit(clause.Associations).Create(&npc).Error; err == nil && !npc.IsAlive {
+					// GORM skips false for is_alive, which defaults to true
+					err = tx.Model(&npc).Update("is_alive", false).Error
+				}
+				sourceID = npc.ID
+			} else {
+				event := m.event
+				if change.Change == fork.Changed {
+					err = tx.Model(&event).Select(mergedEventColumns).Updates(&event).Error
+				} else {
+					event.NPCs, event.OrganizationIDs, event.LocationIDs, err = mergedParticipants(tx, origins, source.ID, *change.ForkID)
+					if err == nil {
+						err = createEvent(tx, &event)
+					}
+				}
+				sourceID = event.ID
+			}
+			if err != nil {
+				fail()
+				return
+			}
+
+			origin := models.WorldForkOrigin{WorldID: forked.ID, Kind: kind, ID: *change.ForkID, SourceID: sourceID}
+			if origins[kind] == nil {
+				origins[kind] = make(map[uint]models.WorldForkOrigin)
+			}
+			origins[kind][origin.ID] = origin
+			merged = append(merged, origin)
+		}
+	}
+
+	if err := assignEventEras(tx, source.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign events to eras"})
+		return
+	}
+
+	// Merged rows now match, which is the new base for later changes
+	for i, origin := range merged {
+		if origin.Kind == models.ForkNPC {
+			var npc models.NPC
+			err = tx.First(&npc, origin.SourceID).Error
+			merged[i].Base = fork.NPCFingerprint(npc)
+		} else {
+			var event models.TimelineEvent
+			err = tx.First(&event, origin.SourceID).Error
+			merged[i].Base = fork.EventFingerprint(event)
+		}
+		if err != nil {
+			fail()
+			return
+		}
+	}
+	if len(merged) > 0 {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&merged).Error; err != nil {
+			fail()
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge changes"})
+		return
+	}
+
+	diff, err = fork.Compare(h.DB, *forked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare worlds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"merged": len(merges), "diff": diff})
+}
+
+// Loads the fork in the URL and the world it came from, both visible to the
+// current user, writing the error response otherwise
+func (h *WorldHandler) forkAndSource(c *gin.Context) (*models.World, *models.World, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
+		return nil, nil, false
+	}
+
+	user, _ := middleware.GetCurrentUser(c)
+	forked, err := findVisibleWorld(h.DB, user, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found"})
+		return nil, nil, false
+	}
+	if forked.ForkedFromID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This world is not a fork"})
+		return nil, nil, false
+	}
+	source, err := findVisibleWorld(h.DB, user, int(*forked.ForkedFromID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The world this was forked from no longer exists"})
+		return nil, nil, false
+	}
+	return forked, source, true
+}
+
+// The source world's ID for a fork row of a kind, when it came from or was
+// merged into a row the source still has
+func mergedID(tx *gorm.DB, origins fork.Origins, kind string, model interface{}, sourceWorldID uint, forkID *uint) *uint {
+	if forkID == nil {
+		return nil
+	}
+	sourceID, ok := origins.Source(kind, *forkID)
+	if !ok {
+		return nil
+	}
+	var count int64
+	if tx.Model(model).Where("id = ? AND world_id = ?", sourceID, sourceWorldID).Count(&count); count == 0 {
+		return nil
+	}
+	return &sourceID
+}
+
+// A fork event's participants as the source world knows them, leaving out
+// any the source doesn't have
+func mergedParticipants(tx *gorm.DB, origins fork.Origins, sourceWorldID, eventID uint) ([]models.TimelineEventNPC, []uint, []uint, error) {
+	var event models.TimelineEvent
+	if err := tx.Preload("NPCs").Preload("Organizations").Preload("Locations").First(&event, eventID).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	npcs := []models.TimelineEventNPC{}
+	for _, participant := range event.NPCs {
+		if id := mergedID(tx, origins, models.ForkNPC, &models.NPC{}, sourceWorldID, &participant.NPCID); id != nil {
+			npcs = append(npcs, models.TimelineEventNPC{NPCID: *id, Role: participant.Role})
+		}
+	}
+	organizationIDs := []uint{}
+	for _, organization := range event.Organizations {
+		if id := mergedID(tx, origins, models.ForkOrganization, &models.Organization{}, sourceWorldID, &organization.ID); id != nil {
+			organizationIDs = append(organizationIDs, *id)
+		}
+	}
+	locationIDs := []uint{}
+	for _, location := range event.Locations {
+		if id := mergedID(tx, origins, models.ForkLocation, &models.NPCLocation{}, sourceWorldID, &location.ID); id != nil {
+			locationIDs = append(locationIDs, *id)
+		}
+	}
+	return npcs, organizationIDs, locationIDs, nil
+}
+
+// Deletes an NPC the way DELETE /worlds/:id/npcs/:npcId does
+func deleteMergedNPC(tx *gorm.DB, worldID, npcID uint) error {
+	if err := tx.Exec("DELETE FROM timeline_event_npcs WHERE npc_id = ?", npcID).Error; err != nil {
+		return err
+	}
+	return tx.Where("world_id = ? AND id = ?", worldID, npcID).Delete(&models.NPC{}).Error
+}
+
+func sameID(a, b *uint) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func selectionID(selection MergeSelection) uint {
+	if selection.ForkID != nil {
+		return *selection.ForkID
+	}
+	if selection.SourceID != nil {
+		return *selection.SourceID
+	}
+	return 0
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/naetharu/rpg-api/internal/middleware"
 	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/query"
 	"gorm.io/gorm"
 )
 
@@ -18,28 +19,47 @@ func NewWorldHandler(db *gorm.DB) *WorldHandler {
 	return &WorldHandler{DB: db}
 }
 
+// Sorting and filters for GET /worlds
+var worldList = query.List{
+	Sorts:        map[string]string{"title": "title", "created_at": "created_at", "updated_at": "updated_at"},
+	DefaultSort:  "created_at",
+	DefaultOrder: "desc",
+	Filters: map[string]query.Filter{
+		"genre":       {Column: "genres", Kind: query.Contains},
+		"age_rating":  {Column: "age_rating", Kind: query.Equal},
+		"is_official": {Column: "is_official", Kind: query.Bool},
+	},
+}
+
 // GET /worlds
 func (h *WorldHandler) GetWorlds(c *gin.Context) {
+	params, err := worldList.Parse(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var worlds []models.World
 
 	// Get current user (may be nil for unauthenticated requests)
 	user, _ := middleware.GetCurrentUser(c)
 
-	query := h.DB.Preload("User")
+	db := h.DB
 
 	// If user is not authenticated or not admin, only show official/reviewed content and user's own content
 	if user == nil {
-		query = query.Where("is_official = ? OR reviewed = ?", true, true)
+		db = db.Where("is_official = ? OR reviewed = ?", true, true)
 	} else if !user.IsAdmin {
-		query = query.Where("is_official = ? OR reviewed = ? OR user_id = ?", true, true, user.ID)
+		db = db.Where("is_official = ? OR reviewed = ? OR user_id = ?", true, true, user.ID)
 	}
 
-	if err := query.Find(&worlds).Error; err != nil {
+	meta, err := params.Find(db, &worlds, "User")
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch worlds"})
 		return
 	}
 
-	c.JSON(http.StatusOK, worlds)
+	c.JSON(http.StatusOK, query.Response(worlds, meta))
 }
 
 // GET /worlds/:id
@@ -92,6 +112,8 @@ func (h *WorldHandler) CreateWorld(c *gin.Context) {
 		world.IsOfficial = false
 		world.Reviewed = false
 	}
+	// Only POST /worlds/:id/fork makes forks
+	world.ForkedFromID, world.ForkedAt = nil, nil
 
 	if err := h.DB.Create(&world).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create world"})
@@ -141,6 +163,7 @@ func (h *WorldHandler) UpdateWorld(c *gin.Context) {
 		updates.IsOfficial = world.IsOfficial
 		updates.Reviewed = world.Reviewed
 	}
+	updates.ForkedFromID, updates.ForkedAt = nil, nil
 
 	if err := h.DB.Model(&world).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update world"})
@@ -178,39 +201,46 @@ func (h *WorldHandler) DeleteWorld(c *gin.Context) {
 		return
 	}
 
-	// Start a transaction to ensure all deletes succeed or none do
-	tx := h.DB.Begin()
-	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+	// Soft delete only - timeline, eras and NPCs are removed when the trash is purged
+	if err := h.DB.Delete(&world).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete world"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "World moved to trash"})
+}
+
+// POST /worlds/:id/restore
+func (h *WorldHandler) RestoreWorld(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid world ID"})
 		return
 	}
 
-	// Delete timeline events first
-	if err := tx.Where("world_id = ?", id).Delete(&models.TimelineEvent{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete timeline events"})
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 		return
 	}
 
-	// Delete world eras
-	if err := tx.Where("world_id = ?", id).Delete(&models.WorldEra{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete world eras"})
+	var world models.World
+	if err := h.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&world).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "World not found in trash"})
 		return
 	}
 
-	// Finally delete the world
-	if err := tx.Delete(&world).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete world"})
+	// Check if user owns this world or is admin
+	if world.UserID == nil || *world.UserID != user.ID && !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+	if err := h.DB.Unscoped().Model(&world).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore world"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "World deleted successfully"})
+	h.DB.Preload("User").First(&world, world.ID)
+	c.JSON(http.StatusOK, world)
 }
@@ -0,0 +1,175 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/services"
+	"gorm.io/gorm"
+)
+
+// Default number of hours an unreferenced upload is kept before it is deleted
+const DefaultImageGraceHours = 48
+
+// Every column holding an image ID. Soft-deleted rows still count, the trash
+// purger deletes their images.
+const referencedImageIDs = `
+	SELECT image_id FROM assets WHERE image_id <> ''
+	UNION SELECT banner_image_id FROM adventures WHERE banner_image_id <> ''
+	UNION SELECT card_image_id FROM adventures WHERE card_image_id <> ''
+	UNION SELECT banner_image_id FROM title_pages WHERE banner_image_id <> ''
+	UNION SELECT image_id FROM scenes WHERE image_id <> ''
+	UNION SELECT banner_image_id FROM worlds WHERE banner_image_id <> ''
+	UNION SELECT card_image_id FROM worlds WHERE card_image_id <> ''
+	UNION SELECT image_id FROM timeline_events WHERE image_id <> ''
+	UNION SELECT image_id FROM lore_articles WHERE image_id <> ''
+	UNION SELECT cover_image_id FROM stories WHERE cover_image_id <> ''`
+
+// ImageCollector deletes uploaded images that no content references. An
+// image is only collected once it has gone unreferenced for the whole grace
+// period, counted from its upload or from the last time it was in use, so
+// forms still being filled in and recently replaced images are left alone.
+// Images uploaded before uploads were recorded are never touched.
+type ImageCollector struct {
+	DB          *gorm.DB
+	Images      services.ImageStore
+	GracePeriod time.Duration
+	Interval    time.Duration
+}
+
+// OrphanedImage is an upload the collector would delete
+type OrphanedImage struct {
+	ImageID      string     `json:"image_id"`
+	UserID       *uint      `json:"user_id"`
+	Filename     string     `json:"filename"`
+	UploadedAt   time.Time  `json:"uploaded_at"`
+	ReferencedAt *time.Time `json:"referenced_at"`
+}
+
+// ImageGCReport describes one collection run. On a dry run nothing is
+// deleted and Deleted stays zero.
+type ImageGCReport struct {
+	DryRun      bool            `json:"dry_run"`
+	GraceHours  int             `json:"grace_hours"`
+	Tracked     int64           `json:"tracked"`
+	Referenced  int64           `json:"referenced"`
+	Orphaned    []OrphanedImage `json:"orphaned"`
+	Deleted     int             `json:"deleted"`
+	Failed      []string        `json:"failed"`
+	StartedAt   time.Time       `json:"started_at"`
+	CompletedAt time.Time       `json:"completed_at"`
+}
+
+func NewImageCollector(db *gorm.DB, images services.ImageStore) *ImageCollector {
+	return &ImageCollector{
+		DB:          db,
+		Images:      images,
+		GracePeriod: ImageGracePeriod(),
+		Interval:    6 * time.Hour,
+	}
+}
+
+// ImageGracePeriod reads IMAGE_GC_GRACE_HOURS, falling back to the default
+func ImageGracePeriod() time.Duration {
+	hours := DefaultImageGraceHours
+	if value, err := strconv.Atoi(os.Getenv("IMAGE_GC_GRACE_HOURS")); err == nil && value > 0 {
+		hours = value
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// Start runs a collection immediately and then on every interval in the background
+func (g *ImageCollector) Start() {
+	go func() {
+		g.run()
+
+		ticker := time.NewTicker(g.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			g.run()
+		}
+	}()
+}
+
+func (g *ImageCollector) run() {
+	report, err := g.Collect(false)
+	if err != nil {
+		log.Printf("Warning: Image collection failed: %v", err)
+		return
+	}
+	if report.Deleted > 0 || len(report.Failed) > 0 {
+		log.Printf("Image collection deleted %d orphaned images, %d failed", report.Deleted, len(report.Failed))
+	}
+}
+
+// Collect marks every upload still in use as referenced, then deletes the
+// uploads that have been unreferenced for longer than the grace period.
+// With dryRun set it only reports what would be deleted.
+func (g *ImageCollector) Collect(dryRun bool) (*ImageGCReport, error) {
+	now := time.Now()
+	cutoff := now.Add(-g.GracePeriod)
+	report := &ImageGCReport{
+		DryRun:     dryRun,
+		GraceHours: int(g.GracePeriod / time.Hour),
+		Orphaned:   []OrphanedImage{},
+		Failed:     []string{},
+		StartedAt:  now,
+	}
+
+	if err := g.DB.Model(&models.Upload{}).Count(&report.Tracked).Error; err != nil {
+		return nil, err
+	}
+
+	// A dry run must not change anything, so it only counts references
+	referenced := g.DB.Model(&models.Upload{}).Where("image_id IN (" + referencedImageIDs + ")")
+	if dryRun {
+		if err := referenced.Count(&report.Referenced).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		result := referenced.Update("referenced_at", now)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		report.Referenced = result.RowsAffected
+	}
+
+	var orphans []models.Upload
+	if err := g.DB.Where("image_id NOT IN ("+referencedImageIDs+")").
+		Where("created_at < ? AND (referenced_at IS NULL OR referenced_at < ?)", cutoff, cutoff).
+		Order("created_at").
+		Find(&orphans).Error; err != nil {
+		return nil, err
+	}
+
+	for _, upload := range orphans {
+		report.Orphaned = append(report.Orphaned, OrphanedImage{
+			ImageID:      upload.ImageID,
+			UserID:       upload.UserID,
+			Filename:     upload.Filename,
+			UploadedAt:   upload.CreatedAt,
+			ReferencedAt: upload.ReferencedAt,
+		})
+	}
+
+	if !dryRun {
+		for _, upload := range orphans {
+			if err := g.Images.Delete(upload.ImageID); err != nil {
+				// The row stays so the next run tries again
+				log.Printf("Warning: Failed to delete orphaned image %s: %v", upload.ImageID, err)
+				report.Failed = append(report.Failed, upload.ImageID)
+				continue
+			}
+			if err := g.DB.Delete(&upload).Error; err != nil {
+				log.Printf("Warning: Failed to remove upload record for %s: %v", upload.ImageID, err)
+			}
+			report.Deleted++
+		}
+	}
+
+	report.CompletedAt = time.Now()
+	return report, nil
+}
@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/services"
+	"github.com/naetharu/rpg-api/internal/testdb"
+)
+
+func TestImageCollector(t *testing.T) {
+	db := testdb.Open(t,
+		&models.Upload{}, &models.Asset{}, &models.Adventure{}, &models.TitlePage{}, &models.Scene{},
+		&models.World{}, &models.TimelineEvent{}, &models.LoreArticle{}, &models.Story{})
+	store := services.NewMemoryImageStore()
+	collector := &ImageCollector{DB: db, Images: store, GracePeriod: 48 * time.Hour}
+
+	upload := func(age time.Duration) string {
+		t.Helper()
+		stored, err := store.Upload(bytes.NewReader([]byte("image")), "image.png", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&models.Upload{ImageID: stored.ID, CreatedAt: time.Now().Add(-age)}).Error; err != nil {
+			t.Fatal(err)
+		}
+		return stored.ID
+	}
+	orphan := upload(72 * time.Hour)
+	recent := upload(time.Hour)
+	used := upload(72 * time.Hour)
+	if err := db.Create(&models.Asset{Name: "Map", ImageID: used}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Recorded but already gone from the store, so deleting it fails
+	if err := db.Create(&models.Upload{ImageID: "missing", CreatedAt: time.Now().Add(-72 * time.Hour)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := collector.Collect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Tracked != 4 || report.Referenced != 1 || len(report.Orphaned) != 2 || report.Deleted != 0 {
+		t.Errorf("Dry run reported %+v", report)
+	}
+	if !store.Has(orphan) {
+		t.Fatal("Dry run deleted an image")
+	}
+
+	report, err = collector.Collect(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deleted != 1 || len(report.Failed) != 1 || report.Failed[0] != "missing" {
+		t.Errorf("Collection reported %+v", report)
+	}
+	if store.Has(orphan) {
+		t.Error("Orphaned image was kept")
+	}
+	if !store.Has(recent) || !store.Has(used) {
+		t.Error("An image in use or inside the grace period was deleted")
+	}
+
+	var left []string
+	db.Model(&models.Upload{}).Order("image_id").Pluck("image_id", &left)
+	if want := []string{recent, used, "missing"}; len(left) != 3 || left[0] != want[0] || left[1] != want[1] || left[2] != want[2] {
+		t.Errorf("Upload records left %v, want %v", left, want)
+	}
+	var referenced models.Upload
+	db.Where("image_id = ?", used).First(&referenced)
+	if referenced.ReferencedAt == nil {
+		t.Error("Image in use was not marked as referenced")
+	}
+
+	// Once the asset is purged and the grace period has passed, its image goes too
+	db.Unscoped().Where("image_id = ?", used).Delete(&models.Asset{})
+	db.Model(&models.Upload{}).Where("image_id = ?", used).Update("referenced_at", time.Now().Add(-72*time.Hour))
+	if _, err := collector.Collect(false); err != nil {
+		t.Fatal(err)
+	}
+	if store.Has(used) {
+		t.Error("Image no longer in use was kept")
+	}
+}
@@ -0,0 +1,326 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/services"
+	"gorm.io/gorm"
+)
+
+// Default number of days soft-deleted content stays in the trash
+const DefaultTrashRetentionDays = 30
+
+// TrashPurger permanently removes content that has been in the trash longer
+// than the retention period. It is the only place that performs the full
+// cascade and deletes images from the image store.
+type TrashPurger struct {
+	DB        *gorm.DB
+	Images    services.ImageStore
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+func NewTrashPurger(db *gorm.DB, images services.ImageStore) *TrashPurger {
+	return &TrashPurger{
+		DB:        db,
+		Images:    images,
+		Retention: TrashRetention(),
+		Interval:  time.Hour,
+	}
+}
+
+// TrashRetention reads TRASH_RETENTION_DAYS, falling back to the default
+func TrashRetention() time.Duration {
+	days := DefaultTrashRetentionDays
+	if value, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && value > 0 {
+		days = value
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Start runs the purge immediately and then on every interval in the background
+func (p *TrashPurger) Start() {
+	go func() {
+		p.PurgeExpired()
+
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			p.PurgeExpired()
+		}
+	}()
+}
+
+// PurgeExpired permanently deletes everything trashed before the retention cutoff
+func (p *TrashPurger) PurgeExpired() {
+	cutoff := time.Now().Add(-p.Retention)
+
+	var adventures []models.Adventure
+	if err := p.DB.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&adventures).Error; err != nil {
+		log.Printf("Warning: Failed to load expired adventures: %v", err)
+	}
+	for _, adventure := range adventures {
+		if err := p.PurgeAdventure(adventure); err != nil {
+			log.Printf("Warning: Failed to purge adventure %d: %v", adventure.ID, err)
+		}
+	}
+
+	var worlds []models.World
+	if err := p.DB.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&worlds).Error; err != nil {
+		log.Printf("Warning: Failed to load expired worlds: %v", err)
+	}
+	for _, world := range worlds {
+		if err := p.PurgeWorld(world); err != nil {
+			log.Printf("Warning: Failed to purge world %d: %v", world.ID, err)
+		}
+	}
+
+	var assets []models.Asset
+	if err := p.DB.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&assets).Error; err != nil {
+		log.Printf("Warning: Failed to load expired assets: %v", err)
+	}
+	for _, asset := range assets {
+		if err := p.PurgeAsset(asset); err != nil {
+			log.Printf("Warning: Failed to purge asset %d: %v", asset.ID, err)
+		}
+	}
+}
+
+// PurgeAdventure deletes an adventure with its episodes, scenes, title page, epilogue
+// and play history
+func (p *TrashPurger) PurgeAdventure(adventure models.Adventure) error {
+	id := adventure.ID
+
+	// Start a transaction to ensure all deletes succeed or none do
+	tx := p.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	// Collect all Cloudflare image IDs that need to be deleted
+	var imageIDsToDelete []string
+
+	// Adventure images
+	if adventure.BannerImageID != "" {
+		imageIDsToDelete = append(imageIDsToDelete, adventure.BannerImageID)
+	}
+	if adventure.CardImageID != "" {
+		imageIDsToDelete = append(imageIDsToDelete, adventure.CardImageID)
+	}
+
+	// Title page images
+	var titlePage models.TitlePage
+	if err := tx.Where("adventure_id = ?", id).First(&titlePage).Error; err == nil {
+		if titlePage.BannerImageID != "" {
+			imageIDsToDelete = append(imageIDsToDelete, titlePage.BannerImageID)
+		}
+	}
+
+	// Scene images
+	var scenes []models.Scene
+	if err := tx.Joins("JOIN episodes ON scenes.episode_id = episodes.id").
+		Where("episodes.adventure_id = ?", id).Find(&scenes).Error; err == nil {
+		for _, scene := range scenes {
+			if scene.ImageID != "" {
+				imageIDsToDelete = append(imageIDsToDelete, scene.ImageID)
+			}
+		}
+	}
+
+	steps := []struct {
+		description string
+		sql         string
+	}{
+		{"dice rolls", "DELETE FROM dice_rolls WHERE session_id IN (SELECT id FROM play_sessions WHERE adventure_id = ?)"},
+		{"play session events", "DELETE FROM play_session_events WHERE session_id IN (SELECT id FROM play_sessions WHERE adventure_id = ?)"},
+		{"play session players", "DELETE FROM play_session_players WHERE session_id IN (SELECT id FROM play_sessions WHERE adventure_id = ?)"},
+		{"play sessions", "DELETE FROM play_sessions WHERE adventure_id = ?"},
+		{"scene associations", "DELETE FROM scene_assets WHERE scene_id IN (SELECT id FROM scenes WHERE episode_id IN (SELECT id FROM episodes WHERE adventure_id = ?))"},
+		{"scenes", "DELETE FROM scenes WHERE episode_id IN (SELECT id FROM episodes WHERE adventure_id = ?)"},
+		{"episodes", "DELETE FROM episodes WHERE adventure_id = ?"},
+		{"title page", "DELETE FROM title_pages WHERE adventure_id = ?"},
+		{"epilogue outcomes", "DELETE FROM epilogue_outcomes WHERE epilogue_id IN (SELECT id FROM epilogues WHERE adventure_id = ?)"},
+		{"follow up hooks", "DELETE FROM follow_up_hooks WHERE epilogue_id IN (SELECT id FROM epilogues WHERE adventure_id = ?)"},
+		{"epilogue", "DELETE FROM epilogues WHERE adventure_id = ?"},
+		{"adventure associations", "DELETE FROM adventure_assets WHERE adventure_id = ?"},
+	}
+	for _, step := range steps {
+		if err := tx.Exec(step.sql, id).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete %s: %w", step.description, err)
+		}
+	}
+
+	// Finally delete the adventure itself, bypassing the soft delete
+	if err := tx.Unscoped().Delete(&adventure).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete adventure: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	p.deleteImages(adventure.UserID, imageIDsToDelete)
+	return nil
+}
+
+// PurgeWorld deletes a world with its timeline, eras, lore, stories and
+// generated population
+func (p *TrashPurger) PurgeWorld(world models.World) error {
+	id := world.ID
+
+	tx := p.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	var imageIDsToDelete []string
+	if world.BannerImageID != "" {
+		imageIDsToDelete = append(imageIDsToDelete, world.BannerImageID)
+	}
+	if world.CardImageID != "" {
+		imageIDsToDelete = append(imageIDsToDelete, world.CardImageID)
+	}
+
+	var events []models.TimelineEvent
+	if err := tx.Where("world_id = ?", id).Find(&events).Error; err == nil {
+		for _, event := range events {
+			if event.ImageID != "" {
+				imageIDsToDelete = append(imageIDsToDelete, event.ImageID)
+			}
+		}
+	}
+
+	var articleImageIDs []string
+	if err := tx.Model(&models.LoreArticle{}).Where("world_id = ? AND image_id <> ''", id).Pluck("image_id", &articleImageIDs).Error; err == nil {
+		imageIDsToDelete = append(imageIDsToDelete, articleImageIDs...)
+	}
+
+	var coverImageIDs []string
+	if err := tx.Model(&models.Story{}).Where("world_id = ? AND cover_image_id <> ''", id).Pluck("cover_image_id", &coverImageIDs).Error; err == nil {
+		imageIDsToDelete = append(imageIDsToDelete, coverImageIDs...)
+	}
+
+	// Children are removed before the rows they reference
+	steps := []struct {
+		description string
+		sql         string
+	}{
+		{"fork origins", "DELETE FROM world_fork_origins WHERE world_id = ?"},
+		{"story timeline events", "DELETE FROM story_timeline_events WHERE story_id IN (SELECT id FROM stories WHERE world_id = ?)"},
+		{"story chapters", "DELETE FROM story_chapters WHERE story_id IN (SELECT id FROM stories WHERE world_id = ?)"},
+		{"stories", "DELETE FROM stories WHERE world_id = ?"},
+		{"lore links", "DELETE FROM lore_links WHERE world_id = ?"},
+		{"lore articles", "DELETE FROM lore_articles WHERE world_id = ?"},
+		{"timeline event links", "DELETE FROM timeline_event_links WHERE world_id = ?"},
+		{"timeline event NPCs", "DELETE FROM timeline_event_npcs WHERE timeline_event_id IN (SELECT id FROM timeline_events WHERE world_id = ?)"},
+		{"timeline event organizations", "DELETE FROM timeline_event_organizations WHERE timeline_event_id IN (SELECT id FROM timeline_events WHERE world_id = ?)"},
+		{"timeline event locations", "DELETE FROM timeline_event_locations WHERE timeline_event_id IN (SELECT id FROM timeline_events WHERE world_id = ?)"},
+		{"timeline events", "DELETE FROM timeline_events WHERE world_id = ?"},
+		{"world eras", "DELETE FROM world_eras WHERE world_id = ?"},
+		{"calendar", "DELETE FROM world_calendars WHERE world_id = ?"},
+		{"NPC relationships", "DELETE FROM npc_relationships WHERE world_id = ?"},
+		{"organization memberships", "DELETE FROM organization_memberships WHERE organization_id IN (SELECT id FROM organizations WHERE world_id = ?)"},
+		{"NPCs", "DELETE FROM npcs WHERE world_id = ?"},
+		{"organization ranks", "DELETE FROM organization_ranks WHERE organization_id IN (SELECT id FROM organizations WHERE world_id = ?)"},
+		{"organizations", "DELETE FROM organizations WHERE world_id = ?"},
+		{"locations", "DELETE FROM npc_locations WHERE world_id = ?"},
+		{"generation configs", "DELETE FROM npc_generation_configs WHERE world_id = ?"},
+	}
+	for _, step := range steps {
+		if err := tx.Exec(step.sql, id).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete %s: %w", step.description, err)
+		}
+	}
+
+	if err := tx.Unscoped().Delete(&world).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete world: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	p.deleteImages(world.UserID, imageIDsToDelete)
+	return nil
+}
+
+// PurgeAsset detaches an asset from scenes, adventures, collections and tags and deletes it
+func (p *TrashPurger) PurgeAsset(asset models.Asset) error {
+	tx := p.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	steps := []struct {
+		description string
+		sql         string
+	}{
+		{"scene associations", "DELETE FROM scene_assets WHERE asset_id = ?"},
+		{"adventure associations", "DELETE FROM adventure_assets WHERE asset_id = ?"},
+		{"collection items", "DELETE FROM collection_items WHERE asset_id = ?"},
+		{"asset tags", "DELETE FROM asset_tags WHERE asset_id = ?"},
+	}
+
+	for _, step := range steps {
+		if err := tx.Exec(step.sql, asset.ID).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete %s: %w", step.description, err)
+		}
+	}
+	if err := tx.Unscoped().Delete(&asset).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete asset: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	if asset.ImageID != "" {
+		p.deleteImages(asset.UserID, []string{asset.ImageID})
+	}
+	return nil
+}
+
+// Images are removed only after the database rows are gone, so a failed
+// commit never leaves content pointing at deleted images. Image IDs on
+// content are set by clients, so only images the owner uploaded are deleted,
+// and only once nothing else uses them. The image collector deals with the
+// rest.
+func (p *TrashPurger) deleteImages(ownerID *uint, imageIDs []string) {
+	if len(imageIDs) == 0 {
+		return
+	}
+
+	owned := p.DB.Model(&models.Upload{}).
+		Where("image_id IN ?", imageIDs).
+		Where("image_id NOT IN (" + referencedImageIDs + ")")
+	if ownerID != nil {
+		owned = owned.Where("user_id = ?", *ownerID)
+	} else {
+		owned = owned.Where("user_id IS NULL")
+	}
+	var unused []string
+	if err := owned.Pluck("image_id", &unused).Error; err != nil {
+		log.Printf("Warning: Failed to check images for deletion: %v", err)
+		return
+	}
+
+	for _, imageID := range unused {
+		if err := p.Images.Delete(imageID); err != nil {
+			// Log the error but don't fail the purge
+			log.Printf("Warning: Failed to delete image %s: %v", imageID, err)
+			continue
+		}
+		p.DB.Where("image_id = ?", imageID).Delete(&models.Upload{})
+	}
+}
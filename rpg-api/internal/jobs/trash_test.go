@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/services"
+	"github.com/naetharu/rpg-api/internal/testdb"
+)
+
+func TestPurgeAssetImages(t *testing.T) {
+	db := testdb.Open(t,
+		&models.Upload{}, &models.Asset{}, &models.Adventure{}, &models.TitlePage{}, &models.Scene{},
+		&models.World{}, &models.TimelineEvent{}, &models.LoreArticle{}, &models.Story{},
+		&models.CollectionItem{}, &models.AssetTag{})
+	store := services.NewMemoryImageStore()
+	purger := &TrashPurger{DB: db, Images: store}
+
+	victim, owner := uint(1), uint(2)
+	upload := func(userID uint) string {
+		t.Helper()
+		stored, err := store.Upload(bytes.NewReader([]byte("image")), "image.png", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&models.Upload{ImageID: stored.ID, UserID: &userID}).Error; err != nil {
+			t.Fatal(err)
+		}
+		return stored.ID
+	}
+	purge := func(imageID string) {
+		t.Helper()
+		asset := models.Asset{Name: "Trashed", ImageID: imageID, UserID: &owner}
+		if err := db.Create(&asset).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := purger.PurgeAsset(asset); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Someone else's image copied onto the purged asset
+	borrowed := upload(victim)
+	purge(borrowed)
+	if !store.Has(borrowed) {
+		t.Error("Purge deleted an image another user uploaded")
+	}
+
+	// The owner's image, still used elsewhere
+	shared := upload(owner)
+	if err := db.Create(&models.Asset{Name: "Kept", ImageID: shared, UserID: &victim}).Error; err != nil {
+		t.Fatal(err)
+	}
+	purge(shared)
+	if !store.Has(shared) {
+		t.Error("Purge deleted an image still in use")
+	}
+
+	// The owner's image used nowhere else
+	own := upload(owner)
+	purge(own)
+	if store.Has(own) {
+		t.Error("Purge kept the owner's unused image")
+	}
+	var left []string
+	db.Model(&models.Upload{}).Order("image_id").Pluck("image_id", &left)
+	if len(left) != 2 || left[0] != borrowed || left[1] != shared {
+		t.Errorf("Upload records left %v, want %v", left, []string{borrowed, shared})
+	}
+}
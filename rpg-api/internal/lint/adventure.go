@@ -0,0 +1,358 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/naetharu/rpg-api/internal/models"
+)
+
+// Severity levels, from most to least serious
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// EntityRef points at the piece of content a finding is about
+type EntityRef struct {
+	Type      string `json:"type"` // "adventure", "episode", "scene", "epilogue", "outcome", "asset"
+	ID        uint   `json:"id"`
+	EpisodeID *uint  `json:"episode_id,omitempty"` // Set for scenes so the UI can build a link
+}
+
+type Finding struct {
+	Severity string    `json:"severity"`
+	Code     string    `json:"code"`
+	Message  string    `json:"message"`
+	Entity   EntityRef `json:"entity"`
+}
+
+type Report struct {
+	AdventureID uint      `json:"adventure_id"`
+	Errors      int       `json:"errors"`
+	Warnings    int       `json:"warnings"`
+	Info        int       `json:"info"`
+	Findings    []Finding `json:"findings"`
+}
+
+// AdventureInput is everything the linter needs, loaded up front by the caller.
+// Assets must include soft-deleted rows so they can be reported as deleted.
+type AdventureInput struct {
+	Adventure         models.Adventure
+	SceneAssetIDs     map[uint][]uint // scene ID -> referenced asset IDs
+	AdventureAssetIDs []uint
+	Assets            map[uint]models.Asset
+}
+
+// Age ratings ordered from least to most restrictive
+var ageRatingRank = map[string]int{
+	"For Everyone": 0,
+	"Teen":         1,
+	"Adult":        2,
+}
+
+// Genres that require at least the given age rating
+var minimumAgeRatingByGenre = map[string]string{
+	"horror":           "Teen",
+	"cosmic horror":    "Teen",
+	"post-apocalyptic": "Teen",
+	"grimdark":         "Teen",
+	"war":              "Teen",
+	"gore":             "Adult",
+	"mature":           "Adult",
+	"adult":            "Adult",
+}
+
+// LintAdventure runs every check and returns the findings sorted by severity
+func LintAdventure(input AdventureInput) Report {
+	adventure := input.Adventure
+	var findings []Finding
+
+	findings = append(findings, checkEpisodes(adventure)...)
+	findings = append(findings, checkEpilogue(adventure)...)
+	findings = append(findings, checkAssets(input)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank(findings[i].Severity) < severityRank(findings[j].Severity)
+	})
+
+	report := Report{
+		AdventureID: adventure.ID,
+		Findings:    []Finding{},
+	}
+	for _, finding := range findings {
+		switch finding.Severity {
+		case SeverityError:
+			report.Errors++
+		case SeverityWarning:
+			report.Warnings++
+		default:
+			report.Info++
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+
+	return report
+}
+
+func checkEpisodes(adventure models.Adventure) []Finding {
+	var findings []Finding
+	adventureRef := EntityRef{Type: "adventure", ID: adventure.ID}
+
+	if len(adventure.Episodes) == 0 {
+		findings = append(findings, Finding{
+			Severity: SeverityError,
+			Code:     "no_episodes",
+			Message:  "Adventure has no episodes",
+			Entity:   adventureRef,
+		})
+	}
+
+	episodeOrders := make([]orderedItem, len(adventure.Episodes))
+	for i, episode := range adventure.Episodes {
+		episodeOrders[i] = orderedItem{ID: episode.ID, Order: episode.Order}
+	}
+	findings = append(findings, checkOrder(episodeOrders, "episode", nil)...)
+
+	for _, episode := range adventure.Episodes {
+		episodeID := episode.ID
+		episodeRef := EntityRef{Type: "episode", ID: episode.ID}
+
+		if len(episode.Scenes) == 0 {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Code:     "episode_no_scenes",
+				Message:  fmt.Sprintf("Episode %q has no scenes", episode.Title),
+				Entity:   episodeRef,
+			})
+			continue
+		}
+
+		sceneOrders := make([]orderedItem, len(episode.Scenes))
+		for i, scene := range episode.Scenes {
+			sceneOrders[i] = orderedItem{ID: scene.ID, Order: scene.Order}
+		}
+		findings = append(findings, checkOrder(sceneOrders, "scene", &episodeID)...)
+
+		for _, scene := range episode.Scenes {
+			sceneRef := EntityRef{Type: "scene", ID: scene.ID, EpisodeID: &episodeID}
+
+			if strings.TrimSpace(scene.Prose) == "" {
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Code:     "scene_empty_prose",
+					Message:  fmt.Sprintf("Scene %q has no read-aloud prose", scene.Title),
+					Entity:   sceneRef,
+				})
+			}
+			if scene.ImageID == "" && scene.ImageURL == "" {
+				findings = append(findings, Finding{
+					Severity: SeverityInfo,
+					Code:     "scene_missing_image",
+					Message:  fmt.Sprintf("Scene %q has no image", scene.Title),
+					Entity:   sceneRef,
+				})
+			}
+		}
+	}
+
+	return findings
+}
+
+type orderedItem struct {
+	ID    uint
+	Order int
+}
+
+// Orders are expected to run 1..n with no duplicates or gaps
+func checkOrder(items []orderedItem, entityType string, episodeID *uint) []Finding {
+	var findings []Finding
+	if len(items) == 0 {
+		return findings
+	}
+
+	sorted := make([]orderedItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+
+	expected := 1
+	for i, item := range sorted {
+		ref := EntityRef{Type: entityType, ID: item.ID, EpisodeID: episodeID}
+
+		if i > 0 && item.Order == sorted[i-1].Order {
+			findings = append(findings, Finding{
+				Severity: SeverityError,
+				Code:     "duplicate_order",
+				Message:  fmt.Sprintf("More than one %s has order %d", entityType, item.Order),
+				Entity:   ref,
+			})
+			continue
+		}
+
+		if item.Order != expected {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Code:     "order_gap",
+				Message:  fmt.Sprintf("Expected %s order %d but found %d", entityType, expected, item.Order),
+				Entity:   ref,
+			})
+		}
+		expected = item.Order + 1
+	}
+
+	return findings
+}
+
+func checkEpilogue(adventure models.Adventure) []Finding {
+	var findings []Finding
+
+	epilogue := adventure.Epilogue
+	if epilogue == nil {
+		return append(findings, Finding{
+			Severity: SeverityWarning,
+			Code:     "missing_epilogue",
+			Message:  "Adventure has no epilogue",
+			Entity:   EntityRef{Type: "adventure", ID: adventure.ID},
+		})
+	}
+
+	epilogueRef := EntityRef{Type: "epilogue", ID: epilogue.ID}
+
+	for _, outcome := range epilogue.Outcomes {
+		if strings.TrimSpace(outcome.Title) == "" || strings.TrimSpace(outcome.Description) == "" {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Code:     "empty_outcome",
+				Message:  "Epilogue outcome is missing a title or description",
+				Entity:   EntityRef{Type: "outcome", ID: outcome.ID},
+			})
+		}
+	}
+
+	var missing []string
+	if strings.TrimSpace(epilogue.Credits.Designer) == "" {
+		missing = append(missing, "designer")
+	}
+	if strings.TrimSpace(epilogue.Credits.System) == "" {
+		missing = append(missing, "system")
+	}
+	if strings.TrimSpace(epilogue.Credits.Version) == "" {
+		missing = append(missing, "version")
+	}
+	if strings.TrimSpace(epilogue.Credits.Year) == "" {
+		missing = append(missing, "year")
+	}
+	if len(missing) > 0 {
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Code:     "missing_credits",
+			Message:  "Credits are missing: " + strings.Join(missing, ", "),
+			Entity:   epilogueRef,
+		})
+	}
+
+	return findings
+}
+
+func checkAssets(input AdventureInput) []Finding {
+	var findings []Finding
+	adventure := input.Adventure
+
+	// Adventure-level references first, then scene references in reading order
+	for _, assetID := range input.AdventureAssetIDs {
+		findings = append(findings, checkAssetReference(input, assetID, EntityRef{Type: "adventure", ID: adventure.ID})...)
+	}
+	for _, episode := range adventure.Episodes {
+		episodeID := episode.ID
+		for _, scene := range episode.Scenes {
+			for _, assetID := range input.SceneAssetIDs[scene.ID] {
+				findings = append(findings, checkAssetReference(input, assetID, EntityRef{Type: "scene", ID: scene.ID, EpisodeID: &episodeID})...)
+			}
+		}
+	}
+
+	// Each asset's genres only need checking once
+	checked := make(map[uint]bool)
+	adventureRank := ageRatingRank[adventure.AgeRating]
+	for _, assetID := range referencedAssetIDs(input) {
+		asset, ok := input.Assets[assetID]
+		if !ok || checked[assetID] || asset.DeletedAt.Valid {
+			continue
+		}
+		checked[assetID] = true
+
+		for _, genre := range asset.Genres {
+			required, ok := minimumAgeRatingByGenre[strings.ToLower(strings.TrimSpace(genre))]
+			if ok && ageRatingRank[required] > adventureRank {
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Code:     "age_rating_mismatch",
+					Message:  fmt.Sprintf("Asset %q is tagged %q, which suggests a rating of at least %s but the adventure is rated %s", asset.Name, genre, required, adventure.AgeRating),
+					Entity:   EntityRef{Type: "asset", ID: asset.ID},
+				})
+				break
+			}
+		}
+	}
+
+	return findings
+}
+
+func checkAssetReference(input AdventureInput, assetID uint, from EntityRef) []Finding {
+	adventure := input.Adventure
+
+	asset, ok := input.Assets[assetID]
+	if !ok || asset.DeletedAt.Valid {
+		return []Finding{{
+			Severity: SeverityError,
+			Code:     "deleted_asset",
+			Message:  fmt.Sprintf("References asset %d, which has been deleted", assetID),
+			Entity:   from,
+		}}
+	}
+
+	ownedByAuthor := asset.UserID != nil && adventure.UserID != nil && *asset.UserID == *adventure.UserID
+	if !asset.IsOfficial && !ownedByAuthor {
+		return []Finding{{
+			Severity: SeverityError,
+			Code:     "private_asset",
+			Message:  fmt.Sprintf("References asset %q, which belongs to another user", asset.Name),
+			Entity:   from,
+		}}
+	}
+
+	// Published adventures should only lean on content everyone can see
+	if (adventure.IsOfficial || adventure.Reviewed) && !asset.IsOfficial && !asset.Reviewed {
+		return []Finding{{
+			Severity: SeverityWarning,
+			Code:     "unreviewed_asset",
+			Message:  fmt.Sprintf("References asset %q, which is personal and has not been reviewed", asset.Name),
+			Entity:   from,
+		}}
+	}
+
+	return nil
+}
+
+func referencedAssetIDs(input AdventureInput) []uint {
+	ids := append([]uint{}, input.AdventureAssetIDs...)
+	for _, episode := range input.Adventure.Episodes {
+		for _, scene := range episode.Scenes {
+			ids = append(ids, input.SceneAssetIDs[scene.ID]...)
+		}
+	}
+	return ids
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	default:
+		return 2
+	}
+}
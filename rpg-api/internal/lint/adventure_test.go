@@ -0,0 +1,173 @@
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+)
+
+var author, stranger = uint(1), uint(2)
+
+// An adventure with nothing to report: two episodes of ordered, complete
+// scenes, a full epilogue and an asset the author owns
+func cleanInput() AdventureInput {
+	scene := func(id uint, order int) models.Scene {
+		return models.Scene{ID: id, Order: order, Title: fmt.Sprintf("Scene %d", id), Prose: "Read this aloud.", ImageID: "scene"}
+	}
+	return AdventureInput{
+		Adventure: models.Adventure{
+			ID:        1,
+			UserID:    &author,
+			AgeRating: "For Everyone",
+			Episodes: []models.Episode{
+				{ID: 10, Order: 1, Title: "Arrival", Scenes: []models.Scene{scene(100, 1), scene(101, 2)}},
+				{ID: 11, Order: 2, Title: "Escape", Scenes: []models.Scene{scene(110, 1)}},
+			},
+			Epilogue: &models.Epilogue{
+				ID:       20,
+				Outcomes: []models.EpilogueOutcome{{ID: 30, Title: "Freedom", Description: "They got away."}},
+				Credits:  models.Credits{Designer: "Ana", System: "Simple D6", Version: "1.0", Year: "2026"},
+			},
+		},
+		SceneAssetIDs:     map[uint][]uint{100: {40}},
+		AdventureAssetIDs: []uint{40},
+		Assets: map[uint]models.Asset{
+			40: {ID: 40, Name: "Lantern", UserID: &author},
+		},
+	}
+}
+
+func TestLintAdventure(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		change func(*AdventureInput)
+		want   []string // "severity code type:id", in report order
+	}{
+		{"clean", func(*AdventureInput) {}, nil},
+		{"no episodes", func(in *AdventureInput) {
+			in.Adventure.Episodes = nil
+		}, []string{"error no_episodes adventure:1"}},
+		{"episode order gap", func(in *AdventureInput) {
+			in.Adventure.Episodes[1].Order = 3
+		}, []string{"warning order_gap episode:11"}},
+		{"episode order starts late", func(in *AdventureInput) {
+			in.Adventure.Episodes[0].Order = 2
+			in.Adventure.Episodes[1].Order = 3
+		}, []string{"warning order_gap episode:10"}},
+		{"duplicate episode order", func(in *AdventureInput) {
+			in.Adventure.Episodes[1].Order = 1
+		}, []string{"error duplicate_order episode:11"}},
+		{"scene order gap and duplicate", func(in *AdventureInput) {
+			scenes := in.Adventure.Episodes[0].Scenes
+			in.Adventure.Episodes[0].Scenes = append(scenes, models.Scene{ID: 102, Order: 2, Title: "Again", Prose: "Again.", ImageURL: "again"},
+				models.Scene{ID: 103, Order: 5, Title: "Later", Prose: "Later.", ImageID: "later"})
+		}, []string{"error duplicate_order scene:102", "warning order_gap scene:103"}},
+		{"episode without scenes", func(in *AdventureInput) {
+			in.Adventure.Episodes[1].Scenes = nil
+		}, []string{"warning episode_no_scenes episode:11"}},
+		{"scene without prose or image", func(in *AdventureInput) {
+			in.Adventure.Episodes[1].Scenes[0].Prose = "  "
+			in.Adventure.Episodes[1].Scenes[0].ImageID = ""
+		}, []string{"warning scene_empty_prose scene:110", "info scene_missing_image scene:110"}},
+		{"no epilogue", func(in *AdventureInput) {
+			in.Adventure.Epilogue = nil
+		}, []string{"warning missing_epilogue adventure:1"}},
+		{"empty epilogue outcomes", func(in *AdventureInput) {
+			in.Adventure.Epilogue.Outcomes = append(in.Adventure.Epilogue.Outcomes,
+				models.EpilogueOutcome{ID: 31, Title: " ", Description: "Untitled."},
+				models.EpilogueOutcome{ID: 32, Title: "Undescribed"},
+			)
+		}, []string{"warning empty_outcome outcome:31", "warning empty_outcome outcome:32"}},
+		{"missing credits", func(in *AdventureInput) {
+			in.Adventure.Epilogue.Credits.System = ""
+			in.Adventure.Epilogue.Credits.Year = " "
+		}, []string{"warning missing_credits epilogue:20"}},
+		{"deleted asset", func(in *AdventureInput) {
+			lantern := in.Assets[40]
+			lantern.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			in.Assets[40] = lantern
+		}, []string{"error deleted_asset adventure:1", "error deleted_asset scene:100"}},
+		{"missing asset", func(in *AdventureInput) {
+			in.SceneAssetIDs[110] = []uint{41}
+		}, []string{"error deleted_asset scene:110"}},
+		{"private asset", func(in *AdventureInput) {
+			in.Assets[41] = models.Asset{ID: 41, Name: "Stolen", UserID: &stranger}
+			in.SceneAssetIDs[101] = []uint{41}
+		}, []string{"error private_asset scene:101"}},
+		{"someone else's official asset", func(in *AdventureInput) {
+			in.Assets[41] = models.Asset{ID: 41, Name: "Shared", UserID: &stranger, IsOfficial: true}
+			in.SceneAssetIDs[101] = []uint{41}
+		}, nil},
+		{"unreviewed asset in a reviewed adventure", func(in *AdventureInput) {
+			in.Adventure.Reviewed = true
+		}, []string{"warning unreviewed_asset adventure:1", "warning unreviewed_asset scene:100"}},
+		{"age rating below an asset's genre", func(in *AdventureInput) {
+			lantern := in.Assets[40]
+			lantern.Genres = []string{"Fantasy", " Horror ", "gore"}
+			in.Assets[40] = lantern
+		}, []string{"warning age_rating_mismatch asset:40"}},
+		{"age rating below a stronger genre", func(in *AdventureInput) {
+			in.Adventure.AgeRating = "Teen"
+			lantern := in.Assets[40]
+			lantern.Genres = []string{"Horror", "Gore"}
+			in.Assets[40] = lantern
+		}, []string{"warning age_rating_mismatch asset:40"}},
+		{"age rating high enough", func(in *AdventureInput) {
+			in.Adventure.AgeRating = "Adult"
+			lantern := in.Assets[40]
+			lantern.Genres = []string{"Horror", "Gore"}
+			in.Assets[40] = lantern
+		}, nil},
+		{"deleted asset's genres are ignored", func(in *AdventureInput) {
+			in.Assets[41] = models.Asset{ID: 41, Name: "Gone", UserID: &author, Genres: []string{"gore"},
+				DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}
+			in.SceneAssetIDs[101] = []uint{41}
+		}, []string{"error deleted_asset scene:101"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			input := cleanInput()
+			test.change(&input)
+			report := LintAdventure(input)
+
+			var got []string
+			for _, finding := range report.Findings {
+				got = append(got, fmt.Sprintf("%s %s %s:%d", finding.Severity, finding.Code, finding.Entity.Type, finding.Entity.ID))
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("Got findings %q, want %q", got, test.want)
+			}
+			if report.Errors+report.Warnings+report.Info != len(report.Findings) {
+				t.Errorf("Counted %d errors, %d warnings and %d info for %d findings", report.Errors, report.Warnings, report.Info, len(report.Findings))
+			}
+		})
+	}
+}
+
+func TestLintAdventureSortsBySeverity(t *testing.T) {
+	input := cleanInput()
+	input.Adventure.Epilogue = nil
+	input.Adventure.Episodes[0].Scenes[0].ImageID = ""
+	input.Adventure.Episodes[1].Order = 1
+
+	report := LintAdventure(input)
+	var severities []string
+	for _, finding := range report.Findings {
+		severities = append(severities, finding.Severity)
+	}
+	if want := []string{SeverityError, SeverityWarning, SeverityInfo}; !slices.Equal(severities, want) {
+		t.Errorf("Got %v, want %v", severities, want)
+	}
+	if report.Errors != 1 || report.Warnings != 1 || report.Info != 1 {
+		t.Errorf("Got %+v", report)
+	}
+
+	// Scene findings carry their episode so the UI can link to them
+	scene := report.Findings[2].Entity
+	if scene.Type != "scene" || scene.EpisodeID == nil || *scene.EpisodeID != 10 {
+		t.Errorf("Got entity %+v", scene)
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/calendar"
+)
+
+// WorldCalendar is how a world counts days. A world has at most one; while
+// it has none, timeline dates are free text and events sort by SortOrder.
+type WorldCalendar struct {
+	ID           uint               `json:"id" gorm:"primaryKey"`
+	WorldID      uint               `json:"world_id" gorm:"not null;uniqueIndex"`
+	Name         string             `json:"name"`
+	Months       []calendar.Month   `json:"months" gorm:"serializer:json;type:jsonb"`
+	Weekdays     []string           `json:"weekdays" gorm:"serializer:json;type:jsonb"`
+	FirstWeekday int                `json:"first_weekday"`
+	Epochs       []calendar.Epoch   `json:"epochs" gorm:"serializer:json;type:jsonb"`
+	Leap         *calendar.LeapRule `json:"leap" gorm:"serializer:json;type:jsonb"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// Calendar is the definition the calendar package works with
+func (w WorldCalendar) Calendar() calendar.Calendar {
+	return calendar.Calendar{
+		Months:       w.Months,
+		Weekdays:     w.Weekdays,
+		FirstWeekday: w.FirstWeekday,
+		Epochs:       w.Epochs,
+		Leap:         w.Leap,
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Tags and Collections
+
+// Tag is a user's own label for assets. Anyone can tag any asset they can
+// see, official ones included; tags are only visible to the user who made them.
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Color     string    `json:"color"` // Optional hex colour for display
+	CreatedAt time.Time `json:"created_at"`
+
+	// Filled in by GET /tags
+	AssetCount int64 `json:"asset_count" gorm:"-"`
+}
+
+type AssetTag struct {
+	TagID     uint      `json:"tag_id" gorm:"primaryKey"`
+	AssetID   uint      `json:"asset_id" gorm:"primaryKey;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Collaborator roles
+const (
+	CollectionRoleViewer = "viewer" // Can see the collection and attach it to their adventures
+	CollectionRoleEditor = "editor" // Can also add, remove and reorder assets
+)
+
+// Collection is a named, ordered list of assets, e.g. "Goblin Warren bestiary"
+type Collection struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	User          *User                    `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Items         []CollectionItem         `json:"items,omitempty" gorm:"foreignKey:CollectionID"`
+	Collaborators []CollectionCollaborator `json:"collaborators,omitempty" gorm:"foreignKey:CollectionID"`
+}
+
+type CollectionItem struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	CollectionID uint      `json:"collection_id" gorm:"not null;uniqueIndex:idx_collection_items_asset"`
+	AssetID      uint      `json:"asset_id" gorm:"not null;uniqueIndex:idx_collection_items_asset;index"`
+	Position     int       `json:"position" gorm:"not null"`
+	AddedByID    uint      `json:"added_by_id"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Relationships
+	Asset *Asset `json:"asset,omitempty" gorm:"foreignKey:AssetID"`
+}
+
+type CollectionCollaborator struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	CollectionID uint      `json:"collection_id" gorm:"not null;uniqueIndex:idx_collection_collaborators_user"`
+	UserID       uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_collection_collaborators_user;index"`
+	Role         string    `json:"role" gorm:"not null;default:'viewer'"` // viewer, editor
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Relationships
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
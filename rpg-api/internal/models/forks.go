@@ -0,0 +1,21 @@
+package models
+
+// Kinds of rows a fork remembers the origin of
+const (
+	ForkEra          = "era"
+	ForkEvent        = "event"
+	ForkLocation     = "location"
+	ForkOrganization = "organization"
+	ForkNPC          = "npc"
+)
+
+// WorldForkOrigin pairs a row in a fork with the row in the source world it
+// was copied from, or was merged into. Base fingerprints the source row as
+// it was then, so changes since can be told apart from changes in the fork.
+type WorldForkOrigin struct {
+	WorldID  uint   `json:"world_id" gorm:"primaryKey"` // The fork
+	Kind     string `json:"kind" gorm:"primaryKey"`
+	ID       uint   `json:"id" gorm:"primaryKey;autoIncrement:false"`
+	SourceID uint   `json:"source_id" gorm:"not null"`
+	Base     string `json:"base"` // Empty for kinds that are never compared
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// LoreArticle is a wiki page about a world. Body is markdown and can link to
+// other articles, NPCs, organizations, locations and timeline events with
+// [[Name]]; see the wiki package for the syntax.
+type LoreArticle struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	WorldID    uint           `json:"world_id" gorm:"not null;index"`
+	Title      string         `json:"title" gorm:"not null"`
+	Summary    string         `json:"summary"`
+	Body       string         `json:"body" gorm:"type:text"`
+	Categories pq.StringArray `json:"categories" gorm:"type:text[]"`
+	ImageURL   string         `json:"image_url"`
+	ImageID    string         `json:"image_id"`
+	UserID     *uint          `json:"user_id" gorm:"index"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+
+	// Relationships
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// LoreLink is one [[link]] found in an article body, kept so backlinks and
+// broken links can be found without re-parsing every article. Links are
+// stored by name and resolved when read, so creating the missing NPC or
+// article fixes a broken link without touching the article.
+type LoreLink struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	WorldID    uint   `json:"world_id" gorm:"not null;index"`
+	ArticleID  uint   `json:"article_id" gorm:"not null;index"`
+	TargetType string `json:"target_type"`                       // Empty for unqualified links
+	TargetName string `json:"target_name" gorm:"not null;index"` // Lowercased
+	Raw        string `json:"raw"`
+	Label      string `json:"label"`
+}
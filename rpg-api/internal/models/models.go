@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/lib/pq"
+	"gorm.io/gorm"
 )
 
 type User struct {
@@ -42,8 +43,10 @@ type Asset struct {
 	IsOfficial bool           `json:"is_official" gorm:"default:false"`
 	Reviewed   bool           `json:"reviewed" gorm:"default:false"`
 	Genres     pq.StringArray `json:"genres" gorm:"type:text[]"`
+	StatBlock  *StatBlock     `json:"stat_block" gorm:"serializer:json;type:jsonb"` // Game data, shape depends on Type
 	UserID     *uint          `json:"user_id" gorm:"index"`
 	CreatedAt  time.Time      `json:"created_at"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"` // Set when moved to trash
 
 	// Relationship
 	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -86,6 +89,7 @@ type Adventure struct {
 	AgeRating      string         `json:"age_rating" gorm:"default:'For Everyone'"`
 	UserID         *uint          `json:"user_id" gorm:"index"`
 	CreatedAt      time.Time      `json:"created_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"` // Set when moved to trash
 
 	// Relationships
 	User      *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -177,8 +181,11 @@ type World struct {
 	Reviewed       bool           `json:"reviewed" gorm:"default:false"`
 	AgeRating      string         `json:"age_rating" gorm:"default:'For Everyone'"`
 	UserID         *uint          `json:"user_id" gorm:"index"`
+	ForkedFromID   *uint          `json:"forked_from_id" gorm:"index"` // The world this is a fork of, kept after that world is deleted
+	ForkedAt       *time.Time     `json:"forked_at"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"` // Set when moved to trash
 
 	// Relationships
 	User           *User           `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -186,32 +193,51 @@ type World struct {
 }
 
 type TimelineEvent struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	WorldID     uint      `json:"world_id"`
-	Title       string    `json:"title" gorm:"not null"`
-	Description string    `json:"description"`
-	StartDate   string    `json:"start_date" gorm:"not null"`
-	EndDate     *string   `json:"end_date"`
-	Era         string    `json:"era" gorm:"not null"`
-	Importance  string    `json:"importance" gorm:"not null;default:'minor'"`
-	SortOrder   int       `json:"sort_order" gorm:"not null"`
-	ImageURL    string    `json:"image_url"`
-	ImageID     string    `json:"image_id"`
-	Details     string    `json:"details" gorm:"type:text"`
-	UserID      *uint     `json:"user_id" gorm:"index"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	WorldID      uint      `json:"world_id"`
+	Title        string    `json:"title" gorm:"not null"`
+	Description  string    `json:"description"`
+	StartDate    string    `json:"start_date" gorm:"not null"`
+	EndDate      *string   `json:"end_date"`
+	StartDay     *int64    `json:"start_day" gorm:"index"` // Day numbers from the world calendar, nil without one
+	EndDay       *int64    `json:"end_day"`
+	DurationDays *int64    `json:"duration_days"` // Days covered, counting both ends
+	EraID        *uint     `json:"era_id" gorm:"index"`
+	Era          string    `json:"era" gorm:"not null"` // Name of EraID, kept in step with it
+	Importance   string    `json:"importance" gorm:"not null;default:'minor'"`
+	SortOrder    int       `json:"sort_order" gorm:"not null"`
+	ImageURL     string    `json:"image_url"`
+	ImageID      string    `json:"image_id"`
+	Details      string    `json:"details" gorm:"type:text"`
+	UserID       *uint     `json:"user_id" gorm:"index"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// For frontend communication
+	OrganizationIDs []uint `json:"organization_ids" gorm:"-"`
+	LocationIDs     []uint `json:"location_ids" gorm:"-"`
 
 	// Relationships
-	World World `json:"world,omitempty" gorm:"foreignKey:WorldID"`
-	User  *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	World         World              `json:"world,omitempty" gorm:"foreignKey:WorldID"`
+	User          *User              `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	WorldEra      *WorldEra          `json:"-" gorm:"foreignKey:EraID;constraint:OnDelete:RESTRICT"`
+	NPCs          []TimelineEventNPC `json:"npcs,omitempty" gorm:"foreignKey:TimelineEventID"`
+	Organizations []Organization     `json:"organizations,omitempty" gorm:"many2many:timeline_event_organizations;"`
+	Locations     []NPCLocation      `json:"locations,omitempty" gorm:"many2many:timeline_event_locations;"`
 }
 
+// WorldEra is a named span of a world's history. With a calendar, an era
+// with a start date covers every day from it up to its end date, or onwards
+// when it has none, and dated events are assigned to the era they fall in.
 type WorldEra struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	WorldID   uint      `json:"world_id" gorm:"not null;index"`
 	Name      string    `json:"name" gorm:"not null"`
 	SortOrder int       `json:"sort_order" gorm:"not null"`
+	StartDate string    `json:"start_date"`
+	EndDate   *string   `json:"end_date"`
+	StartDay  *int64    `json:"start_day"`
+	EndDay    *int64    `json:"end_day"`
 	CreatedAt time.Time `json:"created_at"`
 
 	// Relationships
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/dice"
+)
+
+// Play Sessions
+
+// Session status values
+const (
+	SessionStatusActive = "active"
+	SessionStatusEnded  = "ended"
+)
+
+// Session event types, recorded in the order they happen
+const (
+	SessionEventStarted = "started"
+	SessionEventScene   = "scene"
+	SessionEventNote    = "note"
+	SessionEventEnded   = "ended"
+)
+
+type PlaySession struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	AdventureID    uint       `json:"adventure_id" gorm:"not null;index"`
+	GMID           uint       `json:"gm_id" gorm:"not null;index"`
+	Name           string     `json:"name"`
+	JoinCode       string     `json:"join_code" gorm:"index"`                  // Shared with players so they can join the game table
+	Status         string     `json:"status" gorm:"not null;default:'active'"` // active, ended
+	CurrentSceneID *uint      `json:"current_scene_id"`
+	OutcomeID      *uint      `json:"outcome_id"` // EpilogueOutcome reached when the session ended
+	StartedAt      time.Time  `json:"started_at"`
+	EndedAt        *time.Time `json:"ended_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Relationships
+	Adventure    *Adventure          `json:"adventure,omitempty" gorm:"foreignKey:AdventureID"`
+	GM           *User               `json:"gm,omitempty" gorm:"foreignKey:GMID"`
+	CurrentScene *Scene              `json:"current_scene,omitempty" gorm:"foreignKey:CurrentSceneID"`
+	Outcome      *EpilogueOutcome    `json:"outcome,omitempty" gorm:"foreignKey:OutcomeID"`
+	Players      []PlaySessionPlayer `json:"players,omitempty" gorm:"foreignKey:SessionID"`
+}
+
+type PlaySessionPlayer struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	SessionID     uint      `json:"session_id" gorm:"not null;index"`
+	UserID        *uint     `json:"user_id" gorm:"index"` // Null for players without an account
+	Name          string    `json:"name" gorm:"not null"`
+	CharacterName string    `json:"character_name"`
+	Token         string    `json:"-" gorm:"index"` // Issued on join, sent as X-Player-Token to watch the table and roll
+	JoinedAt      time.Time `json:"joined_at"`
+
+	// Relationships
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// PlaySessionEvent is one line of the session log: the start, each scene
+// visited, notes taken by the GM during play, and the end
+type PlaySessionEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	SessionID uint      `json:"session_id" gorm:"not null;index"`
+	Type      string    `json:"type" gorm:"not null"` // started, scene, note, ended
+	SceneID   *uint     `json:"scene_id"`
+	OutcomeID *uint     `json:"outcome_id"`
+	Content   string    `json:"content" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Scene *Scene `json:"scene,omitempty" gorm:"foreignKey:SceneID"`
+}
+
+// DiceRoll is an authoritative roll made by the server during a session.
+// The seed and expression are kept so any participant can reproduce it.
+type DiceRoll struct {
+	ID           uint              `json:"id" gorm:"primaryKey"`
+	SessionID    uint              `json:"session_id" gorm:"not null;index"`
+	UserID       *uint             `json:"user_id"`
+	PlayerID     *uint             `json:"player_id"`
+	RollerName   string            `json:"roller_name"`
+	Label        string            `json:"label"` // What the roll was for, e.g. "Sneak past the guard"
+	Expression   string            `json:"expression" gorm:"not null"`
+	Seed         int64             `json:"seed"`
+	SeedProvided bool              `json:"seed_provided"` // True when the roller chose the seed
+	Terms        []dice.TermResult `json:"terms" gorm:"serializer:json;type:text"`
+	Natural      int               `json:"natural"`
+	Modifier     int               `json:"modifier"`
+	Total        int               `json:"total"`
+	Outcome      string            `json:"outcome"` // Simple D6 band, empty for other rolls
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// Join codes avoid characters that are easy to misread aloud (0/O, 1/I)
+const joinCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Generate a short join code for players to type in
+func GenerateJoinCode() (string, error) {
+	bytes := make([]byte, 6)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	for i, b := range bytes {
+		bytes[i] = joinCodeAlphabet[int(b)%len(joinCodeAlphabet)]
+	}
+	return string(bytes), nil
+}
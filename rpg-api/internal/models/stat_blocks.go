@@ -0,0 +1,196 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stat Blocks
+
+// Asset types
+const (
+	AssetTypeCharacter = "character"
+	AssetTypeCreature  = "creature"
+	AssetTypeLocation  = "location"
+	AssetTypeItem      = "item"
+)
+
+// Simple D6 health states, from full health down to out of the fight
+const (
+	HealthHealthy   = "Healthy"
+	HealthHurt      = "Hurt"
+	HealthBadlyHurt = "Badly Hurt" // -1 to all rolls
+	HealthDown      = "Down"
+)
+
+// A standard character or creature has 3 health: Healthy, Hurt, Badly Hurt, then Down
+const (
+	DefaultMaxHealth = 3
+	MaxHealthLimit   = 10
+)
+
+// Limits on how much can be put into a single stat block
+const (
+	maxStatEntries = 20
+	maxStatText    = 500
+)
+
+// StatBlock is the game data for an asset. Characters and creatures use the
+// health track, expertise, abilities and attacks; items use properties.
+type StatBlock struct {
+	Health     *HealthTrack     `json:"health,omitempty"`
+	Expertise  []string         `json:"expertise,omitempty"` // Areas that grant +1, e.g. "Swordplay"
+	Abilities  []SpecialAbility `json:"abilities,omitempty"`
+	Attacks    []Attack         `json:"attacks,omitempty"`
+	Properties []ItemProperty   `json:"properties,omitempty"`
+}
+
+type HealthTrack struct {
+	Max     int    `json:"max"`
+	Current int    `json:"current"`
+	State   string `json:"state"` // Derived from current health, set on validation
+}
+
+type SpecialAbility struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type Attack struct {
+	Name   string `json:"name"`
+	Damage int    `json:"damage"` // Health lost on a hit, 1 unless the attack is especially deadly
+	Range  string `json:"range"`  // "melee", "near", "far"
+	Notes  string `json:"notes"`
+}
+
+type ItemProperty struct {
+	Name  string `json:"name"`  // e.g. "Weight", "Grants expertise"
+	Value string `json:"value"` // e.g. "Heavy", "Climbing"
+}
+
+// HealthState names where a health track sits. Larger creatures with more
+// than 3 health stay Hurt until their last point.
+func HealthState(current int, max int) string {
+	switch {
+	case current <= 0:
+		return HealthDown
+	case current >= max:
+		return HealthHealthy
+	case current == 1:
+		return HealthBadlyHurt
+	default:
+		return HealthHurt
+	}
+}
+
+// ValidateStatBlock checks a stat block against the asset type it belongs to
+// and fills in defaults: a full 3 point health track for characters and
+// creatures, and 1 damage for attacks. Locations have no stat block.
+func ValidateStatBlock(assetType string, block *StatBlock) error {
+	if block == nil {
+		return nil
+	}
+
+	switch assetType {
+	case AssetTypeCharacter, AssetTypeCreature:
+		if len(block.Properties) > 0 {
+			return fmt.Errorf("%s stat blocks cannot have item properties", assetType)
+		}
+		return validateCombatant(block)
+	case AssetTypeItem:
+		if block.Health != nil || len(block.Expertise) > 0 || len(block.Abilities) > 0 || len(block.Attacks) > 0 {
+			return fmt.Errorf("item stat blocks can only have properties")
+		}
+		return validateProperties(block.Properties)
+	case AssetTypeLocation:
+		return fmt.Errorf("locations do not have stat blocks")
+	default:
+		return fmt.Errorf("unknown asset type %q", assetType)
+	}
+}
+
+func validateCombatant(block *StatBlock) error {
+	if block.Health == nil {
+		block.Health = &HealthTrack{Max: DefaultMaxHealth, Current: DefaultMaxHealth}
+	}
+	if block.Health.Max == 0 {
+		block.Health.Max = DefaultMaxHealth
+		if block.Health.Current == 0 {
+			block.Health.Current = DefaultMaxHealth
+		}
+	}
+	if block.Health.Max < 1 || block.Health.Max > MaxHealthLimit {
+		return fmt.Errorf("health must be between 1 and %d", MaxHealthLimit)
+	}
+	if block.Health.Current < 0 || block.Health.Current > block.Health.Max {
+		return fmt.Errorf("current health must be between 0 and %d", block.Health.Max)
+	}
+	block.Health.State = HealthState(block.Health.Current, block.Health.Max)
+
+	if len(block.Expertise) > maxStatEntries || len(block.Abilities) > maxStatEntries || len(block.Attacks) > maxStatEntries {
+		return fmt.Errorf("stat blocks can have at most %d expertise areas, abilities and attacks each", maxStatEntries)
+	}
+
+	seen := map[string]bool{}
+	for i, area := range block.Expertise {
+		area = strings.TrimSpace(area)
+		if area == "" {
+			return fmt.Errorf("expertise areas cannot be blank")
+		}
+		if len(area) > maxStatText {
+			return fmt.Errorf("expertise %q is too long", area)
+		}
+		if seen[strings.ToLower(area)] {
+			return fmt.Errorf("expertise %q is listed twice", area)
+		}
+		seen[strings.ToLower(area)] = true
+		block.Expertise[i] = area
+	}
+
+	for i := range block.Abilities {
+		ability := &block.Abilities[i]
+		ability.Name = strings.TrimSpace(ability.Name)
+		if ability.Name == "" {
+			return fmt.Errorf("special abilities need a name")
+		}
+		if len(ability.Name) > maxStatText || len(ability.Description) > maxStatText*4 {
+			return fmt.Errorf("special ability %q is too long", ability.Name)
+		}
+	}
+
+	for i := range block.Attacks {
+		attack := &block.Attacks[i]
+		attack.Name = strings.TrimSpace(attack.Name)
+		if attack.Name == "" {
+			return fmt.Errorf("attacks need a name")
+		}
+		if attack.Damage == 0 {
+			attack.Damage = 1
+		}
+		if attack.Damage < 1 || attack.Damage > DefaultMaxHealth {
+			return fmt.Errorf("attack %q must deal between 1 and %d damage", attack.Name, DefaultMaxHealth)
+		}
+		if len(attack.Name) > maxStatText || len(attack.Range) > maxStatText || len(attack.Notes) > maxStatText*4 {
+			return fmt.Errorf("attack %q is too long", attack.Name)
+		}
+	}
+
+	return nil
+}
+
+func validateProperties(properties []ItemProperty) error {
+	if len(properties) > maxStatEntries {
+		return fmt.Errorf("items can have at most %d properties", maxStatEntries)
+	}
+	for i := range properties {
+		property := &properties[i]
+		property.Name = strings.TrimSpace(property.Name)
+		if property.Name == "" {
+			return fmt.Errorf("item properties need a name")
+		}
+		if len(property.Name) > maxStatText || len(property.Value) > maxStatText*4 {
+			return fmt.Errorf("item property %q is too long", property.Name)
+		}
+	}
+	return nil
+}
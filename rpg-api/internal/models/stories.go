@@ -0,0 +1,83 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Story publish states
+const (
+	StoryDraft     = "draft"
+	StoryPublished = "published"
+)
+
+// Average adult silent reading speed, used for reading time estimates
+const WordsPerMinute = 230
+
+// Story is long-form fiction set in a world, read chapter by chapter. Word
+// count and reading time are totals over the chapters, kept up to date
+// whenever a chapter changes.
+type Story struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	WorldID        uint       `json:"world_id" gorm:"not null;index"`
+	Title          string     `json:"title" gorm:"not null"`
+	Category       string     `json:"category"`
+	Excerpt        string     `json:"excerpt"`
+	CoverImageURL  string     `json:"cover_image_url"`
+	CoverImageID   string     `json:"cover_image_id"`
+	Status         string     `json:"status" gorm:"not null;default:'draft'"`
+	PublishedAt    *time.Time `json:"published_at"`
+	IsOfficial     bool       `json:"is_official" gorm:"default:false"`
+	Reviewed       bool       `json:"reviewed" gorm:"default:false"`
+	WordCount      int        `json:"word_count"`
+	ReadingMinutes int        `json:"reading_minutes"`
+	UserID         *uint      `json:"user_id" gorm:"index"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// For frontend communication
+	TimelineEventIDs []uint `json:"timeline_event_ids" gorm:"-"`
+
+	// Relationships
+	User           *User           `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Chapters       []StoryChapter  `json:"chapters,omitempty" gorm:"foreignKey:StoryID"`
+	TimelineEvents []TimelineEvent `json:"timeline_events,omitempty" gorm:"many2many:story_timeline_events;"`
+}
+
+type StoryChapter struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	StoryID        uint      `json:"story_id" gorm:"not null;index"`
+	Title          string    `json:"title" gorm:"not null"`
+	Body           string    `json:"body" gorm:"type:text"` // Markdown
+	SortOrder      int       `json:"sort_order" gorm:"not null"`
+	WordCount      int       `json:"word_count"`
+	ReadingMinutes int       `json:"reading_minutes"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CountWords counts the words in a chapter body
+func CountWords(markdown string) int {
+	return len(strings.Fields(markdown))
+}
+
+// ReadingMinutes estimates reading time in whole minutes, rounded up so any
+// text takes at least a minute
+func ReadingMinutes(words int) int {
+	return (words + WordsPerMinute - 1) / WordsPerMinute
+}
+
+// VisibleStories limits a story query to published official or reviewed
+// stories, plus the viewer's own drafts and unreviewed stories. Admins see
+// every story and a nil user is a visitor.
+func VisibleStories(db *gorm.DB, user *User) *gorm.DB {
+	if user == nil {
+		return db.Where("status = ? AND (is_official = ? OR reviewed = ?)", StoryPublished, true, true)
+	}
+	if user.IsAdmin {
+		return db
+	}
+	return db.Where("(status = ? AND (is_official = ? OR reviewed = ?)) OR user_id = ?", StoryPublished, true, true, user.ID)
+}
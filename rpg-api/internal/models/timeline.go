@@ -0,0 +1,89 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Event importance, from least to most important
+const (
+	ImportanceMinor    = "minor"
+	ImportanceMajor    = "major"
+	ImportanceCritical = "critical"
+)
+
+var Importances = []string{ImportanceMinor, ImportanceMajor, ImportanceCritical}
+
+// Timeline event link types. Caused and part-of links point from the cause
+// or sub-event to the effect or whole; concurrent and contradicts links read
+// the same either way round.
+const (
+	LinkCaused      = "caused"
+	LinkPartOf      = "part_of"
+	LinkConcurrent  = "concurrent"
+	LinkContradicts = "contradicts"
+)
+
+// LinkTypes lists every link type in the order they are documented
+var LinkTypes = []string{LinkCaused, LinkPartOf, LinkConcurrent, LinkContradicts}
+
+// TimelineEventLink is a typed link between two events in the same world.
+// An event is part of at most one other event, so part-of links nest events
+// into a tree of sub-events.
+type TimelineEventLink struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	WorldID     uint      `json:"world_id" gorm:"not null;index"`
+	FromEventID uint      `json:"from_event_id" gorm:"not null;uniqueIndex:idx_timeline_event_link"`
+	ToEventID   uint      `json:"to_event_id" gorm:"not null;uniqueIndex:idx_timeline_event_link;index"`
+	Type        string    `json:"type" gorm:"not null;uniqueIndex:idx_timeline_event_link"`
+	Note        string    `json:"note"`
+	UserID      *uint     `json:"user_id" gorm:"index"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Relationships
+	FromEvent *TimelineEvent `json:"from_event,omitempty" gorm:"foreignKey:FromEventID"`
+	ToEvent   *TimelineEvent `json:"to_event,omitempty" gorm:"foreignKey:ToEventID"`
+}
+
+// TimelineEventNPC is an NPC's part in an event. Role is free text such as
+// "instigator", "victim" or "witness".
+type TimelineEventNPC struct {
+	TimelineEventID uint   `json:"timeline_event_id" gorm:"primaryKey"`
+	NPCID           uint   `json:"npc_id" gorm:"primaryKey;index"`
+	Role            string `json:"role" gorm:"not null"`
+
+	// Relationships
+	NPC *NPC `json:"npc,omitempty" gorm:"foreignKey:NPCID"`
+}
+
+// Role given to NPCs added to an event without one
+const DefaultEventRole = "participant"
+
+// Directed reports whether the order of a link type's events matters
+func Directed(linkType string) bool {
+	return linkType == LinkCaused || linkType == LinkPartOf
+}
+
+// OverlappingEras lists the pairs of dated eras that share a day, each pair
+// in start order. Eras without a start day never overlap anything; an era
+// without an end day runs on forever.
+func OverlappingEras(eras []WorldEra) [][2]WorldEra {
+	var dated []WorldEra
+	for _, era := range eras {
+		if era.StartDay != nil {
+			dated = append(dated, era)
+		}
+	}
+	sort.SliceStable(dated, func(i, j int) bool { return *dated[i].StartDay < *dated[j].StartDay })
+
+	var pairs [][2]WorldEra
+	for i, era := range dated {
+		for _, later := range dated[i+1:] {
+			if era.EndDay != nil && *later.StartDay > *era.EndDay {
+				break
+			}
+			pairs = append(pairs, [2]WorldEra{era, later})
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Upload records an image sent to the image store so images that never end
+// up referenced by content can be found and deleted. ReferencedAt is the
+// last time the image collector saw content using the image. Direct uploads
+// are recorded as pending when their URL is issued and confirmed once the
+// file has arrived; pending ones that are never confirmed get collected.
+type Upload struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       *uint      `json:"user_id" gorm:"index"` // Nil for images added by the system
+	ImageID      string     `json:"image_id" gorm:"not null;uniqueIndex"`
+	Filename     string     `json:"filename"`
+	Bytes        int64      `json:"bytes"` // Size after processing, counted against the owner's quota
+	Pending      bool       `json:"pending" gorm:"default:false"`
+	ReferencedAt *time.Time `json:"referenced_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
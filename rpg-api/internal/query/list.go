@@ -0,0 +1,296 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Page size limits shared by every list endpoint
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// How a filter's query value is matched against its column
+type FilterKind int
+
+const (
+	Equal    FilterKind = iota // column = value
+	Bool                       // column = true/false
+	Contains                   // text[] column contains value
+	Like                       // case-insensitive substring match
+	ID                         // integer foreign key, "none" matches NULL
+)
+
+type Filter struct {
+	Column string
+	Kind   FilterKind
+}
+
+// List describes what a list endpoint lets clients sort and filter on.
+// Keys are query parameter names, values are database columns.
+type List struct {
+	Sorts        map[string]string
+	DefaultSort  string
+	DefaultOrder string // asc or desc
+	Filters      map[string]Filter
+}
+
+// Params is a parsed list request
+type Params struct {
+	list    List
+	filters map[string]string
+	sort    string
+	column  string
+	desc    bool
+	limit   int
+	offset  int
+	cursor  *cursor
+}
+
+// Meta is returned alongside every page of results
+type Meta struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Sort       string `json:"sort"`
+	Order      string `json:"order"`
+	NextCursor string `json:"next_cursor,omitempty"` // Pass back as ?cursor= for the next page
+}
+
+// Page is the envelope list endpoints respond with
+type Page struct {
+	Data interface{} `json:"data"`
+	Meta Meta        `json:"meta"`
+}
+
+// A cursor marks the last row of a page by its sort value and ID, so the
+// next page starts right after it even when rows are added in between
+type cursor struct {
+	Value interface{} `json:"v"`
+	Null  bool        `json:"null,omitempty"` // The sort value was NULL
+	ID    uint        `json:"id"`
+}
+
+// Parse reads limit, offset, cursor, sort, order and the list's filters
+// from the query string
+func (l List) Parse(c *gin.Context) (*Params, error) {
+	params := &Params{list: l, limit: DefaultLimit, filters: map[string]string{}}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("limit must be a positive number")
+		}
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+		params.limit = limit
+	}
+
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("offset must be zero or more")
+		}
+		params.offset = offset
+	}
+
+	params.sort = c.DefaultQuery("sort", l.DefaultSort)
+	column, ok := l.Sorts[params.sort]
+	if !ok {
+		return nil, fmt.Errorf("cannot sort by %q", params.sort)
+	}
+	params.column = column
+
+	order := strings.ToLower(c.DefaultQuery("order", l.DefaultOrder))
+	switch order {
+	case "asc":
+	case "desc":
+		params.desc = true
+	default:
+		return nil, fmt.Errorf("order must be asc or desc")
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		if params.offset > 0 {
+			return nil, fmt.Errorf("use either cursor or offset, not both")
+		}
+		decoded, err := decodeCursor(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+		params.cursor = decoded
+	}
+
+	for name, filter := range l.Filters {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		if filter.Kind == Bool {
+			if _, err := strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("%s must be true or false", name)
+			}
+		}
+		if filter.Kind == ID && value != "none" {
+			if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+				return nil, fmt.Errorf("%s must be an ID or none", name)
+			}
+		}
+		params.filters[name] = value
+	}
+
+	return params, nil
+}
+
+// Filter narrows the query by whichever filters were given
+func (p *Params) Filter(db *gorm.DB) *gorm.DB {
+	for name, value := range p.filters {
+		filter := p.list.Filters[name]
+		switch filter.Kind {
+		case Bool:
+			b, _ := strconv.ParseBool(value)
+			db = db.Where(filter.Column+" = ?", b)
+		case Contains:
+			db = db.Where("? = ANY("+filter.Column+")", value)
+		case Like:
+			db = db.Where(filter.Column+" ILIKE ?", "%"+escapeLike(value)+"%")
+		case ID:
+			if value == "none" {
+				db = db.Where(filter.Column + " IS NULL")
+			} else {
+				db = db.Where(filter.Column+" = ?", value)
+			}
+		default:
+			db = db.Where(filter.Column+" = ?", value)
+		}
+	}
+	return db
+}
+
+// Find filters, counts and fetches one page into dest, which must be a
+// pointer to a slice of models. Preloads are applied to the page only.
+func (p *Params) Find(db *gorm.DB, dest interface{}, preloads ...string) (Meta, error) {
+	db = p.Filter(db)
+
+	meta := Meta{Limit: p.limit, Offset: p.offset, Sort: p.sort, Order: "asc"}
+	if p.desc {
+		meta.Order = "desc"
+	}
+
+	if err := db.Session(&gorm.Session{}).Model(dest).Count(&meta.Total).Error; err != nil {
+		return meta, err
+	}
+
+	// NULLs are spelled out where Postgres puts them anyway, last going up
+	// and first going down, so cursors know which side of a NULL they are on
+	direction, nulls := "ASC", "NULLS LAST"
+	if p.desc {
+		direction, nulls = "DESC", "NULLS FIRST"
+	}
+
+	page := db.Session(&gorm.Session{})
+	if p.cursor != nil {
+		page = page.Where(p.after())
+	}
+	for _, preload := range preloads {
+		page = page.Preload(preload)
+	}
+
+	// Fetch one extra row to know whether there is another page
+	result := page.Order(p.column + " " + direction + " " + nulls).Order("id " + direction).
+		Offset(p.offset).Limit(p.limit + 1).Find(dest)
+	if result.Error != nil {
+		return meta, result.Error
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() > p.limit {
+		rows.Set(rows.Slice(0, p.limit))
+		last := rows.Index(p.limit - 1)
+		next, err := p.nextCursor(result, last)
+		if err != nil {
+			return meta, err
+		}
+		meta.NextCursor = next
+	}
+
+	return meta, nil
+}
+
+// The rows that sort after the cursor
+func (p *Params) after() clause.Expr {
+	column, id := p.column, p.cursor.ID
+	switch {
+	case p.cursor.Null && p.desc:
+		// NULLs come first, so every other row is still to come
+		return gorm.Expr(fmt.Sprintf("(%s IS NULL AND id < ?) OR %s IS NOT NULL", column, column), id)
+	case p.cursor.Null:
+		return gorm.Expr(fmt.Sprintf("%s IS NULL AND id > ?", column), id)
+	case p.desc:
+		return gorm.Expr(fmt.Sprintf("(%s < ?) OR (%s = ? AND id < ?)", column, column), p.cursor.Value, p.cursor.Value, id)
+	default:
+		// NULLs come last, after every value
+		return gorm.Expr(fmt.Sprintf("(%s > ?) OR (%s = ? AND id > ?) OR %s IS NULL", column, column, column),
+			p.cursor.Value, p.cursor.Value, id)
+	}
+}
+
+// Response wraps a page of results in the list envelope
+func Response(data interface{}, meta Meta) Page {
+	return Page{Data: data, Meta: meta}
+}
+
+func (p *Params) nextCursor(result *gorm.DB, last reflect.Value) (string, error) {
+	schema := result.Statement.Schema
+	sortField := schema.LookUpField(p.column)
+	idField := schema.LookUpField("id")
+	if sortField == nil || idField == nil {
+		return "", fmt.Errorf("cannot build cursor for %s", p.column)
+	}
+
+	value, _ := sortField.ValueOf(result.Statement.Context, last)
+	id, _ := idField.ValueOf(result.Statement.Context, last)
+
+	next := cursor{Value: value}
+	if v := reflect.ValueOf(value); !v.IsValid() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		next = cursor{Null: true}
+	}
+	if id, ok := id.(uint); ok {
+		next.ID = id
+	}
+
+	encoded, err := json.Marshal(next)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+func decodeCursor(value string) (*cursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var c cursor
+	if err := json.Unmarshal(decoded, &c); err != nil {
+		return nil, err
+	}
+	if c.Value == nil && !c.Null {
+		return nil, fmt.Errorf("cursor has no value")
+	}
+	return &c, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
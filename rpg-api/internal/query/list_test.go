@@ -0,0 +1,162 @@
+package query
+
+import (
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/naetharu/rpg-api/internal/testdb"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type item struct {
+	ID     uint
+	Name   string
+	Rank   *int // NULL for unranked items
+	Hidden bool
+	TagID  *uint
+}
+
+var itemList = List{
+	Sorts:        map[string]string{"name": "name", "rank": "rank"},
+	DefaultSort:  "name",
+	DefaultOrder: "asc",
+	Filters: map[string]Filter{
+		"name":   {Column: "name", Kind: Equal},
+		"hidden": {Column: "hidden", Kind: Bool},
+		"tag":    {Column: "tag_id", Kind: ID},
+	},
+}
+
+func parse(t *testing.T, rawQuery string) (*Params, error) {
+	t.Helper()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items?"+rawQuery, nil)
+	return itemList.Parse(c)
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		query string
+		err   string
+	}{
+		{"", ""},
+		{"limit=10&offset=20&sort=rank&order=DESC&hidden=true&tag=none", ""},
+		{"limit=0", "limit must be a positive number"},
+		{"limit=ten", "limit must be a positive number"},
+		{"offset=-1", "offset must be zero or more"},
+		{"sort=secret", `cannot sort by "secret"`},
+		{"order=up", "order must be asc or desc"},
+		{"cursor=eyJ2IjoxLCJpZCI6MX0&offset=5", "use either cursor or offset, not both"},
+		{"cursor=not-base64!", "invalid cursor"},
+		{"cursor=eyJpZCI6MX0", "invalid cursor"}, // {"id":1}, no value
+		{"hidden=maybe", "hidden must be true or false"},
+		{"tag=abc", "tag must be an ID or none"},
+	} {
+		got := ""
+		if _, err := parse(t, test.query); err != nil {
+			got = err.Error()
+		}
+		if got != test.err {
+			t.Errorf("%q: got error %q, want %q", test.query, got, test.err)
+		}
+	}
+
+	params, _ := parse(t, "limit=1000&sort=rank&order=desc")
+	if params.limit != MaxLimit || params.column != "rank" || !params.desc {
+		t.Errorf("Parsed %+v", params)
+	}
+	params, _ = parse(t, "")
+	if params.limit != DefaultLimit || params.sort != "name" || params.desc {
+		t.Errorf("Defaults parsed as %+v", params)
+	}
+}
+
+func TestFind(t *testing.T) {
+	db := testdb.Open(t, &item{})
+	rank := func(r int) *int { return &r }
+	tag := uint(7)
+	for _, row := range []item{
+		{Name: "e", Rank: rank(2)},
+		{Name: "a"},
+		{Name: "d", Rank: rank(1), TagID: &tag},
+		{Name: "b", Rank: rank(2), Hidden: true},
+		{Name: "c"},
+		{Name: "f", Rank: rank(3)},
+	} {
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Follows the cursors page by page and returns every name in order
+	pages := func(rawQuery string) []string {
+		t.Helper()
+
+		var names []string
+		next := ""
+		for page := 0; page < 10; page++ {
+			q := rawQuery
+			if next != "" {
+				q += "&cursor=" + next
+			}
+			params, err := parse(t, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var items []item
+			meta, err := params.Find(db, &items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, it := range items {
+				names = append(names, it.Name)
+			}
+			if next = meta.NextCursor; next == "" {
+				return names
+			}
+		}
+		t.Fatalf("%q never ran out of pages", rawQuery)
+		return nil
+	}
+
+	for _, test := range []struct {
+		query string
+		want  []string
+	}{
+		{"limit=2", []string{"a", "b", "c", "d", "e", "f"}},
+		{"limit=4&order=desc", []string{"f", "e", "d", "c", "b", "a"}},
+		// Unranked rows sort last going up and first going down, and no
+		// page boundary skips or repeats them
+		{"limit=2&sort=rank", []string{"d", "e", "b", "f", "a", "c"}},
+		{"limit=1&sort=rank", []string{"d", "e", "b", "f", "a", "c"}},
+		{"limit=3&sort=rank", []string{"d", "e", "b", "f", "a", "c"}},
+		{"limit=1&sort=rank&order=desc", []string{"c", "a", "f", "b", "e", "d"}},
+		{"limit=2&sort=rank&order=desc", []string{"c", "a", "f", "b", "e", "d"}},
+		{"limit=5&sort=rank&order=desc", []string{"c", "a", "f", "b", "e", "d"}},
+		// Filters apply alongside the cursor
+		{"limit=1&sort=rank&hidden=false", []string{"d", "e", "f", "a", "c"}},
+		{"tag=none&sort=rank&order=desc&limit=2", []string{"c", "a", "f", "b", "e"}},
+		{"tag=7", []string{"d"}},
+		{"name=c", []string{"c"}},
+	} {
+		if got := pages(test.query); !slices.Equal(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+
+	params, _ := parse(t, "limit=2&offset=1&hidden=false")
+	var items []item
+	meta, err := params.Find(db, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Total != 5 || len(items) != 2 || items[0].Name != "c" || meta.NextCursor == "" {
+		t.Errorf("Offset page gave %+v with meta %+v", items, meta)
+	}
+}
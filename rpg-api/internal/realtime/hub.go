@@ -0,0 +1,92 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+// Message types pushed to the game table
+const (
+	MessageScene        = "scene"
+	MessageRevealAsset  = "reveal_asset"
+	MessageHandout      = "handout"
+	MessageDice         = "dice"
+	MessageText         = "message"
+	MessagePlayerJoined = "player_joined"
+	MessageEnded        = "ended"
+)
+
+type Message struct {
+	Type      string      `json:"type"`
+	SessionID uint        `json:"session_id"`
+	Payload   interface{} `json:"payload"`
+	SentAt    time.Time   `json:"sent_at"`
+}
+
+// PubSub fans messages out to everyone watching a play session. The in-process
+// Hub is enough for a single API instance; running several instances needs an
+// implementation backed by a shared broker instead.
+type PubSub interface {
+	Publish(sessionID uint, message Message)
+	// Subscribe returns a channel of messages and a function that must be
+	// called to stop receiving them
+	Subscribe(sessionID uint) (<-chan Message, func())
+}
+
+// Buffered so a slow reader doesn't hold up the publisher
+const subscriberBufferSize = 32
+
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[uint]map[chan Message]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[uint]map[chan Message]struct{}),
+	}
+}
+
+func (h *Hub) Publish(sessionID uint, message Message) {
+	message.SessionID = sessionID
+	if message.SentAt.IsZero() {
+		message.SentAt = time.Now()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers[sessionID] {
+		select {
+		case ch <- message:
+		default:
+			// Drop the message rather than block every other subscriber
+		}
+	}
+}
+
+func (h *Hub) Subscribe(sessionID uint) (<-chan Message, func()) {
+	ch := make(chan Message, subscriberBufferSize)
+
+	h.mu.Lock()
+	if h.subscribers[sessionID] == nil {
+		h.subscribers[sessionID] = make(map[chan Message]struct{})
+	}
+	h.subscribers[sessionID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[sessionID], ch)
+			if len(h.subscribers[sessionID]) == 0 {
+				delete(h.subscribers, sessionID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
@@ -0,0 +1,269 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Searchable content types
+const (
+	TypeAdventure     = "adventure"
+	TypeScene         = "scene"
+	TypeTitlePage     = "title_page"
+	TypeWorld         = "world"
+	TypeTimelineEvent = "timeline_event"
+	TypeAsset         = "asset"
+	TypeNPC           = "npc"
+	TypeLoreArticle   = "lore_article"
+)
+
+// All text is indexed with the English dictionary so stemming matches
+// "dragons" to "dragon"
+const config = "english"
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Snippets wrap matches in <mark>. The source text is HTML-escaped first, so
+// the mark tags are the only markup a snippet can contain.
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// index describes the search_vector column kept on one table. Weighted parts
+// rank title matches (A) above body text (B, C).
+type index struct {
+	Type    string
+	Table   string
+	Vector  []weighted
+	Snippet []string // Columns the snippet is drawn from
+}
+
+type weighted struct {
+	Column string
+	Weight string
+}
+
+var indexes = []index{
+	{TypeAdventure, "adventures", []weighted{{"title", "A"}, {"description", "B"}}, []string{"description"}},
+	{TypeScene, "scenes", []weighted{{"title", "A"}, {"prose", "B"}}, []string{"prose"}},
+	{TypeTitlePage, "title_pages", []weighted{{"title", "A"}, {"subtitle", "A"}, {"introduction", "B"}, {"background", "B"}, {"prologue", "B"}},
+		[]string{"introduction", "background", "prologue"}},
+	{TypeWorld, "worlds", []weighted{{"title", "A"}, {"description", "B"}}, []string{"description"}},
+	{TypeTimelineEvent, "timeline_events", []weighted{{"title", "A"}, {"description", "B"}, {"details", "C"}}, []string{"description", "details"}},
+	{TypeAsset, "assets", []weighted{{"name", "A"}, {"description", "B"}}, []string{"description"}},
+	{TypeNPC, "npcs", []weighted{{"name", "A"}, {"profession", "B"}, {"personality", "C"}}, []string{"profession", "personality"}},
+	{TypeLoreArticle, "lore_articles", []weighted{{"title", "A"}, {"summary", "B"}, {"body", "B"}}, []string{"summary", "body"}},
+}
+
+// Types lists every searchable type in display order
+func Types() []string {
+	types := make([]string, len(indexes))
+	for i, idx := range indexes {
+		types[i] = idx.Type
+	}
+	return types
+}
+
+func (idx index) vectorSQL() string {
+	parts := make([]string, len(idx.Vector))
+	for i, part := range idx.Vector {
+		parts[i] = fmt.Sprintf("setweight(to_tsvector('%s', coalesce(%s, '')), '%s')", config, part.Column, part.Weight)
+	}
+	return strings.Join(parts, " || ")
+}
+
+// Text the snippet is built from, escaped so user content can't inject markup
+func (idx index) snippetSQL(alias string) string {
+	parts := make([]string, len(idx.Snippet))
+	for i, column := range idx.Snippet {
+		parts[i] = fmt.Sprintf("coalesce(%s.%s, '')", alias, column)
+	}
+	text := strings.Join(parts, " || ' ' || ")
+	return fmt.Sprintf("replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')", text)
+}
+
+// Migrate adds a generated search_vector column and GIN index to every
+// searchable table. Postgres keeps the column up to date on every write, so
+// the models don't need to know it exists. Safe to run on every start.
+func Migrate(db *gorm.DB) error {
+	for _, idx := range indexes {
+		statements := []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (%s) STORED",
+				idx.Table, idx.vectorSQL()),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_search_vector ON %s USING GIN (search_vector)", idx.Table, idx.Table),
+		}
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to add search index to %s: %w", idx.Table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Result is one ranked match. Scenes, title pages, timeline events, NPCs and
+// lore articles carry the adventure or world they belong to so clients can
+// link to them.
+type Result struct {
+	Type        string  `json:"type"`
+	ID          uint    `json:"id"`
+	Title       string  `json:"title"`
+	Snippet     string  `json:"snippet"`
+	Rank        float64 `json:"rank"`
+	ParentType  string  `json:"parent_type,omitempty"`
+	ParentID    *uint   `json:"parent_id,omitempty"`
+	ParentTitle string  `json:"parent_title,omitempty"`
+}
+
+// Options narrows a search
+type Options struct {
+	Types []string // Empty searches everything
+	Limit int
+}
+
+// Search runs the query against every requested type and merges the results
+// by rank. Only content the viewer could open through the API is returned;
+// viewer is nil for anonymous requests.
+func Search(db *gorm.DB, q string, viewer *models.User, opts Options) ([]Result, error) {
+	limit := opts.Limit
+	if limit < 1 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	wanted := map[string]bool{}
+	for _, t := range opts.Types {
+		wanted[t] = true
+	}
+
+	results := []Result{}
+	for _, idx := range indexes {
+		if len(wanted) > 0 && !wanted[idx.Type] {
+			continue
+		}
+
+		var found []Result
+		sql, args := idx.searchSQL(q, viewer, limit)
+		if err := db.Raw(sql, args...).Scan(&found).Error; err != nil {
+			return nil, fmt.Errorf("failed to search %s: %w", idx.Table, err)
+		}
+		for i := range found {
+			found[i].Type = idx.Type
+		}
+		results = append(results, found...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank > results[j].Rank
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// Builds the ranked query for one type, joined to whatever decides its
+// visibility: adventures for scenes and title pages, worlds for timeline
+// events, NPCs and lore articles
+func (idx index) searchSQL(q string, viewer *models.User, limit int) (string, []interface{}) {
+	var title, from, parentType, visible string
+	parent := "NULL AS parent_id, NULL AS parent_title"
+	var args []interface{}
+
+	switch idx.Type {
+	case TypeAdventure:
+		title = "t.title"
+		from = "adventures t"
+		visible, args = adventureVisible("t", viewer)
+	case TypeScene:
+		title = "t.title"
+		from = "scenes t JOIN episodes e ON e.id = t.episode_id JOIN adventures a ON a.id = e.adventure_id"
+		parent = "a.id AS parent_id, a.title AS parent_title"
+		parentType = TypeAdventure
+		visible, args = adventureVisible("a", viewer)
+	case TypeTitlePage:
+		title = "coalesce(nullif(t.title, ''), a.title)"
+		from = "title_pages t JOIN adventures a ON a.id = t.adventure_id"
+		parent = "a.id AS parent_id, a.title AS parent_title"
+		parentType = TypeAdventure
+		visible, args = adventureVisible("a", viewer)
+	case TypeWorld:
+		title = "t.title"
+		from = "worlds t"
+		visible, args = worldVisible("t", viewer)
+	case TypeTimelineEvent:
+		title = "t.title"
+		from = "timeline_events t JOIN worlds w ON w.id = t.world_id"
+		parent = "w.id AS parent_id, w.title AS parent_title"
+		parentType = TypeWorld
+		visible, args = worldVisible("w", viewer)
+	case TypeAsset:
+		title = "t.name"
+		from = "assets t"
+		visible, args = assetVisible("t", viewer)
+	case TypeNPC:
+		title = "t.name"
+		from = "npcs t JOIN worlds w ON w.id = t.world_id"
+		parent = "w.id AS parent_id, w.title AS parent_title"
+		parentType = TypeWorld
+		visible, args = worldVisible("w", viewer)
+	case TypeLoreArticle:
+		title = "t.title"
+		from = "lore_articles t JOIN worlds w ON w.id = t.world_id"
+		parent = "w.id AS parent_id, w.title AS parent_title"
+		parentType = TypeWorld
+		visible, args = worldVisible("w", viewer)
+	}
+
+	sql := fmt.Sprintf(`
+		WITH query AS (SELECT websearch_to_tsquery('%[1]s', ?) AS q)
+		SELECT t.id AS id,
+			%[2]s AS title,
+			ts_headline('%[1]s', %[3]s, query.q, '%[4]s') AS snippet,
+			ts_rank(t.search_vector, query.q) AS rank,
+			'%[5]s' AS parent_type,
+			%[6]s
+		FROM %[7]s, query
+		WHERE t.search_vector @@ query.q AND %[8]s
+		ORDER BY rank DESC, t.id
+		LIMIT ?`,
+		config, title, idx.snippetSQL("t"), headlineOptions, parentType,
+		parent, from, visible)
+
+	return sql, append(append([]interface{}{q}, args...), limit)
+}
+
+// Official adventures or the viewer's own, as in GET /adventures/:id
+func adventureVisible(alias string, viewer *models.User) (string, []interface{}) {
+	if viewer == nil {
+		return fmt.Sprintf("%[1]s.deleted_at IS NULL AND %[1]s.user_id IS NULL", alias), nil
+	}
+	return fmt.Sprintf("%[1]s.deleted_at IS NULL AND (%[1]s.user_id IS NULL OR %[1]s.user_id = ?)", alias), []interface{}{viewer.ID}
+}
+
+// Official or reviewed worlds, or the viewer's own. Admins see every world.
+func worldVisible(alias string, viewer *models.User) (string, []interface{}) {
+	if viewer == nil {
+		return fmt.Sprintf("%[1]s.deleted_at IS NULL AND (%[1]s.is_official OR %[1]s.reviewed)", alias), nil
+	}
+	if viewer.IsAdmin {
+		return fmt.Sprintf("%s.deleted_at IS NULL", alias), nil
+	}
+	return fmt.Sprintf("%[1]s.deleted_at IS NULL AND (%[1]s.is_official OR %[1]s.reviewed OR %[1]s.user_id = ?)", alias), []interface{}{viewer.ID}
+}
+
+// Official assets or the viewer's own
+func assetVisible(alias string, viewer *models.User) (string, []interface{}) {
+	if viewer == nil {
+		return fmt.Sprintf("%[1]s.deleted_at IS NULL AND %[1]s.is_official", alias), nil
+	}
+	return fmt.Sprintf("%[1]s.deleted_at IS NULL AND (%[1]s.is_official OR %[1]s.user_id = ?)", alias), []interface{}{viewer.ID}
+}
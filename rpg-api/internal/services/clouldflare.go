@@ -9,6 +9,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"os"
+	"time"
 )
 
 type CloudflareImagesService struct {
@@ -24,6 +25,7 @@ type CloudflareImageResponse struct {
 		Filename string            `json:"filename"`
 		Variants []string          `json:"variants"`
 		Meta     map[string]string `json:"meta"`
+		Draft    bool              `json:"draft"` // Direct upload issued but nothing uploaded yet
 	} `json:"result"`
 	Success bool `json:"success"`
 	Errors  []struct {
@@ -41,7 +43,7 @@ func NewCloudflareImagesService() *CloudflareImagesService {
 	}
 }
 
-func (s *CloudflareImagesService) UploadImage(file multipart.File, filename string, metadata map[string]string) (*CloudflareImageResponse, error) {
+func (s *CloudflareImagesService) Upload(file io.Reader, filename string, metadata map[string]string) (*StoredImage, error) {
 	// Create multipart form
 	var b bytes.Buffer
 	w := multipart.NewWriter(&b)
@@ -66,15 +68,35 @@ func (s *CloudflareImagesService) UploadImage(file multipart.File, filename stri
 
 	w.Close()
 
+	return s.createImage(&b, w.FormDataContentType())
+}
+
+// UploadFromURL asks Cloudflare to fetch the image itself, so the API
+// never downloads arbitrary URLs
+func (s *CloudflareImagesService) UploadFromURL(imageURL string, metadata map[string]string) (*StoredImage, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+
+	w.WriteField("url", imageURL)
+	for key, value := range metadata {
+		w.WriteField(key, value)
+	}
+
+	w.Close()
+
+	return s.createImage(&b, w.FormDataContentType())
+}
+
+func (s *CloudflareImagesService) createImage(body io.Reader, contentType string) (*StoredImage, error) {
 	// Create request
 	url := fmt.Sprintf("%s/accounts/%s/images/v1", s.BaseURL, s.AccountID)
-	req, err := http.NewRequest("POST", url, &b)
+	req, err := http.NewRequest("POST", url, body)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+s.APIToken)
-	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Content-Type", contentType)
 
 	// Make request
 	client := &http.Client{}
@@ -97,17 +119,164 @@ func (s *CloudflareImagesService) UploadImage(file multipart.File, filename stri
 		return nil, fmt.Errorf("unknown cloudflare error")
 	}
 
-	return &cfResp, nil
+	return &StoredImage{ID: cfResp.Result.ID, Filename: cfResp.Result.Filename}, nil
+}
+
+// NewDirectUpload reserves an image ID and a one-time URL the browser can
+// post the file to. Cloudflare accepts expiries between 2 minutes and 6 hours.
+func (s *CloudflareImagesService) NewDirectUpload(expiresAt time.Time, metadata map[string]string) (*DirectUpload, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+
+	w.WriteField("expiry", expiresAt.UTC().Format(time.RFC3339))
+	if len(metadata) > 0 {
+		meta, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, err
+		}
+		w.WriteField("metadata", string(meta))
+	}
+	w.Close()
+
+	url := fmt.Sprintf("%s/accounts/%s/images/v2/direct_upload", s.BaseURL, s.AccountID)
+	req, err := http.NewRequest("POST", url, &b)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.APIToken)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var cfResp struct {
+		Result struct {
+			ID        string `json:"id"`
+			UploadURL string `json:"uploadURL"`
+		} `json:"result"`
+		Success bool `json:"success"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&cfResp); err != nil {
+		return nil, err
+	}
+
+	if !cfResp.Success {
+		if len(cfResp.Errors) > 0 {
+			return nil, fmt.Errorf("cloudflare error: %s", cfResp.Errors[0].Message)
+		}
+		return nil, fmt.Errorf("unknown cloudflare error")
+	}
+
+	return &DirectUpload{ImageID: cfResp.Result.ID, UploadURL: cfResp.Result.UploadURL, ExpiresAt: expiresAt}, nil
+}
+
+// Lookup fetches an image's details, failing with ErrImageNotUploaded while
+// a direct upload is still a draft. The details don't include the size;
+// Fetch the original to measure it.
+func (s *CloudflareImagesService) Lookup(imageID string) (*StoredImage, error) {
+	url := fmt.Sprintf("%s/accounts/%s/images/v1/%s", s.BaseURL, s.AccountID, imageID)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.APIToken)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrImageNotUploaded
+	}
+
+	var cfResp CloudflareImageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&cfResp); err != nil {
+		return nil, err
+	}
+
+	if !cfResp.Success {
+		if len(cfResp.Errors) > 0 {
+			return nil, fmt.Errorf("cloudflare error: %s", cfResp.Errors[0].Message)
+		}
+		return nil, fmt.Errorf("unknown cloudflare error")
+	}
+	if cfResp.Result.Draft {
+		return nil, ErrImageNotUploaded
+	}
+
+	return &StoredImage{ID: cfResp.Result.ID, Filename: cfResp.Result.Filename}, nil
+}
+
+// Fetch downloads an image's original, stopping after maxBytes+1 bytes
+func (s *CloudflareImagesService) Fetch(imageID string, maxBytes int64) ([]byte, error) {
+	url := fmt.Sprintf("%s/accounts/%s/images/v1/%s/blob", s.BaseURL, s.AccountID, imageID)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.APIToken)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch image: status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+}
+
+// Replace swaps an image's file. Cloudflare can't overwrite an image, so the
+// old one is deleted and the new one uploaded with the same custom ID.
+func (s *CloudflareImagesService) Replace(imageID string, file io.Reader, filename string) (*StoredImage, error) {
+	if err := s.Delete(imageID); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+
+	w.WriteField("id", imageID)
+	fw, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(fw, file); err != nil {
+		return nil, err
+	}
+
+	w.Close()
+
+	return s.createImage(&b, w.FormDataContentType())
 }
 
-func (s *CloudflareImagesService) GetImageURL(imageID string, variant string) string {
+func (s *CloudflareImagesService) VariantURL(imageID string, variant string) string {
 	if variant == "" {
-		variant = "public"
+		variant = VariantOriginal
 	}
 	return fmt.Sprintf("https://imagedelivery.net/%s/%s/%s", s.DeliveryHash, imageID, variant)
 }
 
-func (s *CloudflareImagesService) DeleteImage(imageID string) error {
+func (s *CloudflareImagesService) Delete(imageID string) error {
 	url := fmt.Sprintf("%s/accounts/%s/images/v1/%s", s.BaseURL, s.AccountID, imageID)
 
 	req, err := http.NewRequest("DELETE", url, nil)
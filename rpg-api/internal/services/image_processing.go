@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	_ "golang.org/x/image/webp" // Register WebP decoding
+)
+
+// Upload limits used when the environment doesn't set them
+const (
+	DefaultMaxImageMB        = 10
+	DefaultMaxImageDimension = 8000
+	DefaultMaxImageMegapixel = 40
+)
+
+// Returned by PrepareImage. Handlers map them to 413 and 415.
+var (
+	ErrImageTooLarge    = errors.New("image too large")
+	ErrUnsupportedImage = errors.New("unsupported image type")
+)
+
+// ImageRules are the limits every uploaded image is checked against
+type ImageRules struct {
+	MaxBytes     int64
+	MaxDimension int  // Longest allowed edge in pixels
+	MaxPixels    int  // Width times height, guards against decompression bombs
+	ConvertWebP  bool // Re-encode everything as WebP before storing
+}
+
+// ProcessedImage is a validated image re-encoded without its metadata
+type ProcessedImage struct {
+	Data     []byte
+	Format   string // jpeg, png or webp
+	Filename string // Original name with the extension of Format
+	Width    int
+	Height   int
+}
+
+// ImageRulesFromEnv reads IMAGE_MAX_UPLOAD_MB, IMAGE_MAX_DIMENSION,
+// IMAGE_MAX_MEGAPIXELS and IMAGE_CONVERT_WEBP, falling back to the defaults
+func ImageRulesFromEnv() ImageRules {
+	return ImageRules{
+		MaxBytes:     int64(envInt("IMAGE_MAX_UPLOAD_MB", DefaultMaxImageMB)) << 20,
+		MaxDimension: envInt("IMAGE_MAX_DIMENSION", DefaultMaxImageDimension),
+		MaxPixels:    envInt("IMAGE_MAX_MEGAPIXELS", DefaultMaxImageMegapixel) * 1_000_000,
+		ConvertWebP:  os.Getenv("IMAGE_CONVERT_WEBP") == "true",
+	}
+}
+
+func envInt(name string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// InspectImage checks an image's magic bytes and header against the rules
+// without decoding the pixels. It returns the sniffed format.
+func InspectImage(data []byte, rules ImageRules) (string, image.Config, error) {
+	if int64(len(data)) > rules.MaxBytes {
+		return "", image.Config{}, fmt.Errorf("%w: the limit is %d MB", ErrImageTooLarge, rules.MaxBytes>>20)
+	}
+
+	format := sniffImageFormat(data)
+	if format == "" {
+		return "", image.Config{}, fmt.Errorf("%w: only JPEG, PNG and WebP are allowed", ErrUnsupportedImage)
+	}
+
+	// The header is enough to catch decompression bombs before decoding
+	config, decoded, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || decoded != format {
+		return "", image.Config{}, fmt.Errorf("%w: the file is not a valid %s image", ErrUnsupportedImage, strings.ToUpper(format))
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return "", image.Config{}, fmt.Errorf("%w: the image has no pixels", ErrUnsupportedImage)
+	}
+	if config.Width > rules.MaxDimension || config.Height > rules.MaxDimension {
+		return "", image.Config{}, fmt.Errorf("%w: images can be at most %d pixels on each side", ErrImageTooLarge, rules.MaxDimension)
+	}
+	if config.Width*config.Height > rules.MaxPixels {
+		return "", image.Config{}, fmt.Errorf("%w: images can be at most %d megapixels", ErrImageTooLarge, rules.MaxPixels/1_000_000)
+	}
+
+	return format, config, nil
+}
+
+// PrepareImage reads an upload, checks it against the rules and re-encodes
+// it. Re-encoding drops EXIF, GPS and every other piece of metadata, so JPEG
+// orientation is applied to the pixels first.
+func PrepareImage(file io.Reader, filename string, rules ImageRules) (*ProcessedImage, error) {
+	data, err := io.ReadAll(io.LimitReader(file, rules.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	format, _, err := InspectImage(data, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: the file is not a valid %s image", ErrUnsupportedImage, strings.ToUpper(format))
+	}
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	if rules.ConvertWebP {
+		format = "webp"
+	}
+
+	var out bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: 90})
+	case "png":
+		err = png.Encode(&out, img)
+	case "webp":
+		err = nativewebp.Encode(&out, img, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not re-encode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	return &ProcessedImage{
+		Data:     out.Bytes(),
+		Format:   format,
+		Filename: strings.TrimSuffix(filename, filepath.Ext(filename)) + "." + format,
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+	}, nil
+}
+
+// Identifies an image from its magic bytes rather than its name
+func sniffImageFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return "jpeg"
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return "webp"
+	}
+	return ""
+}
+
+// Finds the EXIF orientation tag of a JPEG, 1 (upright) when there is none
+func jpegOrientation(data []byte) int {
+	// Walk the segments after the start of image marker
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // Start of scan, end of image
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// Reads tag 0x0112 from the first IFD of a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < entries; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// Rotates and mirrors an image so it displays upright without its EXIF
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 { // The four orientations that swap width and height
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // Rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // Mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // Transposed
+				dx, dy = y, x
+			case 6: // Rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // Transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // Rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// Variants every image store serves. "public" is the untouched original,
+// named after the Cloudflare Images default variant.
+const (
+	VariantThumbnail = "thumbnail" // 200px
+	VariantMedium    = "medium"    // 800px
+	VariantLarge     = "large"     // 1200px
+	VariantOriginal  = "public"
+)
+
+// StoredImage is what a store hands back after an upload
+type StoredImage struct {
+	ID       string
+	Filename string
+	Bytes    int64 // Zero when the store doesn't report sizes
+}
+
+// ImageStore is where uploaded images live. Handlers only ever talk to this
+// interface; the backend is picked by IMAGE_STORE at startup.
+type ImageStore interface {
+	Upload(file io.Reader, filename string, metadata map[string]string) (*StoredImage, error)
+	Delete(imageID string) error
+	VariantURL(imageID string, variant string) string
+}
+
+// URLUploader is implemented by stores that can fetch an image from a URL
+// themselves. The local store doesn't, so the API never downloads arbitrary URLs.
+type URLUploader interface {
+	UploadFromURL(imageURL string, metadata map[string]string) (*StoredImage, error)
+}
+
+// ImageFetcher is implemented by stores that take files the API never saw,
+// through a URL or a direct upload. Fetch reads back at most maxBytes+1
+// bytes of an original, so it can be checked like any other upload, and
+// Replace stores the checked copy under the same ID.
+type ImageFetcher interface {
+	Fetch(imageID string, maxBytes int64) ([]byte, error)
+	Replace(imageID string, file io.Reader, filename string) (*StoredImage, error)
+}
+
+// DirectUploader is implemented by stores the browser can upload to without
+// going through the API. NewDirectUpload hands out a one-time URL for a new
+// image ID; Lookup reports ErrImageNotUploaded until the file has arrived.
+type DirectUploader interface {
+	NewDirectUpload(expiresAt time.Time, metadata map[string]string) (*DirectUpload, error)
+	Lookup(imageID string) (*StoredImage, error)
+}
+
+// DirectUpload is a reserved image ID and where to send its file
+type DirectUpload struct {
+	ImageID   string    `json:"image_id"`
+	UploadURL string    `json:"upload_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var ErrImageNotUploaded = errors.New("image has not been uploaded")
+
+// Every backend satisfies the interface
+var (
+	_ ImageStore     = (*CloudflareImagesService)(nil)
+	_ ImageStore     = (*LocalImageStore)(nil)
+	_ ImageStore     = (*MemoryImageStore)(nil)
+	_ URLUploader    = (*CloudflareImagesService)(nil)
+	_ URLUploader    = (*MemoryImageStore)(nil)
+	_ ImageFetcher   = (*CloudflareImagesService)(nil)
+	_ ImageFetcher   = (*MemoryImageStore)(nil)
+	_ DirectUploader = (*CloudflareImagesService)(nil)
+	_ DirectUploader = (*LocalImageStore)(nil)
+	_ DirectUploader = (*MemoryImageStore)(nil)
+)
+
+// ImageURLs are the URLs for every variant of one image
+type ImageURLs struct {
+	Thumbnail string `json:"thumbnail"`
+	Medium    string `json:"medium"`
+	Large     string `json:"large"`
+	Original  string `json:"original"`
+}
+
+func VariantURLs(store ImageStore, imageID string) ImageURLs {
+	return ImageURLs{
+		Thumbnail: store.VariantURL(imageID, VariantThumbnail),
+		Medium:    store.VariantURL(imageID, VariantMedium),
+		Large:     store.VariantURL(imageID, VariantLarge),
+		Original:  store.VariantURL(imageID, VariantOriginal),
+	}
+}
+
+// NewImageStore builds the backend named by IMAGE_STORE: "cloudflare" (the
+// default) or "local" for self-hosting and offline development
+func NewImageStore() ImageStore {
+	switch os.Getenv("IMAGE_STORE") {
+	case "local":
+		return NewLocalImageStore()
+	case "", "cloudflare":
+		return NewCloudflareImagesService()
+	default:
+		log.Printf("Warning: Unknown IMAGE_STORE %q, using Cloudflare", os.Getenv("IMAGE_STORE"))
+		return NewCloudflareImagesService()
+	}
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register WebP decoding
+)
+
+// Largest upload the local store will decode
+const maxLocalImageBytes = 20 << 20
+
+// Longest edge of each generated variant
+var localVariantSizes = map[string]int{
+	VariantThumbnail: 200,
+	VariantMedium:    800,
+	VariantLarge:     1200,
+}
+
+// LocalImageStore keeps images on disk and generates the resized variants
+// itself. Each image gets a directory holding the original and one file per
+// variant; the API serves them from GET /images/:id/:variant. Direct uploads
+// go to POST /images/upload/:id with an HMAC signature standing in for auth.
+type LocalImageStore struct {
+	Dir     string // Where images are written
+	BaseURL string // Public URL of the /images route
+	Secret  []byte // Signs direct upload URLs
+}
+
+var ErrInvalidUploadSignature = errors.New("upload URL is invalid or has expired")
+
+func NewLocalImageStore() *LocalImageStore {
+	dir := os.Getenv("IMAGE_STORAGE_DIR")
+	if dir == "" {
+		dir = "uploads/images"
+	}
+	baseURL := os.Getenv("IMAGE_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080/images"
+	}
+	secret := os.Getenv("IMAGE_UPLOAD_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	return &LocalImageStore{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/"), Secret: []byte(secret)}
+}
+
+func (s *LocalImageStore) Upload(file io.Reader, filename string, metadata map[string]string) (*StoredImage, error) {
+	id, err := newLocalImageID()
+	if err != nil {
+		return nil, err
+	}
+	return s.save(id, file, filename)
+}
+
+// UploadAs stores the file for a direct upload's reserved ID. Each ID can
+// only be uploaded once.
+func (s *LocalImageStore) UploadAs(imageID string, file io.Reader, filename string) (*StoredImage, error) {
+	if !validLocalImageID(imageID) {
+		return nil, ErrInvalidUploadSignature
+	}
+	return s.save(imageID, file, filename)
+}
+
+// Writes the original and every variant into the image's directory
+func (s *LocalImageStore) save(id string, file io.Reader, filename string) (*StoredImage, error) {
+	data, err := io.ReadAll(io.LimitReader(file, maxLocalImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxLocalImageBytes {
+		return nil, fmt.Errorf("image is larger than %d MB", maxLocalImageBytes>>20)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not read image: %w", err)
+	}
+
+	// Mkdir rather than MkdirAll so a reused direct upload ID fails
+	dir := filepath.Join(s.Dir, id)
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("image %s has already been uploaded", id)
+		}
+		return nil, err
+	}
+
+	// The original is kept byte for byte
+	if err := os.WriteFile(filepath.Join(dir, VariantOriginal+"."+format), data, 0o644); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	// PNGs keep their transparency, everything else becomes JPEG
+	for variant, size := range localVariantSizes {
+		var path string
+		var encode func(io.Writer, image.Image) error
+		if format == "png" {
+			path = filepath.Join(dir, variant+".png")
+			encode = png.Encode
+		} else {
+			path = filepath.Join(dir, variant+".jpeg")
+			encode = func(w io.Writer, m image.Image) error {
+				return jpeg.Encode(w, m, &jpeg.Options{Quality: 85})
+			}
+		}
+
+		if err := writeImage(path, resizeToFit(img, size), encode); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	}
+
+	return &StoredImage{ID: id, Filename: filename, Bytes: int64(len(data))}, nil
+}
+
+// NewDirectUpload reserves an ID and signs a URL for uploading it. Nothing
+// is written until the file arrives.
+func (s *LocalImageStore) NewDirectUpload(expiresAt time.Time, metadata map[string]string) (*DirectUpload, error) {
+	if len(s.Secret) == 0 {
+		return nil, fmt.Errorf("IMAGE_UPLOAD_SECRET is not set")
+	}
+	id, err := newLocalImageID()
+	if err != nil {
+		return nil, err
+	}
+
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{"expires": {expires}, "signature": {s.sign(id, expires)}}
+	return &DirectUpload{
+		ImageID:   id,
+		UploadURL: fmt.Sprintf("%s/upload/%s?%s", s.BaseURL, id, query.Encode()),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// VerifyDirectUpload checks the signature and expiry of a direct upload URL
+func (s *LocalImageStore) VerifyDirectUpload(imageID, expires, signature string) error {
+	if len(s.Secret) == 0 || !validLocalImageID(imageID) {
+		return ErrInvalidUploadSignature
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return ErrInvalidUploadSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(imageID, expires))) {
+		return ErrInvalidUploadSignature
+	}
+	return nil
+}
+
+func (s *LocalImageStore) sign(imageID, expires string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(imageID + ":" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Lookup reports an image once its original has been written
+func (s *LocalImageStore) Lookup(imageID string) (*StoredImage, error) {
+	path, err := s.Path(imageID, VariantOriginal)
+	if err != nil {
+		return nil, ErrImageNotUploaded
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, ErrImageNotUploaded
+	}
+	return &StoredImage{ID: imageID, Filename: filepath.Base(path), Bytes: info.Size()}, nil
+}
+
+func (s *LocalImageStore) Delete(imageID string) error {
+	if !validLocalImageID(imageID) {
+		return fmt.Errorf("invalid image ID")
+	}
+	return os.RemoveAll(filepath.Join(s.Dir, imageID))
+}
+
+func (s *LocalImageStore) VariantURL(imageID string, variant string) string {
+	if variant == "" {
+		variant = VariantOriginal
+	}
+	return fmt.Sprintf("%s/%s/%s", s.BaseURL, imageID, variant)
+}
+
+// Path finds the file for one variant of an image, for serving it
+func (s *LocalImageStore) Path(imageID string, variant string) (string, error) {
+	if !validLocalImageID(imageID) {
+		return "", os.ErrNotExist
+	}
+	if _, ok := localVariantSizes[variant]; !ok && variant != VariantOriginal {
+		return "", os.ErrNotExist
+	}
+
+	matches, err := filepath.Glob(filepath.Join(s.Dir, imageID, variant+".*"))
+	if err != nil || len(matches) == 0 {
+		return "", os.ErrNotExist
+	}
+	return matches[0], nil
+}
+
+// Scales an image down so its longest edge is at most size. Smaller images
+// are left alone rather than blown up.
+func resizeToFit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Over, nil)
+	return resized
+}
+
+func writeImage(path string, img image.Image, encode func(io.Writer, image.Image) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func newLocalImageID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// IDs are 32 hex characters, which also keeps them from escaping the directory
+func validLocalImageID(imageID string) bool {
+	if len(imageID) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(imageID)
+	return err == nil
+}
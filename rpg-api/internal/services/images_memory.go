@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// MemoryImageStore keeps images in memory. It is a fake for tests and CI:
+// nothing is resized and everything is lost when the process exits.
+type MemoryImageStore struct {
+	mu       sync.Mutex
+	nextID   int
+	Images   map[string][]byte
+	Names    map[string]string
+	Reserved map[string]bool   // Direct uploads waiting for Complete
+	Remote   map[string][]byte // Files UploadFromURL can fetch, by URL
+}
+
+func NewMemoryImageStore() *MemoryImageStore {
+	return &MemoryImageStore{
+		Images:   make(map[string][]byte),
+		Names:    make(map[string]string),
+		Reserved: make(map[string]bool),
+		Remote:   make(map[string][]byte),
+	}
+}
+
+func (s *MemoryImageStore) Upload(file io.Reader, filename string, metadata map[string]string) (*StoredImage, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	id := fmt.Sprintf("memory-%d", s.nextID)
+	s.Images[id] = data
+	s.Names[id] = filename
+
+	return &StoredImage{ID: id, Filename: filename}, nil
+}
+
+func (s *MemoryImageStore) UploadFromURL(imageURL string, metadata map[string]string) (*StoredImage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.Remote[imageURL]
+	if !ok {
+		return nil, fmt.Errorf("could not fetch %s", imageURL)
+	}
+
+	s.nextID++
+	id := fmt.Sprintf("memory-%d", s.nextID)
+	s.Images[id] = data
+	s.Names[id] = imageURL
+
+	return &StoredImage{ID: id, Filename: imageURL}, nil
+}
+
+func (s *MemoryImageStore) NewDirectUpload(expiresAt time.Time, metadata map[string]string) (*DirectUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	id := fmt.Sprintf("memory-%d", s.nextID)
+	s.Reserved[id] = true
+
+	return &DirectUpload{ImageID: id, UploadURL: "memory://upload/" + id, ExpiresAt: expiresAt}, nil
+}
+
+// Complete stands in for the browser sending a direct upload's file
+func (s *MemoryImageStore) Complete(imageID string, data []byte, filename string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.Reserved[imageID] {
+		return fmt.Errorf("no direct upload for %s", imageID)
+	}
+	delete(s.Reserved, imageID)
+	s.Images[imageID] = data
+	s.Names[imageID] = filename
+	return nil
+}
+
+func (s *MemoryImageStore) Lookup(imageID string) (*StoredImage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.Images[imageID]
+	if !ok {
+		return nil, ErrImageNotUploaded
+	}
+	return &StoredImage{ID: imageID, Filename: s.Names[imageID], Bytes: int64(len(data))}, nil
+}
+
+func (s *MemoryImageStore) Fetch(imageID string, maxBytes int64) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.Images[imageID]
+	if !ok {
+		return nil, fmt.Errorf("image %s not found", imageID)
+	}
+	if int64(len(data)) > maxBytes {
+		data = data[:maxBytes+1]
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (s *MemoryImageStore) Replace(imageID string, file io.Reader, filename string) (*StoredImage, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.Images[imageID]; !ok {
+		return nil, fmt.Errorf("image %s not found", imageID)
+	}
+	s.Images[imageID] = data
+	s.Names[imageID] = filename
+
+	return &StoredImage{ID: imageID, Filename: filename, Bytes: int64(len(data))}, nil
+}
+
+func (s *MemoryImageStore) Delete(imageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Reserved[imageID] {
+		delete(s.Reserved, imageID)
+		return nil
+	}
+	if _, ok := s.Images[imageID]; !ok {
+		return fmt.Errorf("image %s not found", imageID)
+	}
+	delete(s.Images, imageID)
+	delete(s.Names, imageID)
+	return nil
+}
+
+func (s *MemoryImageStore) VariantURL(imageID string, variant string) string {
+	if variant == "" {
+		variant = VariantOriginal
+	}
+	return fmt.Sprintf("memory://%s/%s", imageID, variant)
+}
+
+// Has reports whether an image is still stored
+func (s *MemoryImageStore) Has(imageID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.Images[imageID]
+	return ok
+}
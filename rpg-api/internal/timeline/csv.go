@@ -0,0 +1,179 @@
+package timeline
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/naetharu/rpg-api/internal/models"
+)
+
+// CSVColumns are the columns of an exported timeline, in order. Imports can
+// have them in any order and leave out all but title and start_date.
+var CSVColumns = []string{"title", "start_date", "end_date", "era", "importance", "sort_order", "description", "details"}
+
+// Row is an event read from a CSV file. Number counts rows the way a
+// spreadsheet does, with the header as row 1.
+type Row struct {
+	Number       int
+	Event        models.TimelineEvent
+	HasSortOrder bool
+}
+
+// RowError is a problem with one row, or with the header when Row is 1
+type RowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
+
+// Spreadsheets run a cell starting with any of these as a formula
+const formulaStarts = "=+-@\t\r"
+
+// WriteCSV writes events one per row under a header of CSVColumns. Text that
+// a spreadsheet would run as a formula gets a leading ', which ReadCSV drops,
+// and so does text starting with a ' that ReadCSV would otherwise drop.
+func WriteCSV(w io.Writer, events []models.TimelineEvent) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(CSVColumns); err != nil {
+		return err
+	}
+	for _, event := range events {
+		endDate := ""
+		if event.EndDate != nil {
+			endDate = *event.EndDate
+		}
+		row := []string{
+			escapeCell(event.Title),
+			escapeCell(event.StartDate),
+			escapeCell(endDate),
+			escapeCell(event.Era),
+			escapeCell(event.Importance),
+			strconv.Itoa(event.SortOrder),
+			escapeCell(event.Description),
+			escapeCell(event.Details),
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// ReadCSV reads events written by WriteCSV, or drafted in a spreadsheet with
+// the same column names. Every row is checked and each problem reported,
+// rather than stopping at the first. err is only set when the file can't be
+// read as CSV at all or has more than maxRows rows.
+func ReadCSV(r io.Reader, maxRows int) (rows []Row, problems []RowError, err error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1 // Short rows are padded rather than rejected
+	in.TrimLeadingSpace = true
+
+	header, err := in.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch {
+		case name == "":
+			continue
+		case !slices.Contains(CSVColumns, name):
+			problems = append(problems, RowError{Row: 1, Column: name, Error: fmt.Sprintf("unknown column, expected %s", strings.Join(CSVColumns, ", "))})
+		case columns[name] != 0:
+			problems = append(problems, RowError{Row: 1, Column: name, Error: "column appears more than once"})
+		default:
+			columns[name] = i + 1
+		}
+	}
+	for _, name := range []string{"title", "start_date"} {
+		if columns[name] == 0 {
+			problems = append(problems, RowError{Row: 1, Column: name, Error: "required column is missing"})
+		}
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	for number := 2; ; number++ {
+		record, err := in.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rows) == maxRows {
+			return nil, nil, fmt.Errorf("a file can have at most %d rows", maxRows)
+		}
+
+		field := func(name string) string {
+			if i := columns[name]; i > 0 && i <= len(record) {
+				return unescapeCell(strings.TrimSpace(record[i-1]))
+			}
+			return ""
+		}
+		if strings.Join(record, "") == "" {
+			continue // Blank lines spreadsheets leave at the end
+		}
+
+		row := Row{Number: number}
+		event := &row.Event
+		fail := func(column, message string) {
+			problems = append(problems, RowError{Row: number, Column: column, Error: message})
+		}
+
+		if event.Title = field("title"); event.Title == "" {
+			fail("title", "title is required")
+		}
+		if event.StartDate = field("start_date"); event.StartDate == "" {
+			fail("start_date", "start_date is required")
+		}
+		if endDate := field("end_date"); endDate != "" {
+			event.EndDate = &endDate
+		}
+		event.Era = field("era")
+		event.Importance = strings.ToLower(field("importance"))
+		if event.Importance == "" {
+			event.Importance = models.ImportanceMinor
+		} else if !slices.Contains(models.Importances, event.Importance) {
+			fail("importance", fmt.Sprintf("importance must be %s", strings.Join(models.Importances, ", ")))
+		}
+		if sortOrder := field("sort_order"); sortOrder != "" {
+			n, err := strconv.Atoi(sortOrder)
+			if err != nil {
+				fail("sort_order", "sort_order must be a whole number")
+			}
+			event.SortOrder, row.HasSortOrder = n, true
+		}
+		event.Description = field("description")
+		event.Details = field("details")
+
+		rows = append(rows, row)
+	}
+	return rows, problems, nil
+}
+
+func escapeCell(text string) string {
+	if text != "" && strings.ContainsRune(formulaStarts, rune(text[0])) || unescapeCell(text) != text {
+		return "'" + text
+	}
+	return text
+}
+
+func unescapeCell(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.ContainsRune(formulaStarts+"'", rune(text[1])) {
+		return text[1:]
+	}
+	return text
+}
@@ -0,0 +1,66 @@
+package timeline
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/naetharu/rpg-api/internal/models"
+)
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	endDate := "-12"
+	events := []models.TimelineEvent{
+		{Title: "=HYPERLINK(\"https://example.com\")", StartDate: "+1", EndDate: &endDate, Era: "@era", Importance: models.ImportanceMajor, SortOrder: -3, Description: "-- notes", Details: "plain"},
+	}
+
+	var out bytes.Buffer
+	if err := WriteCSV(&out, events); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Got %d lines", len(lines))
+	}
+	want := `"'=HYPERLINK(""https://example.com"")",'+1,'-12,'@era,major,-3,'-- notes,plain`
+	if lines[1] != want {
+		t.Errorf("Got row\n%s\nwant\n%s", lines[1], want)
+	}
+
+	// Reading the file back gives the original text
+	rows, problems, err := ReadCSV(&out, 10)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("Read failed: %v %v", err, problems)
+	}
+	got := rows[0].Event
+	if got.Title != events[0].Title || got.StartDate != "+1" || *got.EndDate != "-12" || got.Era != "@era" ||
+		got.SortOrder != -3 || got.Description != "-- notes" || got.Details != "plain" {
+		t.Errorf("Read back %+v", got)
+	}
+}
+
+func TestCSVRoundTripKeepsText(t *testing.T) {
+	for _, text := range []string{
+		"\tcmd", "\r=1+1", "'=x", "''=x", "''", "'", "'Tis the season", "it's fine", "=", "plain",
+	} {
+		events := []models.TimelineEvent{{Title: text, StartDate: "1203", Description: text}}
+		var out bytes.Buffer
+		if err := WriteCSV(&out, events); err != nil {
+			t.Fatal(err)
+		}
+
+		// Nothing is left for a spreadsheet to run
+		cells := strings.SplitN(strings.Split(out.String(), "\n")[1], ",", 2)
+		if cell := strings.TrimPrefix(cells[0], `"`); cell != "" && strings.ContainsRune(formulaStarts, rune(cell[0])) {
+			t.Errorf("%q was written as %q", text, cells[0])
+		}
+
+		rows, problems, err := ReadCSV(&out, 10)
+		if err != nil || len(problems) > 0 {
+			t.Fatalf("%q: read failed: %v %v", text, err, problems)
+		}
+		if got := rows[0].Event; got.Title != text || got.Description != text {
+			t.Errorf("%q read back as %q and %q", text, got.Title, got.Description)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package timeline
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/naetharu/rpg-api/internal/models"
+)
+
+// WriteChronicle writes a world's history as Markdown: a section per era in
+// era order, then the events outside every era. Events keep the order given
+// within their section and eras without events are left out.
+func WriteChronicle(w io.Writer, title string, eras []models.WorldEra, events []models.TimelineEvent) error {
+	byEra := make(map[uint][]models.TimelineEvent)
+	var other []models.TimelineEvent
+	for _, event := range events {
+		if event.EraID != nil {
+			byEra[*event.EraID] = append(byEra[*event.EraID], event)
+		} else {
+			other = append(other, event)
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "# %s\n", title)
+	if len(events) == 0 {
+		fmt.Fprintf(out, "\nNothing has happened yet.\n")
+	}
+
+	for _, era := range eras {
+		if len(byEra[era.ID]) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\n## %s\n", era.Name)
+		if era.StartDate != "" {
+			fmt.Fprintf(out, "\n*%s*\n", span(era.StartDate, era.EndDate, "onwards"))
+		}
+		writeChronicleEvents(out, byEra[era.ID])
+	}
+	if len(other) > 0 {
+		if len(byEra) > 0 {
+			fmt.Fprintf(out, "\n## Other events\n")
+		}
+		writeChronicleEvents(out, other)
+	}
+	return out.Flush()
+}
+
+func writeChronicleEvents(out *bufio.Writer, events []models.TimelineEvent) {
+	for _, event := range events {
+		fmt.Fprintf(out, "\n### %s\n\n", event.Title)
+		fmt.Fprintf(out, "*%s*", span(event.StartDate, event.EndDate, ""))
+		if event.Importance == models.ImportanceCritical {
+			fmt.Fprintf(out, " · **Critical**")
+		}
+		fmt.Fprintln(out)
+
+		if who := participants(event); who != "" {
+			fmt.Fprintf(out, "\n%s\n", who)
+		}
+		if description := strings.TrimSpace(event.Description); description != "" {
+			fmt.Fprintf(out, "\n%s\n", description)
+		}
+	}
+}
+
+// "15 Frostfall 1203 – 3 Thawmoon 1204", or the start date alone
+func span(start string, end *string, openEnded string) string {
+	if end != nil && strings.TrimSpace(*end) != "" {
+		return start + " – " + *end
+	}
+	if openEnded != "" {
+		return start + " " + openEnded
+	}
+	return start
+}
+
+// "With Aldric (instigator) and the Iron Guild, at Varn"
+func participants(event models.TimelineEvent) string {
+	var with []string
+	for _, npc := range event.NPCs {
+		if npc.NPC == nil {
+			continue
+		}
+		name := npc.NPC.Name
+		if npc.Role != "" && npc.Role != models.DefaultEventRole {
+			name += " (" + npc.Role + ")"
+		}
+		with = append(with, name)
+	}
+	for _, organization := range event.Organizations {
+		with = append(with, organization.Name)
+	}
+	var at []string
+	for _, location := range event.Locations {
+		at = append(at, location.Name)
+	}
+
+	var parts []string
+	if len(with) > 0 {
+		parts = append(parts, "With "+join(with))
+	}
+	if len(at) > 0 {
+		parts = append(parts, "at "+join(at))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	text := strings.Join(parts, ", ")
+	return strings.ToUpper(text[:1]) + text[1:]
+}
+
+func join(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// Day 0 on the Gregorian calendar, see WriteICS
+var gregorianEpoch = time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteICS writes events as all-day iCalendar events. Day numbers are laid
+// onto the Gregorian calendar with day 0 as 1 January of year 1, so a world
+// calendar set up as Gregorian gives real dates and any other keeps its
+// order and spacing. The world's own dates go in each description. Events
+// without day numbers, or that fall outside years 1 to 9999, are left out
+// and counted in skipped.
+func WriteICS(w io.Writer, name string, worldID uint, events []models.TimelineEvent, now time.Time) (skipped int, err error) {
+	out := bufio.NewWriter(w)
+	line := func(text string) {
+		// Lines longer than 75 octets are folded onto lines starting with a space
+		for len(text) > 75 {
+			cut := 75
+			for cut > 0 && !utf8Start(text[cut]) {
+				cut--
+			}
+			out.WriteString(text[:cut] + "\r\n")
+			text = " " + text[cut:]
+		}
+		out.WriteString(text + "\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//rpg-api//Timeline Export//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:" + escapeText(name))
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, event := range events {
+		if event.StartDay == nil || event.EndDay == nil {
+			skipped++
+			continue
+		}
+		start := gregorianEpoch.AddDate(0, 0, int(*event.StartDay))
+		end := gregorianEpoch.AddDate(0, 0, int(*event.EndDay)+1) // DTEND is the day after
+		if start.Year() < 1 || end.Year() > 9999 {
+			skipped++
+			continue
+		}
+
+		description := span(event.StartDate, event.EndDate, "")
+		if text := strings.TrimSpace(event.Description); text != "" {
+			description += "\n\n" + text
+		}
+
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:timeline-event-%d-world-%d@rpg-api", event.ID, worldID))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + start.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + end.Format("20060102"))
+		line("SUMMARY:" + escapeText(event.Title))
+		line("DESCRIPTION:" + escapeText(description))
+		if event.Era != "" {
+			line("CATEGORIES:" + escapeText(event.Era))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return skipped, out.Flush()
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(text string) string {
+	return icsEscaper.Replace(text)
+}
+
+// Whether a byte starts a UTF-8 character, so folding never splits one
+func utf8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
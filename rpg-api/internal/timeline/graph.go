@@ -0,0 +1,208 @@
+// Package timeline checks the links between timeline events for loops and
+// for dates that contradict them.
+package timeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/naetharu/rpg-api/internal/models"
+)
+
+// Issue kinds
+const (
+	IssueCycle            = "cycle"              // Events that cause, or are part of, themselves
+	IssueCauseAfterEffect = "cause_after_effect" // A cause that starts after its effect
+	IssueOutsideParent    = "outside_parent"     // A sub-event dated outside the event it is part of
+)
+
+// Issue is a problem with some links. Nothing stops a world having them, but
+// editors should be told.
+type Issue struct {
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+	EventIDs []uint `json:"event_ids"`
+	LinkIDs  []uint `json:"link_ids"`
+}
+
+// Path finds the events along a chain of links of one type leading from one
+// event to another, nil when there is none. Adding a link the other way
+// round would close a loop.
+func Path(links []models.TimelineEventLink, linkType string, from, to uint) []uint {
+	next := adjacency(links, linkType)
+	previous := map[uint]uint{from: from}
+	queue := []uint{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == to {
+			path := []uint{to}
+			for id != from {
+				id = previous[id]
+				path = append([]uint{id}, path...)
+			}
+			return path
+		}
+		for _, link := range next[id] {
+			if _, seen := previous[link.ToEventID]; !seen {
+				previous[link.ToEventID] = id
+				queue = append(queue, link.ToEventID)
+			}
+		}
+	}
+	return nil
+}
+
+// Check finds the loops in caused and part-of links, causes dated after their
+// effects and sub-events dated outside their whole. Dates are only compared
+// when both events have day numbers.
+func Check(events []models.TimelineEvent, links []models.TimelineEventLink) []Issue {
+	byID := make(map[uint]*models.TimelineEvent, len(events))
+	for i := range events {
+		byID[events[i].ID] = &events[i]
+	}
+
+	issues := []Issue{}
+	for _, linkType := range []string{models.LinkCaused, models.LinkPartOf} {
+		for _, loop := range cycles(events, links, linkType) {
+			issue := Issue{Kind: IssueCycle, EventIDs: loop, LinkIDs: []uint{}}
+			in := make(map[uint]bool, len(loop))
+			titles := make([]string, len(loop))
+			for i, id := range loop {
+				in[id] = true
+				if event := byID[id]; event != nil {
+					titles[i] = event.Title
+				}
+			}
+			for _, link := range links {
+				if link.Type == linkType && in[link.FromEventID] && in[link.ToEventID] {
+					issue.LinkIDs = append(issue.LinkIDs, link.ID)
+				}
+			}
+			if linkType == models.LinkCaused {
+				issue.Message = fmt.Sprintf("%s cause each other in a loop", list(titles))
+			} else {
+				issue.Message = fmt.Sprintf("%s are each part of one another in a loop", list(titles))
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	for _, link := range links {
+		from, to := byID[link.FromEventID], byID[link.ToEventID]
+		if from == nil || to == nil || from.StartDay == nil || to.StartDay == nil {
+			continue
+		}
+		switch link.Type {
+		case models.LinkCaused:
+			if *from.StartDay > *to.StartDay {
+				issues = append(issues, Issue{
+					Kind:     IssueCauseAfterEffect,
+					Message:  fmt.Sprintf("%q caused %q but starts after it", from.Title, to.Title),
+					EventIDs: []uint{from.ID, to.ID},
+					LinkIDs:  []uint{link.ID},
+				})
+			}
+		case models.LinkPartOf:
+			if *from.StartDay < *to.StartDay || to.EndDay != nil && from.EndDay != nil && *from.EndDay > *to.EndDay {
+				issues = append(issues, Issue{
+					Kind:     IssueOutsideParent,
+					Message:  fmt.Sprintf("%q is part of %q but is dated outside it", from.Title, to.Title),
+					EventIDs: []uint{from.ID, to.ID},
+					LinkIDs:  []uint{link.ID},
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// ForLink picks out the issues that involve a link
+func ForLink(issues []Issue, linkID uint) []Issue {
+	found := []Issue{}
+	for _, issue := range issues {
+		for _, id := range issue.LinkIDs {
+			if id == linkID {
+				found = append(found, issue)
+				break
+			}
+		}
+	}
+	return found
+}
+
+func adjacency(links []models.TimelineEventLink, linkType string) map[uint][]models.TimelineEventLink {
+	next := make(map[uint][]models.TimelineEventLink)
+	for _, link := range links {
+		if link.Type == linkType {
+			next[link.FromEventID] = append(next[link.FromEventID], link)
+		}
+	}
+	return next
+}
+
+// Finds the groups of events that reach each other through links of one
+// type, using Tarjan's strongly connected components
+func cycles(events []models.TimelineEvent, links []models.TimelineEventLink, linkType string) [][]uint {
+	next := adjacency(links, linkType)
+	index := make(map[uint]int)
+	low := make(map[uint]int)
+	onStack := make(map[uint]bool)
+	var stack []uint
+	var loops [][]uint
+
+	var visit func(id uint)
+	visit = func(id uint) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, link := range next[id] {
+			to := link.ToEventID
+			if _, seen := index[to]; !seen {
+				visit(to)
+				low[id] = min(low[id], low[to])
+			} else if onStack[to] {
+				low[id] = min(low[id], index[to])
+			}
+		}
+
+		if low[id] == index[id] {
+			var group []uint
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				group = append(group, top)
+				if top == id {
+					break
+				}
+			}
+			if len(group) > 1 {
+				sort.Slice(group, func(i, j int) bool { return group[i] < group[j] })
+				loops = append(loops, group)
+			}
+		}
+	}
+
+	for _, event := range events {
+		if _, seen := index[event.ID]; !seen {
+			visit(event.ID)
+		}
+	}
+	return loops
+}
+
+// "A", "B" and "C"
+func list(titles []string) string {
+	quoted := make([]string, len(titles))
+	for i, title := range titles {
+		quoted[i] = fmt.Sprintf("%q", title)
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " and " + quoted[len(quoted)-1]
+}
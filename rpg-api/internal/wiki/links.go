@@ -0,0 +1,178 @@
+package wiki
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Things a [[link]] can point at
+const (
+	TargetArticle       = "article"
+	TargetNPC           = "npc"
+	TargetOrganization  = "organization"
+	TargetLocation      = "location"
+	TargetTimelineEvent = "timeline_event"
+)
+
+// Unqualified links resolve to the first type with a match, in this order
+var targetOrder = []string{TargetArticle, TargetNPC, TargetOrganization, TargetLocation, TargetTimelineEvent}
+
+// Prefixes accepted in [[type:Name]] to pick the type explicitly
+var prefixes = map[string]string{
+	"article":      TargetArticle,
+	"lore":         TargetArticle,
+	"npc":          TargetNPC,
+	"organization": TargetOrganization,
+	"org":          TargetOrganization,
+	"location":     TargetLocation,
+	"event":        TargetTimelineEvent,
+}
+
+// [[Name]], [[Name|label]], [[npc:Name]] or [[npc:Name|label]]
+var linkPattern = regexp.MustCompile(`\[\[([^\[\]|]+)(?:\|([^\[\]]*))?\]\]`)
+
+// Link is one [[...]] in a body. Type is empty for unqualified links.
+type Link struct {
+	Raw   string `json:"raw"`
+	Type  string `json:"type,omitempty"`
+	Name  string `json:"name"`
+	Label string `json:"label"`
+}
+
+// Target is what a link resolves to
+type Target struct {
+	Type string `json:"type"`
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// ParseLinks finds every wiki link in a body, in order, without duplicates
+func ParseLinks(body string) []Link {
+	links := []Link{}
+	seen := map[string]bool{}
+	for _, match := range linkPattern.FindAllStringSubmatch(body, -1) {
+		link := Link{Raw: match[0], Name: strings.TrimSpace(match[1]), Label: strings.TrimSpace(match[2])}
+		if prefix, name, ok := strings.Cut(link.Name, ":"); ok {
+			if linkType, known := prefixes[strings.ToLower(strings.TrimSpace(prefix))]; known {
+				link.Type = linkType
+				link.Name = strings.TrimSpace(name)
+			}
+		}
+		if link.Name == "" {
+			continue
+		}
+		if link.Label == "" {
+			link.Label = link.Name
+		}
+
+		key := link.Type + ":" + strings.ToLower(link.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		links = append(links, link)
+	}
+	return links
+}
+
+// Resolver matches link names against everything in one world. Matching
+// ignores case; when two things of the same type share a name the oldest wins.
+type Resolver struct {
+	names map[string]map[string]Target // Type, then lowercased name
+}
+
+// NewResolver loads the names of every linkable thing in a world
+func NewResolver(db *gorm.DB, worldID uint) (*Resolver, error) {
+	r := &Resolver{names: make(map[string]map[string]Target)}
+
+	sources := []struct {
+		linkType string
+		model    interface{}
+		column   string
+	}{
+		{TargetArticle, &models.LoreArticle{}, "title"},
+		{TargetNPC, &models.NPC{}, "name"},
+		{TargetOrganization, &models.Organization{}, "name"},
+		{TargetLocation, &models.NPCLocation{}, "name"},
+		{TargetTimelineEvent, &models.TimelineEvent{}, "title"},
+	}
+	for _, source := range sources {
+		var rows []struct {
+			ID   uint
+			Name string
+		}
+		if err := db.Model(source.model).Select("id, "+source.column+" AS name").
+			Where("world_id = ?", worldID).Order("id").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+
+		byName := make(map[string]Target, len(rows))
+		for _, row := range rows {
+			key := strings.ToLower(strings.TrimSpace(row.Name))
+			if _, taken := byName[key]; !taken {
+				byName[key] = Target{Type: source.linkType, ID: row.ID, Name: row.Name}
+			}
+		}
+		r.names[source.linkType] = byName
+	}
+
+	return r, nil
+}
+
+// Resolve finds what a link points at
+func (r *Resolver) Resolve(link Link) (Target, bool) {
+	key := strings.ToLower(link.Name)
+	if link.Type != "" {
+		target, ok := r.names[link.Type][key]
+		return target, ok
+	}
+	for _, linkType := range targetOrder {
+		if target, ok := r.names[linkType][key]; ok {
+			return target, true
+		}
+	}
+	return Target{}, false
+}
+
+// Types lists every link target type
+func Types() []string {
+	return append([]string{}, targetOrder...)
+}
+
+// Lookup finds a linkable thing by type and ID
+func (r *Resolver) Lookup(linkType string, id uint) (Target, bool) {
+	for _, target := range r.names[linkType] {
+		if target.ID == id {
+			return target, true
+		}
+	}
+	return Target{}, false
+}
+
+// SaveLinks replaces an article's stored links with the ones in its body.
+// Pass the caller's transaction.
+func SaveLinks(tx *gorm.DB, article models.LoreArticle) error {
+	if err := tx.Where("article_id = ?", article.ID).Delete(&models.LoreLink{}).Error; err != nil {
+		return err
+	}
+
+	parsed := ParseLinks(article.Body)
+	if len(parsed) == 0 {
+		return nil
+	}
+	links := make([]models.LoreLink, len(parsed))
+	for i, link := range parsed {
+		links[i] = models.LoreLink{
+			WorldID:    article.WorldID,
+			ArticleID:  article.ID,
+			TargetType: link.Type,
+			TargetName: strings.ToLower(link.Name),
+			Raw:        link.Raw,
+			Label:      link.Label,
+		}
+	}
+	return tx.Create(&links).Error
+}
@@ -0,0 +1,100 @@
+package wiki
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/testdb"
+)
+
+func TestParseLinks(t *testing.T) {
+	for _, test := range []struct {
+		body string
+		want []Link
+	}{
+		{"No links here, [just brackets] and [[]]", []Link{}},
+		{"Ask [[Mira]] at [[ The Drowned Lantern ]].", []Link{
+			{Raw: "[[Mira]]", Name: "Mira", Label: "Mira"},
+			{Raw: "[[ The Drowned Lantern ]]", Name: "The Drowned Lantern", Label: "The Drowned Lantern"},
+		}},
+		{"[[Mira|the innkeeper]] and [[Mira| ]]", []Link{
+			{Raw: "[[Mira|the innkeeper]]", Name: "Mira", Label: "the innkeeper"},
+		}},
+		{"[[npc:Mira]] [[NPC: Mira]] [[mira]] [[Org:Tide Guild|guild]] [[lore:Tides]] [[location:Varn]] [[event:The Flood]] [[article:Tides]]", []Link{
+			{Raw: "[[npc:Mira]]", Type: TargetNPC, Name: "Mira", Label: "Mira"},
+			{Raw: "[[mira]]", Name: "mira", Label: "mira"},
+			{Raw: "[[Org:Tide Guild|guild]]", Type: TargetOrganization, Name: "Tide Guild", Label: "guild"},
+			{Raw: "[[lore:Tides]]", Type: TargetArticle, Name: "Tides", Label: "Tides"},
+			{Raw: "[[location:Varn]]", Type: TargetLocation, Name: "Varn", Label: "Varn"},
+			{Raw: "[[event:The Flood]]", Type: TargetTimelineEvent, Name: "The Flood", Label: "The Flood"},
+		}},
+		// Unknown prefixes are part of the name, so titles with colons still link
+		{"[[Book: The Tides]] [[spell:Fireball|fire]]", []Link{
+			{Raw: "[[Book: The Tides]]", Name: "Book: The Tides", Label: "Book: The Tides"},
+			{Raw: "[[spell:Fireball|fire]]", Name: "spell:Fireball", Label: "fire"},
+		}},
+		{"[[npc:]] [[ |label]] [[[Mira]]]", []Link{
+			{Raw: "[[Mira]]", Name: "Mira", Label: "Mira"},
+		}},
+	} {
+		if got := ParseLinks(test.body); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q:\ngot  %+v\nwant %+v", test.body, got, test.want)
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	db := testdb.Open(t, &models.LoreArticle{}, &models.NPC{}, &models.Organization{}, &models.NPCLocation{}, &models.TimelineEvent{})
+	world, other := uint(1), uint(2)
+	for _, row := range []interface{}{
+		&models.NPC{WorldID: world, Name: "Mira"},
+		&models.LoreArticle{WorldID: world, Title: "Mira"},
+		&models.NPC{WorldID: world, Name: "Varn"},
+		&models.NPCLocation{WorldID: world, Name: "Varn"},
+		&models.Organization{WorldID: world, Name: "Tide Guild"},
+		&models.Organization{WorldID: world, Name: "tide guild"},
+		&models.TimelineEvent{WorldID: world, Title: "The Flood"},
+		&models.NPC{WorldID: other, Name: "Oren"},
+	} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := NewResolver(db, world)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		link  string
+		want  Target
+		found bool
+	}{
+		// Unqualified links take the first type with a match
+		{"[[mira]]", Target{Type: TargetArticle, ID: 1, Name: "Mira"}, true},
+		{"[[Varn]]", Target{Type: TargetNPC, ID: 2, Name: "Varn"}, true},
+		{"[[The Flood]]", Target{Type: TargetTimelineEvent, ID: 1, Name: "The Flood"}, true},
+		// A prefix picks the type
+		{"[[npc:Mira]]", Target{Type: TargetNPC, ID: 1, Name: "Mira"}, true},
+		{"[[location:varn]]", Target{Type: TargetLocation, ID: 1, Name: "Varn"}, true},
+		{"[[event:Mira]]", Target{}, false},
+		// The oldest of two same-named things wins
+		{"[[org:TIDE GUILD]]", Target{Type: TargetOrganization, ID: 1, Name: "Tide Guild"}, true},
+		// Other worlds aren't linkable
+		{"[[Oren]]", Target{}, false},
+		{"[[Nobody]]", Target{}, false},
+	} {
+		got, found := r.Resolve(ParseLinks(test.link)[0])
+		if got != test.want || found != test.found {
+			t.Errorf("%s: got %+v, %v, want %+v, %v", test.link, got, found, test.want, test.found)
+		}
+	}
+
+	if target, ok := r.Lookup(TargetOrganization, 2); ok {
+		t.Errorf("Looked up the shadowed organization as %+v", target)
+	}
+	if target, ok := r.Lookup(TargetLocation, 1); !ok || target.Name != "Varn" {
+		t.Errorf("Looked up %+v, %v", target, ok)
+	}
+}
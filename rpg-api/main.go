@@ -8,8 +8,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/naetharu/rpg-api/internal/handlers"
+	"github.com/naetharu/rpg-api/internal/jobs"
 	"github.com/naetharu/rpg-api/internal/middleware"
 	"github.com/naetharu/rpg-api/internal/models"
+	"github.com/naetharu/rpg-api/internal/realtime"
+	"github.com/naetharu/rpg-api/internal/search"
+	"github.com/naetharu/rpg-api/internal/services"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -47,24 +51,72 @@ func main() {
 		&models.OrganizationMembership{},
 		&models.NPCRelationship{},
 		&models.NPCGenerationConfig{},
+		&models.PlaySession{},
+		&models.PlaySessionPlayer{},
+		&models.PlaySessionEvent{},
+		&models.DiceRoll{},
+		&models.Tag{},
+		&models.AssetTag{},
+		&models.Collection{},
+		&models.CollectionItem{},
+		&models.CollectionCollaborator{},
+		&models.Upload{},
+		&models.LoreArticle{},
+		&models.LoreLink{},
+		&models.Story{},
+		&models.StoryChapter{},
+		&models.WorldCalendar{},
+		&models.TimelineEventLink{},
+		&models.TimelineEventNPC{},
+		&models.WorldForkOrigin{},
 	)
 
+	// Events from before eras had IDs point at their era by name
+	if err := handlers.BackfillEventEras(db); err != nil {
+		log.Fatal("Failed to link timeline events to eras:", err)
+	}
+
+	// Full-text search columns and indexes live outside the models
+	if err := search.Migrate(db); err != nil {
+		log.Fatal("Failed to set up search indexes:", err)
+	}
+
 	// Setup middleware
 	authMiddleware := middleware.NewAuthMiddleware(db)
 
+	// Images go to Cloudflare or the local disk depending on IMAGE_STORE
+	imageStore := services.NewImageStore()
+
 	// Setup handlers
-	assetHandler := handlers.NewAssetHandler(db)
-	adventureHandler := handlers.NewAdventureHandler(db)
+	assetHandler := handlers.NewAssetHandler(db, imageStore)
+	adventureHandler := handlers.NewAdventureHandler(db, imageStore)
 	authHandler := handlers.NewAuthHandler(db)
-	uploadHandler := handlers.NewUploadHandler()
+	uploadHandler := handlers.NewUploadHandler(db, imageStore)
 	adminHandler := handlers.NewAdminHandler(db)
 	worldHandler := handlers.NewWorldHandler(db)
 	timelineEventHandler := handlers.NewTimelineEventHandler(db)
+	timelineLinkHandler := handlers.NewTimelineLinkHandler(db)
 	worldEraHandler := handlers.NewWorldEraHandler(db)
+	calendarHandler := handlers.NewCalendarHandler(db)
 	taskHandler := handlers.NewTaskHandler(db)
 	phoneticHandler := handlers.NewPhoneticHandler(db)
 	npcHandler := handlers.NewNPCHandler(db)
 	orgHandler := handlers.NewOrganizationHandler(db)
+	loreHandler := handlers.NewLoreHandler(db)
+	storyHandler := handlers.NewStoryHandler(db)
+	trashHandler := handlers.NewTrashHandler(db)
+	searchHandler := handlers.NewSearchHandler(db)
+	tagHandler := handlers.NewTagHandler(db)
+	collectionHandler := handlers.NewCollectionHandler(db)
+	playSessionHandler := handlers.NewPlaySessionHandler(db, realtime.NewHub())
+
+	// Permanently remove trashed content once its retention period has passed
+	jobs.NewTrashPurger(db, imageStore).Start()
+
+	// Delete uploaded images nothing ended up using
+	imageCollector := jobs.NewImageCollector(db, imageStore)
+	imageCollector.Start()
+	imageGCHandler := handlers.NewImageGCHandler(imageCollector)
 
 	// Setup routes
 	r := gin.Default()
@@ -72,8 +124,8 @@ func main() {
 	// Add CORS middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Player-Token"},
 		AllowCredentials: true,
 	}))
 
@@ -92,17 +144,30 @@ func main() {
 	api.Use(authMiddleware.RequireAuth())
 	{
 		api.POST("/upload/image", uploadHandler.UploadImage)
+		api.GET("/upload/quota", uploadHandler.GetQuota)
+		api.POST("/upload/url", uploadHandler.CreateUploadURL)
+		api.POST("/upload/confirm", uploadHandler.ConfirmUpload)
+	}
+
+	// Locally stored images are served by the API itself
+	if localStore, ok := imageStore.(*services.LocalImageStore); ok {
+		imageFileHandler := handlers.NewImageFileHandler(localStore)
+		r.GET("/images/:id/:variant", imageFileHandler.GetImage)
+		r.POST("/images/upload/:id", imageFileHandler.DirectUpload)
 	}
 
 	// Asset routes
 	// GET endpoints use optional auth (show different content based on auth status)
 	r.GET("/assets", authMiddleware.OptionalAuth(), assetHandler.GetAssets)
 	r.GET("/assets/:id", authMiddleware.OptionalAuth(), assetHandler.GetAsset)
+	r.GET("/assets/:id/usages", authMiddleware.OptionalAuth(), assetHandler.GetAssetUsages)
 
 	// POST/PATCH/DELETE endpoints require auth
 	r.POST("/assets", authMiddleware.RequireAuth(), assetHandler.CreateAsset)
+	r.POST("/assets/import", authMiddleware.RequireAuth(), assetHandler.ImportAssets)
 	r.PATCH("/assets/:id", authMiddleware.RequireAuth(), assetHandler.UpdateAsset)
 	r.DELETE("/assets/:id", authMiddleware.RequireAuth(), assetHandler.DeleteAsset)
+	r.POST("/assets/:id/restore", authMiddleware.RequireAuth(), assetHandler.RestoreAsset)
 
 	// Adventure routes (similar pattern)
 	r.GET("/adventures", authMiddleware.OptionalAuth(), adventureHandler.GetAdventures)
@@ -110,6 +175,9 @@ func main() {
 	r.POST("/adventures", authMiddleware.RequireAuth(), adventureHandler.CreateAdventure)
 	r.PATCH("/adventures/:id", authMiddleware.RequireAuth(), adventureHandler.UpdateAdventure)
 	r.DELETE("/adventures/:id", authMiddleware.RequireAuth(), adventureHandler.DeleteAdventure)
+	r.POST("/adventures/:id/restore", authMiddleware.RequireAuth(), adventureHandler.RestoreAdventure)
+	r.GET("/adventures/:id/lint", authMiddleware.OptionalAuth(), adventureHandler.LintAdventure)
+	r.GET("/adventures/:id/export", authMiddleware.OptionalAuth(), adventureHandler.ExportAdventure)
 
 	// Title Page routes
 	r.GET("/adventures/:id/title-page", authMiddleware.OptionalAuth(), adventureHandler.GetTitlePage)
@@ -141,13 +209,36 @@ func main() {
 	r.POST("/worlds", authMiddleware.RequireAuth(), worldHandler.CreateWorld)
 	r.PATCH("/worlds/:id", authMiddleware.RequireAuth(), worldHandler.UpdateWorld)
 	r.DELETE("/worlds/:id", authMiddleware.RequireAuth(), worldHandler.DeleteWorld)
+	r.POST("/worlds/:id/restore", authMiddleware.RequireAuth(), worldHandler.RestoreWorld)
+	r.GET("/worlds/:id/export", authMiddleware.OptionalAuth(), worldHandler.ExportWorld)
+	r.POST("/worlds/import", authMiddleware.RequireAuth(), worldHandler.ImportWorld)
+	r.POST("/worlds/:id/fork", authMiddleware.RequireAuth(), worldHandler.ForkWorld)
+	r.GET("/worlds/:id/forks", authMiddleware.OptionalAuth(), worldHandler.GetWorldForks)
+	r.GET("/worlds/:id/diff", authMiddleware.OptionalAuth(), worldHandler.DiffWorldFork)
+	r.POST("/worlds/:id/merge", authMiddleware.RequireAuth(), worldHandler.MergeWorldFork)
 
 	// Timeline Event routes
 	r.GET("/worlds/:id/timeline-events", authMiddleware.OptionalAuth(), timelineEventHandler.GetTimelineEvents)
 	r.POST("/worlds/:id/timeline-events", authMiddleware.RequireAuth(), timelineEventHandler.CreateTimelineEvent)
+	r.POST("/worlds/:id/timeline-events/reorder", authMiddleware.RequireAuth(), timelineEventHandler.ReorderTimelineEvents)
+	r.POST("/worlds/:id/timeline-events/batch", authMiddleware.RequireAuth(), timelineEventHandler.BatchTimelineEvents)
+	r.GET("/worlds/:id/timeline-events/export", authMiddleware.OptionalAuth(), timelineEventHandler.ExportTimeline)
+	r.POST("/worlds/:id/timeline-events/import", authMiddleware.RequireAuth(), timelineEventHandler.ImportTimelineCSV)
 	r.PATCH("/worlds/:id/timeline-events/:eventId", authMiddleware.RequireAuth(), timelineEventHandler.UpdateTimelineEvent)
 	r.DELETE("/worlds/:id/timeline-events/:eventId", authMiddleware.RequireAuth(), timelineEventHandler.DeleteTimelineEvent)
 
+	// Timeline event link routes
+	r.GET("/worlds/:id/timeline-events/graph", authMiddleware.OptionalAuth(), timelineLinkHandler.GetGraph)
+	r.GET("/worlds/:id/timeline-events/:eventId/links", authMiddleware.OptionalAuth(), timelineLinkHandler.GetEventLinks)
+	r.POST("/worlds/:id/timeline-events/:eventId/links", authMiddleware.RequireAuth(), timelineLinkHandler.CreateEventLink)
+	r.DELETE("/worlds/:id/timeline-events/:eventId/links/:linkId", authMiddleware.RequireAuth(), timelineLinkHandler.DeleteEventLink)
+
+	// Calendar routes
+	r.GET("/worlds/:id/calendar", authMiddleware.OptionalAuth(), calendarHandler.GetCalendar)
+	r.PUT("/worlds/:id/calendar", authMiddleware.RequireAuth(), calendarHandler.SaveCalendar)
+	r.DELETE("/worlds/:id/calendar", authMiddleware.RequireAuth(), calendarHandler.DeleteCalendar)
+	r.GET("/worlds/:id/calendar/parse", authMiddleware.OptionalAuth(), calendarHandler.ParseDate)
+
 	// World Era routes
 	r.GET("/worlds/:id/eras", authMiddleware.OptionalAuth(), worldEraHandler.GetEras)
 	r.POST("/worlds/:id/eras", authMiddleware.RequireAuth(), worldEraHandler.CreateEra)
@@ -155,6 +246,27 @@ func main() {
 	r.DELETE("/worlds/:id/eras/:eraId", authMiddleware.RequireAuth(), worldEraHandler.DeleteEra)
 	r.POST("/worlds/:id/eras/reorder", authMiddleware.RequireAuth(), worldEraHandler.ReorderEras)
 
+	// Play session routes
+	r.GET("/adventures/:id/sessions", authMiddleware.RequireAuth(), playSessionHandler.GetAdventureSessions)
+	r.GET("/adventures/:id/sessions/stats", authMiddleware.OptionalAuth(), playSessionHandler.GetAdventureSessionStats)
+	r.POST("/adventures/:id/sessions", authMiddleware.RequireAuth(), playSessionHandler.StartSession)
+	r.GET("/sessions/:id", authMiddleware.RequireAuth(), playSessionHandler.GetSession)
+	r.GET("/sessions/:id/log", authMiddleware.RequireAuth(), playSessionHandler.GetSessionLog)
+	r.POST("/sessions/:id/advance", authMiddleware.RequireAuth(), playSessionHandler.AdvanceScene)
+	r.POST("/sessions/:id/notes", authMiddleware.RequireAuth(), playSessionHandler.AddNote)
+	r.POST("/sessions/:id/end", authMiddleware.RequireAuth(), playSessionHandler.EndSession)
+
+	// Game table routes (live updates for players during a session)
+	r.POST("/play/join", authMiddleware.OptionalAuth(), playSessionHandler.JoinSession)
+	r.POST("/sessions/:id/stream-ticket", authMiddleware.OptionalAuth(), playSessionHandler.CreateStreamTicket)
+	r.GET("/sessions/:id/stream", authMiddleware.OptionalAuth(), playSessionHandler.StreamSession)
+	r.POST("/sessions/:id/broadcast", authMiddleware.RequireAuth(), playSessionHandler.Broadcast)
+	r.GET("/sessions/:id/rolls", authMiddleware.OptionalAuth(), playSessionHandler.GetRolls)
+	r.POST("/sessions/:id/rolls", authMiddleware.OptionalAuth(), playSessionHandler.RollDice)
+
+	// Trash routes
+	r.GET("/me/trash", authMiddleware.RequireAuth(), trashHandler.GetTrash)
+
 	// Admin routes (require admin auth)
 	r.GET("/admin/stats", authMiddleware.RequireAuth(), adminHandler.GetStats)
 	r.GET("/admin/users", authMiddleware.RequireAuth(), adminHandler.GetUsers)
@@ -163,6 +275,8 @@ func main() {
 	r.GET("/admin/content/unreviewed", authMiddleware.RequireAuth(), adminHandler.GetUnreviewedContent)
 	r.PATCH("/admin/content/assets/:id/review", authMiddleware.RequireAuth(), adminHandler.MarkAssetReviewed)
 	r.PATCH("/admin/content/adventures/:id/review", authMiddleware.RequireAuth(), adminHandler.MarkAdventureReviewed)
+	r.GET("/admin/images/orphans", authMiddleware.RequireAuth(), imageGCHandler.GetOrphans)
+	r.POST("/admin/images/collect", authMiddleware.RequireAuth(), imageGCHandler.CollectOrphans)
 
 	// Task routes
 	r.GET("/tasks", authMiddleware.RequireAuth(), taskHandler.GetTasks)
@@ -183,6 +297,7 @@ func main() {
 	// NPC routes
 	r.GET("/worlds/:id/npcs", authMiddleware.OptionalAuth(), npcHandler.GetNPCs)
 	r.GET("/worlds/:id/npcs/:npcId", authMiddleware.OptionalAuth(), npcHandler.GetNPC)
+	r.GET("/worlds/:id/npcs/:npcId/history", authMiddleware.OptionalAuth(), npcHandler.GetNPCHistory)
 	r.POST("/worlds/:id/npcs", authMiddleware.RequireAuth(), npcHandler.CreateNPC)
 	r.PATCH("/worlds/:id/npcs/:npcId", authMiddleware.RequireAuth(), npcHandler.UpdateNPC)
 	r.DELETE("/worlds/:id/npcs/:npcId", authMiddleware.RequireAuth(), npcHandler.DeleteNPC)
@@ -191,10 +306,58 @@ func main() {
 	// Organization routes
 	r.GET("/worlds/:id/organizations", authMiddleware.OptionalAuth(), orgHandler.GetOrganizations)
 	r.GET("/worlds/:id/organizations/:orgId", authMiddleware.OptionalAuth(), orgHandler.GetOrganization)
+	r.GET("/worlds/:id/organizations/:orgId/history", authMiddleware.OptionalAuth(), orgHandler.GetOrganizationHistory)
 	r.POST("/worlds/:id/organizations", authMiddleware.RequireAuth(), orgHandler.CreateOrganization)
 	r.PATCH("/worlds/:id/organizations/:orgId", authMiddleware.RequireAuth(), orgHandler.UpdateOrganization)
 	r.DELETE("/worlds/:id/organizations/:orgId", authMiddleware.RequireAuth(), orgHandler.DeleteOrganization)
 
+	// Lore routes
+	r.GET("/worlds/:id/lore", authMiddleware.OptionalAuth(), loreHandler.GetArticles)
+	r.GET("/worlds/:id/lore/categories", authMiddleware.OptionalAuth(), loreHandler.GetCategories)
+	r.GET("/worlds/:id/lore/backlinks", authMiddleware.OptionalAuth(), loreHandler.GetBacklinks)
+	r.GET("/worlds/:id/lore/broken-links", authMiddleware.OptionalAuth(), loreHandler.GetBrokenLinks)
+	r.GET("/worlds/:id/lore/:articleId", authMiddleware.OptionalAuth(), loreHandler.GetArticle)
+	r.POST("/worlds/:id/lore", authMiddleware.RequireAuth(), loreHandler.CreateArticle)
+	r.PATCH("/worlds/:id/lore/:articleId", authMiddleware.RequireAuth(), loreHandler.UpdateArticle)
+	r.DELETE("/worlds/:id/lore/:articleId", authMiddleware.RequireAuth(), loreHandler.DeleteArticle)
+
+	// Story routes
+	r.GET("/worlds/:id/stories", authMiddleware.OptionalAuth(), storyHandler.GetStories)
+	r.GET("/worlds/:id/stories/:storyId", authMiddleware.OptionalAuth(), storyHandler.GetStory)
+	r.POST("/worlds/:id/stories", authMiddleware.RequireAuth(), storyHandler.CreateStory)
+	r.PATCH("/worlds/:id/stories/:storyId", authMiddleware.RequireAuth(), storyHandler.UpdateStory)
+	r.DELETE("/worlds/:id/stories/:storyId", authMiddleware.RequireAuth(), storyHandler.DeleteStory)
+	r.GET("/worlds/:id/stories/:storyId/chapters/:chapterId", authMiddleware.OptionalAuth(), storyHandler.GetChapter)
+	r.POST("/worlds/:id/stories/:storyId/chapters", authMiddleware.RequireAuth(), storyHandler.CreateChapter)
+	r.PATCH("/worlds/:id/stories/:storyId/chapters/:chapterId", authMiddleware.RequireAuth(), storyHandler.UpdateChapter)
+	r.DELETE("/worlds/:id/stories/:storyId/chapters/:chapterId", authMiddleware.RequireAuth(), storyHandler.DeleteChapter)
+	r.POST("/worlds/:id/stories/:storyId/chapters/reorder", authMiddleware.RequireAuth(), storyHandler.ReorderChapters)
+
+	// Tag routes - tags are personal, so everything requires auth
+	r.GET("/tags", authMiddleware.RequireAuth(), tagHandler.GetTags)
+	r.POST("/tags", authMiddleware.RequireAuth(), tagHandler.CreateTag)
+	r.PATCH("/tags/:id", authMiddleware.RequireAuth(), tagHandler.UpdateTag)
+	r.DELETE("/tags/:id", authMiddleware.RequireAuth(), tagHandler.DeleteTag)
+	r.GET("/assets/:id/tags", authMiddleware.RequireAuth(), tagHandler.GetAssetTags)
+	r.POST("/assets/:id/tags", authMiddleware.RequireAuth(), tagHandler.TagAsset)
+	r.DELETE("/assets/:id/tags/:tagId", authMiddleware.RequireAuth(), tagHandler.UntagAsset)
+
+	// Collection routes
+	r.GET("/collections", authMiddleware.RequireAuth(), collectionHandler.GetCollections)
+	r.POST("/collections", authMiddleware.RequireAuth(), collectionHandler.CreateCollection)
+	r.GET("/collections/:id", authMiddleware.RequireAuth(), collectionHandler.GetCollection)
+	r.PATCH("/collections/:id", authMiddleware.RequireAuth(), collectionHandler.UpdateCollection)
+	r.DELETE("/collections/:id", authMiddleware.RequireAuth(), collectionHandler.DeleteCollection)
+	r.POST("/collections/:id/assets", authMiddleware.RequireAuth(), collectionHandler.AddAsset)
+	r.PATCH("/collections/:id/assets/order", authMiddleware.RequireAuth(), collectionHandler.ReorderAssets)
+	r.DELETE("/collections/:id/assets/:assetId", authMiddleware.RequireAuth(), collectionHandler.RemoveAsset)
+	r.POST("/collections/:id/collaborators", authMiddleware.RequireAuth(), collectionHandler.AddCollaborator)
+	r.DELETE("/collections/:id/collaborators/:userId", authMiddleware.RequireAuth(), collectionHandler.RemoveCollaborator)
+	r.POST("/adventures/:id/collections/:collectionId", authMiddleware.RequireAuth(), collectionHandler.AttachToAdventure)
+
+	// Search
+	r.GET("/search", authMiddleware.OptionalAuth(), searchHandler.Search)
+
 	// Start server
 	port := os.Getenv("PORT")
 	if port == "" {
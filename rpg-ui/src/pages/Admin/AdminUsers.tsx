@@ -9,6 +9,7 @@ import {
 import { Card, CardContent } from "@/components/Card/Card";
 import { Button } from "@/components/Button/Button";
 import { Badge } from "@/components/Badge/Badge";
+import { fetchAll } from "@/services/api";
 import type { User } from "@/services/api";
 
 export function AdminUsers() {
@@ -22,17 +23,7 @@ export function AdminUsers() {
 
   const fetchUsers = async () => {
     try {
-      const response = await fetch("http://localhost:8080/admin/users", {
-        headers: {
-          Authorization: `Bearer ${localStorage.getItem("auth_token")}`,
-          "Content-Type": "application/json",
-        },
-      });
-
-      if (response.ok) {
-        const data = await response.json();
-        setUsers(data);
-      }
+      setUsers(await fetchAll<User>("http://localhost:8080/admin/users"));
     } catch (error) {
       console.error("Failed to fetch users:", error);
     } finally {
@@ -74,7 +74,20 @@ export function AssetsGalleryPage() {
   const handleDeleteAsset = async (asset: Asset) => {
     if (confirm(`Are you sure you want to delete "${asset.name}"?`)) {
       try {
-        await assetService.delete(asset.id);
+        try {
+          await assetService.delete(asset.id);
+        } catch (error) {
+          const message = error instanceof Error ? error.message : "";
+          if (
+            !message.includes("still in use") ||
+            !confirm(
+              `"${asset.name}" is used in adventures or collections. Remove it from all of them and delete it anyway?`
+            )
+          ) {
+            throw error;
+          }
+          await assetService.delete(asset.id, true);
+        }
         // Refresh the assets list
         const updatedAssets = await assetService.getAll();
         setAssets(updatedAssets);
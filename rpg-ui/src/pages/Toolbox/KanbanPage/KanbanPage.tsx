@@ -5,6 +5,7 @@ import { KanbanBoard } from "@/components/KanbanBoard/KanbanBoard";
 
 import styles from "@/components/KanbanBoard/Kanban.module.css";
 import { KanbanTaskCreateForm } from "@/components/KanbanBoard/KanbanTaskCreateForm";
+import { fetchAll } from "@/services/api";
 
 interface Task {
   id: number;
@@ -23,16 +24,7 @@ export function KanbanPage() {
 
   const fetchTasks = async () => {
     try {
-      const token = localStorage.getItem("auth_token"); // Changed to auth_token
-      const response = await fetch("http://localhost:8080/tasks", {
-        headers: {
-          Authorization: `Bearer ${token}`,
-        },
-      });
-      if (response.ok) {
-        const data = await response.json();
-        setTasks(data);
-      }
+      setTasks(await fetchAll<Task>("http://localhost:8080/tasks"));
     } catch (error) {
       console.error("Failed to fetch tasks:", error);
     } finally {
@@ -4,6 +4,7 @@ import { Button } from "@/components/Button/Button";
 import { Card, CardHeader, CardContent } from "@/components/Card/Card";
 import { Badge } from "@/components/Badge/Badge";
 import { NPCRelationshipGraph } from "@/components/NPCRelationshipGraph/NPCRelationshipGraph";
+import { fetchAll } from "@/services/api";
 
 interface NPC {
   id: number;
@@ -55,19 +56,11 @@ export function WorldNPCsPage() {
       if (!id) return;
 
       try {
-        const response = await fetch(
-          `http://localhost:8080/worlds/${id}/npcs`,
-          {
-            headers: {
-              Authorization: `Bearer ${localStorage.getItem("auth_token")}`,
-            },
-          }
+        setNpcs(
+          await fetchAll<NPC>(
+            `http://localhost:8080/worlds/${id}/npcs?include=memberships,relationships`
+          )
         );
-
-        if (response.ok) {
-          const data = await response.json();
-          setNpcs(Array.isArray(data) ? data : []);
-        }
       } catch (error) {
         console.error("Failed to fetch NPCs:", error);
       } finally {
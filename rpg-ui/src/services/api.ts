@@ -21,11 +21,26 @@ export interface Asset {
   is_official: boolean;
   reviewed: boolean;
   genres: string[];
+  stat_block?: StatBlock | null;
   user_id?: number;
   user?: User;
   created_at: string;
 }
 
+// Simple D6 game data for an asset. Characters and creatures use health,
+// expertise, abilities and attacks; items use properties.
+export interface StatBlock {
+  health?: {
+    max: number;
+    current: number;
+    state: "Healthy" | "Hurt" | "Badly Hurt" | "Down";
+  };
+  expertise?: string[];
+  abilities?: { name: string; description: string }[];
+  attacks?: { name: string; damage: number; range: string; notes: string }[];
+  properties?: { name: string; value: string }[];
+}
+
 export interface World {
   id: number;
   title: string;
@@ -40,6 +55,9 @@ export interface World {
   age_rating: "For Everyone" | "Teen" | "Adult";
   user_id?: number;
   user?: User;
+  // Set on forks: the world this was copied from, and when
+  forked_from_id?: number | null;
+  forked_at?: string | null;
   created_at: string;
 }
 
@@ -50,7 +68,12 @@ export interface TimelineEvent {
   description: string;
   start_date: string;
   end_date: string | null;
+  // Day numbers from the world calendar, null when the world has none
+  start_day?: number | null;
+  end_day?: number | null;
+  duration_days?: number | null;
   era: string;
+  era_id?: number | null;
   importance: "minor" | "major" | "critical";
   sort_order: number;
   image_url?: string;
@@ -58,16 +81,107 @@ export interface TimelineEvent {
   details?: string;
   user_id?: number;
   user?: User;
+  // Who took part and where; send organization_ids and location_ids to change them
+  npcs?: TimelineEventNPC[];
+  organizations?: { id: number; name: string }[];
+  locations?: { id: number; name: string }[];
+  organization_ids?: number[];
+  location_ids?: number[];
   created_at: string;
   updated_at: string;
 }
 
+export interface TimelineEventNPC {
+  timeline_event_id?: number;
+  npc_id: number;
+  // Free text such as instigator, victim or witness
+  role: string;
+  npc?: { id: number; name: string };
+}
+
+// An event in an NPC's or organization's history
+export interface HistoryEntry extends TimelineEvent {
+  role?: string;
+}
+
+export type TimelineLinkType =
+  | "caused"
+  | "part_of"
+  | "concurrent"
+  | "contradicts";
+
+export interface TimelineEventLink {
+  id: number;
+  world_id: number;
+  // Caused and part_of point from the cause or sub-event
+  from_event_id: number;
+  to_event_id: number;
+  type: TimelineLinkType;
+  note: string;
+  user_id?: number;
+  created_at: string;
+  from_event?: TimelineEvent;
+  to_event?: TimelineEvent;
+}
+
+export interface TimelineIssue {
+  kind: "cycle" | "cause_after_effect" | "outside_parent";
+  message: string;
+  event_ids: number[];
+  link_ids: number[];
+}
+
+export interface TimelineGraph {
+  nodes: TimelineEvent[];
+  edges: TimelineEventLink[];
+  issues: TimelineIssue[];
+}
+
 export interface WorldEra {
   id: number;
   world_id: number;
   name: string;
   sort_order: number;
+  start_date?: string;
+  end_date?: string | null;
+  // Day numbers from the world calendar, null when undated
+  start_day?: number | null;
+  end_day?: number | null;
+  created_at: string;
+}
+
+export interface LoreArticle {
+  id: number;
+  world_id: number;
+  title: string;
+  summary: string;
+  body?: string; // Left out of list responses
+  categories: string[];
+  image_url: string;
+  image_id: string;
+  user_id?: number;
   created_at: string;
+  updated_at: string;
+}
+
+export type LoreLinkType =
+  | "article"
+  | "npc"
+  | "organization"
+  | "location"
+  | "timeline_event";
+
+// A [[link]] in an article body; target is null when it doesn't resolve
+export interface LoreLink {
+  raw: string;
+  type?: LoreLinkType;
+  name: string;
+  label: string;
+  target: { type: LoreLinkType; id: number; name: string } | null;
+}
+
+export interface LoreArticleDetail extends LoreArticle {
+  links: LoreLink[];
 }
 
 export interface Adventure {
@@ -208,6 +322,19 @@ export interface PhoneticSyllable {
   position: number; // 0=start, 1=middle, 2=end
 }
 
+// Envelope returned by every list endpoint
+export interface ListResponse<T> {
+  data: T[];
+  meta: {
+    total: number;
+    limit: number;
+    offset: number;
+    sort: string;
+    order: "asc" | "desc";
+    next_cursor?: string;
+  };
+}
+
 function getAuthHeaders(): HeadersInit {
   const token = localStorage.getItem("auth_token");
   const headers: HeadersInit = {
@@ -256,10 +383,25 @@ async function authenticatedFetch(url: string, options: RequestInit = {}) {
   return response;
 }
 
+// Reads every page of a list endpoint, following next_cursor
+export async function fetchAll<T>(url: string): Promise<T[]> {
+  const items: T[] = [];
+  let cursor: string | undefined;
+  do {
+    const pageUrl = new URL(url);
+    pageUrl.searchParams.set("limit", "200");
+    if (cursor) pageUrl.searchParams.set("cursor", cursor);
+    const response = await authenticatedFetch(pageUrl.toString());
+    const list: ListResponse<T> = await response.json();
+    items.push(...list.data);
+    cursor = list.meta.next_cursor;
+  } while (cursor);
+  return items;
+}
+
 export const assetService = {
   async getAll(): Promise<Asset[]> {
-    const response = await authenticatedFetch(`${API_BASE}/assets`);
-    return response.json();
+    return fetchAll<Asset>(`${API_BASE}/assets`);
   },
 
   async getById(id: number): Promise<Asset> {
@@ -288,10 +430,15 @@ export const assetService = {
     return response.json();
   },
 
-  async delete(id: number): Promise<void> {
-    await authenticatedFetch(`${API_BASE}/assets/${id}`, {
-      method: "DELETE",
-    });
+  // Fails while the asset is used somewhere unless force is set. It then
+  // stays linked until the trash is purged, so restoring brings it back.
+  async delete(id: number, force = false): Promise<void> {
+    await authenticatedFetch(
+      `${API_BASE}/assets/${id}${force ? "?force=true" : ""}`,
+      {
+        method: "DELETE",
+      }
+    );
   },
 };
 
@@ -306,7 +453,16 @@ export interface ImageUploadResponse {
   };
 }
 
+interface DirectUpload {
+  image_id: string;
+  upload_url: string;
+  expires_at: string;
+}
+
 export const imageService = {
+  // Sends the file straight to the image store with a one-time URL and then
+  // confirms it, so large images don't pass through the API. Falls back to
+  // uploading through the API when the store doesn't support direct uploads.
   async uploadImage(
     file: File,
     metadata?: { alt?: string; caption?: string }
@@ -316,6 +472,57 @@ export const imageService = {
       throw new Error("Authentication required for image upload");
     }
 
+    const urlResponse = await fetch(`${API_BASE}/api/upload/url`, {
+      method: "POST",
+      headers: {
+        Authorization: `Bearer ${token}`,
+        "Content-Type": "application/json",
+      },
+      body: JSON.stringify({ filename: file.name, ...metadata }),
+    });
+    if (urlResponse.status === 501) {
+      return this.uploadImageThroughApi(file, metadata);
+    }
+    if (!urlResponse.ok) {
+      if (urlResponse.status === 401) {
+        localStorage.removeItem("auth_token");
+        throw new Error("Authentication required");
+      }
+      const errorData = await urlResponse.json().catch(() => ({}));
+      throw new Error(errorData.error || `Upload failed: ${urlResponse.status}`);
+    }
+    const upload: DirectUpload = await urlResponse.json();
+
+    const formData = new FormData();
+    formData.append("file", file);
+    const uploadResponse = await fetch(upload.upload_url, {
+      method: "POST",
+      body: formData,
+    });
+    if (!uploadResponse.ok) {
+      const errorData = await uploadResponse.json().catch(() => ({}));
+      throw new Error(errorData.error || `Upload failed: ${uploadResponse.status}`);
+    }
+
+    const confirmResponse = await authenticatedFetch(
+      `${API_BASE}/api/upload/confirm`,
+      {
+        method: "POST",
+        body: JSON.stringify({ image_id: upload.image_id }),
+      }
+    );
+    return confirmResponse.json();
+  },
+
+  async uploadImageThroughApi(
+    file: File,
+    metadata?: { alt?: string; caption?: string }
+  ): Promise<ImageUploadResponse> {
+    const token = localStorage.getItem("auth_token");
+    if (!token) {
+      throw new Error("Authentication required for image upload");
+    }
+
     const formData = new FormData();
     formData.append("file", file);
 
@@ -350,8 +557,7 @@ export const imageService = {
 export const adventureService = {
   // Adventure Management
   async getAll(): Promise<Adventure[]> {
-    const response = await authenticatedFetch(`${API_BASE}/adventures`);
-    return response.json();
+    return fetchAll<Adventure>(`${API_BASE}/adventures`);
   },
 
   async getById(id: number): Promise<Adventure> {
@@ -582,8 +788,7 @@ export const adventureService = {
 
 export const worldService = {
   async getAll(): Promise<World[]> {
-    const response = await authenticatedFetch(`${API_BASE}/worlds`);
-    return response.json();
+    return fetchAll<World>(`${API_BASE}/worlds`);
   },
 
   async get(id: number): Promise<World> {
@@ -664,6 +869,270 @@ export const timelineEventService = {
       }
     );
   },
+
+  // The listed events trade their positions into the order given
+  async reorder(worldId: number, eventIds: number[]): Promise<TimelineEvent[]> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/timeline-events/reorder`,
+      {
+        method: "POST",
+        body: JSON.stringify({ event_ids: eventIds }),
+      }
+    );
+    if (!response.ok) {
+      const error = await response.json();
+      throw new BatchError(error.error || "Failed to reorder events", error.errors);
+    }
+    return response.json();
+  },
+
+  // All or nothing: on failure nothing changes and errors are listed by index
+  async batch(
+    worldId: number,
+    operations: TimelineBatchOperation[]
+  ): Promise<TimelineEvent[]> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/timeline-events/batch`,
+      {
+        method: "POST",
+        body: JSON.stringify({ operations }),
+      }
+    );
+    if (!response.ok) {
+      const error = await response.json();
+      throw new BatchError(error.error || "Failed to save events", error.errors);
+    }
+    const data = await response.json();
+    return data.results;
+  },
+
+  // Downloads the timeline as a file; importance keeps events at least that important
+  async export(
+    worldId: number,
+    format: TimelineExportFormat,
+    importance?: TimelineImportance
+  ): Promise<Blob> {
+    const params = new URLSearchParams({ format });
+    if (importance) params.set("importance", importance);
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/timeline-events/export?${params}`
+    );
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || "Failed to export timeline");
+    }
+    return response.blob();
+  },
+
+  // All or nothing: on failure nothing is added and errors are listed by row
+  async importCsv(worldId: number, file: File): Promise<TimelineEvent[]> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/timeline-events/import`,
+      {
+        method: "POST",
+        headers: { "Content-Type": "text/csv" },
+        body: await file.text(),
+      }
+    );
+    if (!response.ok) {
+      const error = await response.json();
+      throw new CsvImportError(error.error || "Failed to import events", error.errors);
+    }
+    const data = await response.json();
+    return data.events;
+  },
+};
+
+export type TimelineExportFormat = "json" | "csv" | "md" | "ics";
+
+export type TimelineImportance = "minor" | "major" | "critical";
+
+export interface RowError {
+  row: number;
+  column?: string;
+  error: string;
+}
+
+export class CsvImportError extends Error {
+  errors: RowError[];
+
+  constructor(message: string, errors: RowError[] = []) {
+    super(message);
+    this.errors = errors;
+  }
+}
+
+export type TimelineBatchOperation =
+  | { op: "create"; event: Partial<TimelineEvent> }
+  | { op: "update"; id: number; event: Partial<TimelineEvent> }
+  | { op: "delete"; id: number };
+
+export interface ItemError {
+  index: number;
+  id?: number;
+  error: string;
+}
+
+export class BatchError extends Error {
+  errors: ItemError[];
+
+  constructor(message: string, errors: ItemError[] = []) {
+    super(message);
+    this.errors = errors;
+  }
+}
+
+export interface ForkChange {
+  kind: "event" | "npc";
+  change: "added" | "removed" | "changed";
+  fork_id?: number;
+  source_id?: number;
+  name: string;
+  fields?: string[];
+  // The source world changed this too since the fork
+  conflict?: boolean;
+}
+
+export interface WorldDiff {
+  fork_id: number;
+  source_id: number;
+  forked_at: string;
+  events: ForkChange[];
+  npcs: ForkChange[];
+}
+
+export const worldForkService = {
+  async fork(worldId: number, title?: string): Promise<World> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/fork`,
+      {
+        method: "POST",
+        body: JSON.stringify({ title }),
+      }
+    );
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || "Failed to fork world");
+    }
+    const data = await response.json();
+    return data.world;
+  },
+
+  async getForks(worldId: number): Promise<World[]> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/forks`
+    );
+    return response.json();
+  },
+
+  async diff(forkId: number): Promise<WorldDiff> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${forkId}/diff`
+    );
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || "Failed to compare worlds");
+    }
+    return response.json();
+  },
+
+  // All or nothing: on failure nothing is merged and errors are listed by index
+  async merge(
+    forkId: number,
+    changes: Pick<ForkChange, "kind" | "change" | "fork_id" | "source_id">[],
+    force = false
+  ): Promise<WorldDiff> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${forkId}/merge`,
+      {
+        method: "POST",
+        body: JSON.stringify({ changes, force }),
+      }
+    );
+    if (!response.ok) {
+      const error = await response.json();
+      throw new BatchError(error.error || "Failed to merge changes", error.errors);
+    }
+    const data = await response.json();
+    return data.diff;
+  },
+};
+
+export const timelineLinkService = {
+  async getForEvent(
+    worldId: number,
+    eventId: number
+  ): Promise<{
+    links: TimelineEventLink[];
+    parent: TimelineEvent | null;
+    sub_events: TimelineEvent[];
+  }> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/timeline-events/${eventId}/links`
+    );
+    return response.json();
+  },
+
+  async create(
+    worldId: number,
+    eventId: number,
+    link: { to_event_id: number; type: TimelineLinkType; note?: string }
+  ): Promise<{ link: TimelineEventLink; warnings: TimelineIssue[] }> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/timeline-events/${eventId}/links`,
+      {
+        method: "POST",
+        body: JSON.stringify(link),
+      }
+    );
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || "Failed to link events");
+    }
+    return response.json();
+  },
+
+  async delete(worldId: number, eventId: number, linkId: number): Promise<void> {
+    await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/timeline-events/${eventId}/links/${linkId}`,
+      {
+        method: "DELETE",
+      }
+    );
+  },
+
+  async getGraph(
+    worldId: number,
+    options: { types?: TimelineLinkType[]; eventId?: number } = {}
+  ): Promise<TimelineGraph> {
+    const params = new URLSearchParams();
+    if (options.types?.length) params.set("types", options.types.join(","));
+    if (options.eventId) params.set("event_id", String(options.eventId));
+    const query = params.toString();
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/timeline-events/graph${query ? `?${query}` : ""}`
+    );
+    return response.json();
+  },
+};
+
+export const historyService = {
+  async getNPCHistory(worldId: number, npcId: number): Promise<HistoryEntry[]> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/npcs/${npcId}/history`
+    );
+    return response.json();
+  },
+
+  async getOrganizationHistory(
+    worldId: number,
+    orgId: number
+  ): Promise<HistoryEntry[]> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/organizations/${orgId}/history`
+    );
+    return response.json();
+  },
 };
 
 export const worldEraService = {
@@ -721,11 +1190,89 @@ export const worldEraService = {
   },
 };
 
+export const loreService = {
+  async getAll(worldId: number, category?: string): Promise<LoreArticle[]> {
+    const params = new URLSearchParams();
+    if (category) params.set("category", category);
+    return fetchAll<LoreArticle>(`${API_BASE}/worlds/${worldId}/lore?${params}`);
+  },
+
+  async get(worldId: number, articleId: number): Promise<LoreArticleDetail> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/lore/${articleId}`
+    );
+    return response.json();
+  },
+
+  async create(
+    worldId: number,
+    article: Pick<LoreArticle, "title" | "summary" | "categories"> &
+      Partial<Pick<LoreArticle, "body" | "image_url" | "image_id">>
+  ): Promise<LoreArticleDetail> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/lore`,
+      {
+        method: "POST",
+        body: JSON.stringify(article),
+      }
+    );
+    return response.json();
+  },
+
+  async update(
+    worldId: number,
+    articleId: number,
+    article: Partial<
+      Pick<
+        LoreArticle,
+        "title" | "summary" | "body" | "categories" | "image_url" | "image_id"
+      >
+    >
+  ): Promise<LoreArticleDetail> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/lore/${articleId}`,
+      {
+        method: "PATCH",
+        body: JSON.stringify(article),
+      }
+    );
+    return response.json();
+  },
+
+  async delete(worldId: number, articleId: number): Promise<void> {
+    await authenticatedFetch(`${API_BASE}/worlds/${worldId}/lore/${articleId}`, {
+      method: "DELETE",
+    });
+  },
+
+  async categories(
+    worldId: number
+  ): Promise<{ name: string; count: number }[]> {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/lore/categories`
+    );
+    return response.json();
+  },
+
+  async backlinks(
+    worldId: number,
+    type: LoreLinkType,
+    id: number
+  ): Promise<
+    { article_id: number; title: string; summary: string; label: string }[]
+  > {
+    const response = await authenticatedFetch(
+      `${API_BASE}/worlds/${worldId}/lore/backlinks?type=${type}&id=${id}`
+    );
+    const result = await response.json();
+    return result.backlinks;
+  },
+};
+
 export const phoneticService = {
   // Get all tables
   async getAll(): Promise<PhoneticTable[]> {
-    const response = await authenticatedFetch(`${API_BASE}/phonetics`);
-    return response.json();
+    return fetchAll<PhoneticTable>(`${API_BASE}/phonetics`);
   },
 
   // Get single table with syllables